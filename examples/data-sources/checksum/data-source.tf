@@ -0,0 +1,8 @@
+data "synclocal_checksum" "installer" {
+  path      = "/path/to/installer.bin"
+  algorithm = "sha512"
+}
+
+output "installer_sha512" {
+  value = data.synclocal_checksum.installer.hash
+}
@@ -0,0 +1,7 @@
+data "synclocal_file" "config" {
+  path = "/path/to/config.yaml"
+}
+
+output "config_sha256" {
+  value = data.synclocal_file.config.exists ? data.synclocal_file.config.content_sha256 : null
+}
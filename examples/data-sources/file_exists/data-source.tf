@@ -0,0 +1,8 @@
+data "synclocal_file_exists" "override" {
+  path = "/etc/app/override.yaml"
+}
+
+resource "synclocal_file" "config" {
+  source      = data.synclocal_file_exists.override.exists ? data.synclocal_file_exists.override.path : "${path.module}/default.yaml"
+  destination = "/opt/app/config.yaml"
+}
@@ -0,0 +1,8 @@
+data "synclocal_http" "version" {
+  url          = "https://example.com/latest/VERSION"
+  bearer_token = var.token
+}
+
+output "latest_version" {
+  value = trimspace(data.synclocal_http.version.body)
+}
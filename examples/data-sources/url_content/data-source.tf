@@ -0,0 +1,8 @@
+data "synclocal_url_content" "release" {
+  url = "https://releases.example.com/app/latest.tar.gz"
+}
+
+resource "synclocal_url" "release" {
+  url      = data.synclocal_url_content.release.url
+  filename = "${path.module}/downloads/app-${substr(data.synclocal_url_content.release.content_sha256, 0, 12)}.tar.gz"
+}
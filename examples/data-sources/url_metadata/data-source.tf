@@ -0,0 +1,8 @@
+data "synclocal_url_metadata" "release" {
+  url             = "https://releases.example.com/app/v1.2.3.tar.gz"
+  fallback_to_get = true
+}
+
+output "release_size" {
+  value = data.synclocal_url_metadata.release.content_length
+}
@@ -1 +1,5 @@
-provider "synclocal" {}
\ No newline at end of file
+provider "synclocal" {
+  default_headers = {
+    Authorization = "Bearer ${var.registry_token}"
+  }
+}
@@ -0,0 +1,7 @@
+resource "synclocal_append" "hosts" {
+  path    = "/etc/hosts"
+  content = <<-EOT
+    10.0.0.10 app.internal
+    10.0.0.11 db.internal
+  EOT
+}
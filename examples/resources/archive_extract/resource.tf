@@ -0,0 +1,4 @@
+resource "synclocal_archive_extract" "release" {
+  source      = "/path/to/release.tar.gz"
+  destination = "/opt/app/release"
+}
@@ -0,0 +1,6 @@
+resource "synclocal_copy_dir" "config" {
+  source_dir      = "./config"
+  destination_dir = "/etc/myapp"
+  include         = ["*.conf", "conf.d/*"]
+  exclude         = ["*.example", ".git"]
+}
@@ -0,0 +1,5 @@
+resource "synclocal_directory" "config" {
+  path      = "/etc/myapp/conf.d"
+  dir_mode  = "0750"
+  recursive = true
+}
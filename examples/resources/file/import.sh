@@ -0,0 +1,2 @@
+# The destination path, or its file:// ID, identifies the file to import.
+terraform import synclocal_file.copy /path/to/destination
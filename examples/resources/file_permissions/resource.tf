@@ -0,0 +1,6 @@
+resource "synclocal_file_permissions" "key" {
+  path      = "/etc/myapp/server.key"
+  file_mode = "0600"
+  owner     = "myapp"
+  group     = "myapp"
+}
@@ -0,0 +1,13 @@
+resource "synclocal_manifest" "bom" {
+  destination = "/path/to/manifest.json"
+
+  file {
+    name = "config"
+    path = synclocal_file.copy.destination
+  }
+
+  file {
+    name = "artifact"
+    path = synclocal_url.webpage.filename
+  }
+}
\ No newline at end of file
@@ -0,0 +1,7 @@
+resource "synclocal_sftp" "release" {
+  host        = "files.example.com"
+  path        = "/releases/app-1.2.0.tar.gz"
+  username    = "deploy"
+  private_key = file("~/.ssh/id_ed25519")
+  destination = "/opt/app/app.tar.gz"
+}
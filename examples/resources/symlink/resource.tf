@@ -0,0 +1,5 @@
+resource "synclocal_symlink" "current" {
+  target    = "releases/v1.2.3"
+  link_path = "/opt/app/current"
+  force     = true
+}
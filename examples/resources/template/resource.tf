@@ -0,0 +1,8 @@
+resource "synclocal_template" "config" {
+  source      = "${path.module}/app.conf.tmpl"
+  destination = "/etc/app/app.conf"
+  vars = {
+    port     = "8080"
+    log_path = "/var/log/app"
+  }
+}
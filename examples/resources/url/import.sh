@@ -0,0 +1,2 @@
+# The filename path, or its file:// ID, identifies the file to import.
+terraform import synclocal_url.webpage /path/to/filename
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"strings"
+)
+
+// defaultAccept is the Accept header of a request that does not set accept.
+const defaultAccept = "*/*"
+
+func acceptSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Default:     defaultAccept,
+		Description: "Accept header of the request, such as \"application/json\", for servers that return different representations. An Accept header in headers is sent instead of the default */*, while any other value replaces it. Combine it with expected_content_type to verify the representation that was returned.",
+	}
+}
+
+// setAccept sets the Accept header of req from accept. The default gives way
+// to an Accept header that is already set from headers.
+func setAccept(req *http.Request, data resourceGetter) {
+	v, ok := data.GetOk("accept")
+	if !ok {
+		return
+	}
+	if accept := v.(string); accept != defaultAccept || req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", accept)
+	}
+}
+
+// acceptWarning warns when accept replaces an Accept header in headers.
+func acceptWarning(data resourceGetter) diag.Diagnostics {
+	v, ok := data.GetOk("accept")
+	if !ok || v.(string) == defaultAccept {
+		return nil
+	}
+	headers, _ := data.GetOk("headers")
+	m, _ := headers.(map[string]interface{})
+	for k, header := range m {
+		if strings.EqualFold(k, "Accept") {
+			return diag.Diagnostics{{
+				Severity:      diag.Warning,
+				Summary:       "accept replaces the Accept header in headers",
+				Detail:        fmt.Sprintf("The Accept header %q is not sent; accept %q is sent instead. Remove one of them.", header, v),
+				AttributePath: cty.GetAttrPath("accept"),
+			}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureDownloadFile_accept(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name        string
+		accept      string
+		headers     map[string]interface{}
+		want        string
+		wantWarning bool
+	}{
+		{"default", "", nil, "*/*", false},
+		{"accept", "application/json", nil, "application/json", false},
+		{"headers", "", map[string]interface{}{"Accept": "application/xml"}, "application/xml", false},
+		{"accept replaces headers", "application/json", map[string]interface{}{"accept": "application/xml"}, "application/json", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{
+				"url":                   srv.URL,
+				"filename":              filepath.Join(t.TempDir(), "dest-file"),
+				"expected_content_type": "application/json",
+			}
+			if tt.accept != "" {
+				raw["accept"] = tt.accept
+			}
+			if tt.headers != nil {
+				raw["headers"] = tt.headers
+			}
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), raw)
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if got != tt.want {
+				t.Fatalf("unexpected Accept header %q, want %q", got, tt.want)
+			}
+			if warned := len(diags) > 0 && diags[0].Severity == diag.Warning; warned != tt.wantWarning {
+				t.Fatalf("unexpected diagnostics %v", diags)
+			}
+		})
+	}
+}
+
+func TestDataSourceHTTPRead_accept(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Accept")
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+	data := schema.TestResourceDataRaw(t, dataSourceHTTPSchema(), map[string]interface{}{
+		"url":    srv.URL,
+		"accept": "application/vnd.github+json",
+	})
+	if diags := dataSourceHTTPRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got != "application/vnd.github+json" {
+		t.Fatalf("unexpected Accept header %q", got)
+	}
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveAllowedRoot returns the absolute path of the allowed_root
+// directory with its symlinks resolved, so destinations can be compared
+// against it after resolving their own symlinks.
+func resolveAllowedRoot(root string) (string, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", err
+	}
+	stat, err := os.Stat(resolved)
+	if err != nil {
+		return "", err
+	}
+	if !stat.IsDir() {
+		return "", fmt.Errorf("%q is not a directory", root)
+	}
+	return resolved, nil
+}
+
+// checkAllowedRoot returns an error if the path of any of keys resolves to
+// a location outside allowed_root. Paths that are not known yet are checked
+// by checkAllowedRootApply when the plan is applied.
+func (c *providerConfig) checkAllowedRoot(data resourceDiffGetter, keys ...string) error {
+	return c.checkAllowedRootPaths(data, true, keys)
+}
+
+// checkAllowedRootLink is checkAllowedRoot for paths that are symlinks
+// managed by the resource. The final element of the path is not followed,
+// since the link is replaced rather than written through.
+func (c *providerConfig) checkAllowedRootLink(data resourceDiffGetter, keys ...string) error {
+	return c.checkAllowedRootPaths(data, false, keys)
+}
+
+// checkAllowedRootApply is checkAllowedRoot for Create and Update. The SDK
+// does not call CustomizeDiff during apply, so a path computed from another
+// resource is only checked here.
+func (c *providerConfig) checkAllowedRootApply(data resourceGetter, keys ...string) error {
+	return c.checkAllowedRootPaths(appliedData{data}, true, keys)
+}
+
+// checkAllowedRootLinkApply is checkAllowedRootLink for Create and Update.
+func (c *providerConfig) checkAllowedRootLinkApply(data resourceGetter, keys ...string) error {
+	return c.checkAllowedRootPaths(appliedData{data}, false, keys)
+}
+
+func (c *providerConfig) checkAllowedRootPaths(data resourceDiffGetter, followLink bool, keys []string) error {
+	if c == nil || c.allowedRoot == "" {
+		return nil
+	}
+	for _, key := range keys {
+		if !data.NewValueKnown(key) {
+			continue
+		}
+		path := data.Get(key).(string)
+		if path == "" {
+			continue
+		}
+		resolved, err := resolveDestination(c.resolvePath(path), followLink)
+		if err != nil {
+			return fmt.Errorf("%s %q could not be resolved: %w", key, path, err)
+		}
+		if !pathWithin(c.allowedRoot, resolved) {
+			return fmt.Errorf("%s %q resolves to %q, which is outside of the provider allowed_root %q", key, path, resolved, c.allowedRoot)
+		}
+	}
+	return nil
+}
+
+// resourceDiffGetter is the part of schema.ResourceDiff used to check
+// paths during plan.
+type resourceDiffGetter interface {
+	resourceGetter
+	NewValueKnown(key string) bool
+}
+
+// appliedData is a resourceDiffGetter for apply, when every value is known.
+type appliedData struct {
+	resourceGetter
+}
+
+func (appliedData) NewValueKnown(key string) bool {
+	return true
+}
+
+// resolveDestination returns the absolute, clean form of path with the
+// symlinks of its existing parents resolved. The final element is resolved
+// as well if followLink is set. Elements that do not exist yet are kept
+// as they are.
+func resolveDestination(path string, followLink bool) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if followLink {
+		return evalExistingSymlinks(abs)
+	}
+	dir, err := evalExistingSymlinks(filepath.Dir(abs))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(abs)), nil
+}
+
+// evalExistingSymlinks resolves the symlinks of the longest existing prefix
+// of the absolute path and appends the rest of it.
+func evalExistingSymlinks(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	dir, err := evalExistingSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(path)), nil
+}
+
+// pathWithin reports whether path is root or inside of it. Both must be
+// absolute and clean.
+func pathWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckAllowedRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{root, outside} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the root is configured through a symlink, and a link inside of it
+	// points outside
+	rootLink := filepath.Join(dir, "root-link")
+	if err := os.Symlink(root, rootLink); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"working_directory": root,
+		"allowed_root":      rootLink,
+	})
+	meta, diags := providerConfigure("test")(context.Background(), data)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"in root", filepath.Join(root, "sub", "file"), false},
+		{"relative", "file", false},
+		{"through root symlink", filepath.Join(rootLink, "file"), false},
+		{"root itself", root, false},
+		{"outside", filepath.Join(outside, "file"), true},
+		{"traversal", filepath.Join(root, "sub", "..", "..", "outside", "file"), true},
+		{"relative traversal", filepath.Join("..", "outside", "file"), true},
+		{"sibling prefix", root + "-other", true},
+		{"symlink escape", filepath.Join(root, "escape", "file"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resourceDirectory().Diff(context.Background(), nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+				"path": tt.path,
+			}), meta)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "outside of the provider allowed_root") {
+					t.Fatalf("expected path %q to be rejected, got %v", tt.path, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestCheckAllowedRootLink(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &providerConfig{allowedRoot: mustEvalSymlinks(t, dir)}
+	// a managed link may point outside of the root, but a file written
+	// through it may not
+	_, err := resourceSymlink().Diff(context.Background(), nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"target":    outside,
+		"link_path": link,
+	}), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, err = resourceTemplate().Diff(context.Background(), nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"template":    "hello",
+		"destination": link,
+	}), cfg)
+	if err == nil {
+		t.Fatal("expected a destination written through the link to be rejected")
+	}
+}
+
+// unknownValue is the value the plugin SDK gives a configuration attribute
+// that is not known until apply.
+const unknownValue = "74D93920-ED26-11E3-AC10-0800200C9A66"
+
+func TestCheckAllowedRootApply(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "dest-file")
+	cfg := &providerConfig{allowedRoot: mustEvalSymlinks(t, root)}
+	// a destination computed from another resource is unknown during plan
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"source":      "./testdata/source-file01",
+		"destination": unknownValue,
+	})
+	if _, err := resourceFile().Diff(context.Background(), nil, config, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "./testdata/source-file01",
+		"destination": outside,
+	})
+	diags := resourceFileCreate(context.Background(), data, cfg)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "outside of the provider allowed_root") {
+		t.Fatalf("expected the destination to be rejected on apply, got %v", diags)
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Fatalf("the destination outside of allowed_root was written: %v", err)
+	}
+}
+
+func TestProviderConfigure_allowedRoot(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, root := range []string{filepath.Join(dir, "missing"), file} {
+		data := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+			"allowed_root": root,
+		})
+		if _, diags := providerConfigure("test")(context.Background(), data); !diags.HasError() {
+			t.Fatalf("expected allowed_root %q to be rejected", root)
+		}
+	}
+}
+
+func mustEvalSymlinks(t *testing.T, path string) string {
+	t.Helper()
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolved
+}
@@ -0,0 +1,243 @@
+package provider
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type archiveFormat int
+
+const (
+	archiveUnknown archiveFormat = iota
+	archiveTar
+	archiveTarGz
+	archiveZip
+)
+
+func (f archiveFormat) String() string {
+	switch f {
+	case archiveTar:
+		return "tar"
+	case archiveTarGz:
+		return "tar.gz"
+	case archiveZip:
+		return "zip"
+	}
+	return "unknown"
+}
+
+// detectArchiveFormat identifies the archive from its leading bytes, falling
+// back to the extension of name when they are not conclusive.
+func detectArchiveFormat(name string, head []byte) archiveFormat {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return archiveZip
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return archiveTarGz
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return archiveTar
+	}
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return archiveZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveTarGz
+	case strings.HasSuffix(lower, ".tar"):
+		return archiveTar
+	}
+	return archiveUnknown
+}
+
+// extractArchiveFile extracts the archive at source into destination.
+func extractArchiveFile(source, destination string, buf []byte) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("could not open archive %q: %w", source, err)
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return fmt.Errorf("could not read archive %q: %w", source, err)
+	}
+	format := detectArchiveFormat(source, head[:n])
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	switch format {
+	case archiveZip:
+		stat, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return extractZip(f, stat.Size(), destination, buf)
+	case archiveTar, archiveTarGz:
+		return extractTarStream(bufio.NewReader(f), format, destination, buf)
+	}
+	return fmt.Errorf("could not detect the archive format of %q", source)
+}
+
+// extractTarStream extracts a tar or gzipped tar read from r.
+func extractTarStream(r io.Reader, format archiveFormat, destination string, buf []byte) error {
+	if format == archiveTarGz {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("could not read gzip stream: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return fmt.Errorf("could not create directory %q: %w", destination, err)
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read tar entry: %w", err)
+		}
+		path, err := archiveEntryPath(destination, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0755)
+		case tar.TypeReg, tar.TypeRegA:
+			err = writeArchiveFile(path, os.FileMode(hdr.Mode).Perm(), tr, buf)
+		case tar.TypeSymlink:
+			err = writeArchiveSymlink(destination, path, hdr.Linkname)
+		default:
+			// hard links, devices and fifos are not extracted
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip extracts the zip archive read from r.
+func extractZip(r io.ReaderAt, size int64, destination string, buf []byte) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("could not read zip archive: %w", err)
+	}
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return fmt.Errorf("could not create directory %q: %w", destination, err)
+	}
+	for _, f := range zr.File {
+		path, err := archiveEntryPath(destination, f.Name)
+		if err != nil {
+			return err
+		}
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = os.MkdirAll(path, 0755)
+		case mode&os.ModeSymlink != 0:
+			err = extractZipSymlink(f, destination, path)
+		case mode.IsRegular():
+			err = extractZipFile(f, path, buf)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipFile(f *zip.File, path string, buf []byte) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("could not read zip entry %q: %w", f.Name, err)
+	}
+	defer rc.Close()
+	return writeArchiveFile(path, f.Mode().Perm(), rc, buf)
+}
+
+func extractZipSymlink(f *zip.File, destination, path string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("could not read zip entry %q: %w", f.Name, err)
+	}
+	defer rc.Close()
+	target, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("could not read zip entry %q: %w", f.Name, err)
+	}
+	return writeArchiveSymlink(destination, path, string(target))
+}
+
+// archiveEntryPath returns where the entry name is extracted to. Entries
+// that would land outside of destination are rejected.
+func archiveEntryPath(destination, name string) (string, error) {
+	clean := filepath.FromSlash(name)
+	if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" {
+		return "", fmt.Errorf("archive entry %q has an absolute path", name)
+	}
+	path := filepath.Join(destination, clean)
+	if !withinDir(destination, path) {
+		return "", fmt.Errorf("archive entry %q is outside of the destination", name)
+	}
+	return path, nil
+}
+
+// withinDir reports whether path is dir or inside of it.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func writeArchiveFile(path string, mode os.FileMode, r io.Reader, buf []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create directory %q: %w", filepath.Dir(path), err)
+	}
+	if mode == 0 {
+		mode = defaultContentFileMode
+	}
+	// an entry is renamed into place once it is complete, so a failed
+	// extraction does not leave truncated files behind, and it replaces a
+	// symlink of an earlier entry instead of writing through it
+	return writeFileAtomic(path, mode, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|oNoFollow, func(w io.Writer) error {
+		if _, err := copyBuffer(w, r, buf); err != nil {
+			return fmt.Errorf("could not extract %q: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// writeArchiveSymlink creates a symlink entry. The link must point inside of
+// destination, so later entries cannot be written through it.
+func writeArchiveSymlink(destination, path, target string) error {
+	resolved := target
+	if !filepath.IsAbs(target) {
+		resolved = filepath.Join(filepath.Dir(path), filepath.FromSlash(target))
+	}
+	if !withinDir(destination, resolved) {
+		return fmt.Errorf("archive symlink %q points outside of the destination", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create directory %q: %w", filepath.Dir(path), err)
+	}
+	os.Remove(path)
+	if err := os.Symlink(target, path); err != nil {
+		return fmt.Errorf("could not create symlink %q: %w", path, err)
+	}
+	return nil
+}
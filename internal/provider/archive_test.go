@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testArchiveEntry struct {
+	name     string
+	body     string
+	linkname string
+}
+
+func testTarGz(t *testing.T, entries []testArchiveEntry) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if e.linkname != "" {
+			hdr = &tar.Header{Name: e.name, Mode: 0777, Linkname: e.linkname, Typeflag: tar.TypeSymlink}
+		} else if strings.HasSuffix(e.name, "/") {
+			hdr = &tar.Header{Name: e.name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func testZip(t *testing.T, entries []testArchiveEntry) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestExtractArchiveFile(t *testing.T) {
+	entries := []testArchiveEntry{
+		{name: "a.txt", body: "alpha\n"},
+		{name: "sub/"},
+		{name: "sub/b.txt", body: "bravo\n"},
+	}
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		// the extensions are deliberately wrong; the format comes from the contents
+		{"archive.zip", testTarGz(t, entries)},
+		{"archive.tar.gz", testZip(t, entries)},
+	}
+	want, err := hashTree("./testdata/source-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			source := filepath.Join(dir, tt.name)
+			if err := ioutil.WriteFile(source, tt.archive, 0644); err != nil {
+				t.Fatal(err)
+			}
+			dest := filepath.Join(dir, "out")
+			if err := extractArchiveFile(source, dest, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := hashTree(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Fatalf("extracted tree does not match testdata/source-dir")
+			}
+		})
+	}
+}
+
+func TestExtractArchiveFile_truncated(t *testing.T) {
+	// random content does not compress, so cutting the archive in half ends
+	// it in the middle of the entry
+	body := make([]byte, 64*1024)
+	rand.New(rand.NewSource(1)).Read(body)
+	archive := testTarGz(t, []testArchiveEntry{{name: "big.bin", body: string(body)}})
+	dir := t.TempDir()
+	source := filepath.Join(dir, "archive.tar.gz")
+	if err := ioutil.WriteFile(source, archive[:len(archive)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "out")
+	if err := extractArchiveFile(source, dest, nil); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Fatalf("partial entry was left behind: %v", entries)
+	}
+}
+
+func TestExtractArchiveFile_pathTraversal(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		{"tar-parent.tar.gz", testTarGz(t, []testArchiveEntry{{name: "../evil.txt", body: "evil"}})},
+		{"tar-nested.tar.gz", testTarGz(t, []testArchiveEntry{{name: "sub/../../evil.txt", body: "evil"}})},
+		{"tar-absolute.tar.gz", testTarGz(t, []testArchiveEntry{{name: "/evil.txt", body: "evil"}})},
+		{"tar-symlink.tar.gz", testTarGz(t, []testArchiveEntry{{name: "link", linkname: "../"}})},
+		{"zip-parent.zip", testZip(t, []testArchiveEntry{{name: "../evil.txt", body: "evil"}})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			source := filepath.Join(dir, tt.name)
+			if err := ioutil.WriteFile(source, tt.archive, 0644); err != nil {
+				t.Fatal(err)
+			}
+			dest := filepath.Join(dir, "out", "extract")
+			if err := extractArchiveFile(source, dest, nil); err == nil {
+				t.Fatalf("expected an error")
+			}
+			if _, err := os.Stat(filepath.Join(dir, "out", "evil.txt")); !os.IsNotExist(err) {
+				t.Fatalf("entry was written outside of the destination")
+			}
+		})
+	}
+}
+
+func TestDetectArchiveFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want archiveFormat
+	}{
+		{"file.bin", []byte("PK\x03\x04rest"), archiveZip},
+		{"file.bin", []byte{0x1f, 0x8b, 0x08}, archiveTarGz},
+		{"file.tgz", nil, archiveTarGz},
+		{"FILE.TAR", nil, archiveTar},
+		{"file.txt", []byte("hello"), archiveUnknown},
+	}
+	for _, tt := range tests {
+		if got := detectArchiveFormat(tt.name, tt.head); got != tt.want {
+			t.Errorf("detectArchiveFormat(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
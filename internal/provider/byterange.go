@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+func rangeStartSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:          schema.TypeInt,
+		Optional:      true,
+		Default:       0,
+		ForceNew:      true,
+		ValidateFunc:  validation.IntAtLeast(0),
+		ConflictsWith: []string{"extract"},
+		Description:   "Offset of the first byte to download. With range_start or range_end set, a GET sends a Range header and only that part of the url is written to filename, so content_sha256 is the hash of the part.",
+	}
+}
+
+func rangeEndSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:          schema.TypeInt,
+		Optional:      true,
+		Default:       -1,
+		ForceNew:      true,
+		ValidateFunc:  validation.IntAtLeast(-1),
+		ConflictsWith: []string{"extract"},
+		Description:   "Offset of the last byte to download, inclusive, such as 511 for the first 512 bytes. -1 downloads to the end of the url.",
+	}
+}
+
+// byteRange returns the range_start and range_end of data, and whether they
+// ask for less than the whole url. end is -1 for a range to the end.
+func byteRange(data resourceGetter) (start, end int64, ok bool) {
+	s, _ := data.Get("range_start").(int)
+	e, isSet := data.Get("range_end").(int)
+	if !isSet {
+		e = -1
+	}
+	return int64(s), int64(e), s > 0 || e >= 0
+}
+
+func validateByteRange(data resourceGetter) error {
+	if start, end, ok := byteRange(data); ok && end >= 0 && end < start {
+		return fmt.Errorf("range_end (%d) is before range_start (%d)", end, start)
+	}
+	return nil
+}
+
+// setRange sets the Range header of a GET request for the range of data.
+func setRange(req *http.Request, data resourceGetter) {
+	start, end, ok := byteRange(data)
+	if !ok || req.Method != http.MethodGet {
+		return
+	}
+	if end < 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", start))
+		return
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+}
+
+// checkRangeResponse checks the response to a ranged request. A 206 must
+// have the Content-Range that was asked for. A server that ignores Range
+// sends the whole body with a 200, so the body is cut down to the range and
+// a warning is returned.
+func checkRangeResponse(req *http.Request, resp *http.Response, data resourceGetter) diag.Diagnostics {
+	start, end, ok := byteRange(data)
+	if !ok || req.Header.Get("Range") == "" {
+		return nil
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		var first, last int64
+		var total string
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%s", &first, &last, &total); err != nil || first != start || (end >= 0 && last > end) {
+			return diagResponseError(resp, "the server returned Content-Range %q for the requested %s", resp.Header.Get("Content-Range"), req.Header.Get("Range"))
+		}
+		return nil
+	case http.StatusOK:
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, start); err != nil {
+			return diag.FromErr(fmt.Errorf("could not skip to range_start (%d) of the response from %q: %w", start, req.URL.Redacted(), err))
+		}
+		body := io.Reader(resp.Body)
+		if end >= 0 {
+			body = io.LimitReader(resp.Body, end-start+1)
+		}
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{body, resp.Body}
+		resp.ContentLength = -1
+		return diag.Diagnostics{{
+			Severity:      diag.Warning,
+			Summary:       "the server ignored the Range header",
+			Detail:        fmt.Sprintf("%q returned %s instead of 206 Partial Content for %s, so the requested bytes were read from the whole body. The server may not support range requests.", req.URL.Redacted(), resp.Status, req.Header.Get("Range")),
+			AttributePath: cty.GetAttrPath("range_start"),
+		}}
+	}
+	return nil
+}
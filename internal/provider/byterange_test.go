@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testRangeContent = "hello world!"
+
+func TestEnsureDownloadFile_range(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end int
+		want       string
+		wantRange  string
+	}{
+		{"slice", 6, 10, "world", "bytes=6-10"},
+		{"prefix", 0, 4, "hello", "bytes=0-4"},
+		{"to the end", 6, -1, "world!", "bytes=6-"},
+		{"past the end", 6, 100, "world!", "bytes=6-100"},
+		{"whole", 0, -1, testRangeContent, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRange string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRange = r.Header.Get("Range")
+				http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader([]byte(testRangeContent)))
+			}))
+			defer srv.Close()
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":         srv.URL,
+				"filename":    dest,
+				"range_start": tt.start,
+				"range_end":   tt.end,
+			})
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			if len(diags) > 0 {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if gotRange != tt.wantRange {
+				t.Fatalf("unexpected Range header %q, want %q", gotRange, tt.wantRange)
+			}
+			assertRangeDownload(t, data, dest, tt.want)
+		})
+	}
+}
+
+func TestEnsureDownloadFile_rangeIgnored(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testRangeContent))
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":         srv.URL,
+		"filename":    dest,
+		"range_start": 6,
+		"range_end":   10,
+	})
+	diags := ensureDownloadFile(context.Background(), data, 0, nil)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || diags[0].Summary != "the server ignored the Range header" {
+		t.Fatalf("expected a warning about the ignored range, got %v", diags)
+	}
+	assertRangeDownload(t, data, dest, "world")
+}
+
+func TestEnsureDownloadFile_rangeMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-4/12")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":         srv.URL,
+		"filename":    dest,
+		"range_start": 6,
+		"range_end":   10,
+	})
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); !diags.HasError() {
+		t.Fatal("expected a Content-Range of other bytes to fail")
+	}
+	if _, err := ioutil.ReadFile(dest); err == nil {
+		t.Fatal("the wrong range was written")
+	}
+}
+
+func TestValidateByteRange(t *testing.T) {
+	tests := []struct {
+		start, end int
+		wantErr    bool
+	}{
+		{0, -1, false},
+		{0, 0, false},
+		{5, 5, false},
+		{5, -1, false},
+		{5, 4, true},
+	}
+	for _, tt := range tests {
+		data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+			"url":         "https://example.com",
+			"filename":    "dest-file",
+			"range_start": tt.start,
+			"range_end":   tt.end,
+		})
+		if err := validateByteRange(data); (err != nil) != tt.wantErr {
+			t.Errorf("range %d-%d: unexpected error %v", tt.start, tt.end, err)
+		}
+	}
+}
+
+func TestMakeRequest_rangeNotForChecksum(t *testing.T) {
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":          "https://example.com/file",
+		"filename":     "dest-file",
+		"checksum_url": "https://example.com/SHA256SUMS",
+		"range_start":  0,
+		"range_end":    511,
+	})
+	req, err := makeRequest(http.MethodGet, data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Range"); got != "bytes=0-511" {
+		t.Fatalf("unexpected Range header %q", got)
+	}
+	req, err = makeRequestURL(http.MethodGet, "https://example.com/SHA256SUMS", checksumRequestData{data}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Range"); got != "" {
+		t.Fatalf("unexpected Range header %q for checksum_url", got)
+	}
+}
+
+func assertRangeDownload(t *testing.T, data *schema.ResourceData, dest, want string) {
+	t.Helper()
+	if b, _ := ioutil.ReadFile(dest); string(b) != want {
+		t.Fatalf("unexpected content %q, want %q", b, want)
+	}
+	sum := sha256.Sum256([]byte(want))
+	if got := data.Get("content_sha256").(string); got != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected content_sha256 %q", got)
+	}
+}
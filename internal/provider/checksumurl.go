@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// maxChecksumFileSize bounds the checksum file read from checksum_url. A
+// SHA256SUMS file for a release is a few kilobytes.
+const maxChecksumFileSize = 1 << 20
+
+// checksumRequestData hides the validators, body, accept and range of the
+// download, which do not apply to the request for checksum_url.
+type checksumRequestData struct {
+	resourceGetter
+}
+
+func (d checksumRequestData) GetOk(key string) (interface{}, bool) {
+	switch key {
+	case "etag", "last_modified", "request_body", "accept":
+		return nil, false
+	}
+	return d.resourceGetter.GetOk(key)
+}
+
+func (d checksumRequestData) Get(key string) interface{} {
+	switch key {
+	case "range_start", "range_end":
+		return nil
+	}
+	return d.resourceGetter.Get(key)
+}
+
+// fetchChecksum downloads checksum_url with the headers and auth of the
+// resource, and returns the SHA256 hash it lists for the url.
+func fetchChecksum(ctx context.Context, data resourceGetter, cfg *providerConfig) (string, diag.Diagnostics) {
+	rawURL := data.Get("checksum_url").(string)
+	content, diags := readChecksumURL(ctx, rawURL, data, cfg)
+	if diags.HasError() {
+		return "", diags
+	}
+	name, err := downloadName(data.Get("url").(string), cfg)
+	if err != nil {
+		return "", append(diags, diag.FromErr(err)...)
+	}
+	hash, err := parseChecksumFile(content, name)
+	if err != nil {
+		return "", append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("could not read the checksum from %q", rawURL),
+			Detail:   err.Error(),
+		})
+	}
+	return hash, diags
+}
+
+func readChecksumURL(ctx context.Context, rawURL string, data resourceGetter, cfg *providerConfig) ([]byte, diag.Diagnostics) {
+	if source, ok, err := localSource(rawURL, cfg); err != nil {
+		return nil, diag.FromErr(err)
+	} else if ok {
+		content, err := ioutil.ReadFile(source)
+		if err != nil {
+			return nil, diag.FromErr(fmt.Errorf("could not read checksum file %q: %w", source, err))
+		}
+		return content, nil
+	}
+	req, err := makeRequestURL(http.MethodGet, rawURL, checksumRequestData{data}, cfg)
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+	c, diags := newHTTPClient(data, cfg)
+	if diags.HasError() {
+		return nil, diags
+	}
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, append(diags, diag.FromErr(fmt.Errorf("error making request to %q: %w", req.URL.Redacted(), err))...)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, append(diags, diagResponseError(resp, "the checksum_url returned an unexpected response code: %s", resp.Status)...)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxChecksumFileSize+1))
+	if err != nil {
+		return nil, append(diags, diag.FromErr(fmt.Errorf("could not read checksum file from %q: %w", req.URL.Redacted(), err))...)
+	}
+	if len(content) > maxChecksumFileSize {
+		return nil, append(diags, diag.Errorf("the checksum file at %q is larger than %d bytes", req.URL.Redacted(), maxChecksumFileSize)...)
+	}
+	return content, diags
+}
+
+// downloadName returns the file name at the end of the path of rawURL,
+// which is the name the checksum file lists.
+func downloadName(rawURL string, cfg *providerConfig) (string, error) {
+	source, err := cfg.resolveURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", err
+	}
+	return path.Base(u.Path), nil
+}
+
+// parseChecksumFile returns the SHA256 hash for name from a checksum file in
+// the format written by sha256sum: one "<hash>  <file>" entry per line, with
+// a "*" before the file name in binary mode. A file with a single entry, or
+// only a bare hash, is used whatever name it lists.
+func parseChecksumFile(content []byte, name string) (string, error) {
+	var hashes []string
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		hash := strings.ToLower(fields[0])
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != 32 {
+			return "", fmt.Errorf("%q is not a SHA256 hash", fields[0])
+		}
+		if len(fields) > 1 {
+			file := strings.TrimPrefix(strings.Join(fields[1:], " "), "*")
+			if path.Base(file) == name {
+				return hash, nil
+			}
+		}
+		hashes = append(hashes, hash)
+	}
+	switch len(hashes) {
+	case 0:
+		return "", fmt.Errorf("the checksum file is empty")
+	case 1:
+		return hashes[0], nil
+	}
+	return "", fmt.Errorf("the checksum file does not list %q", name)
+}
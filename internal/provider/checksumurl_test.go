@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	hashHello   = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	hashGoodbye = "82e35a63ceba37e9646434c5dd412ea577147f1e4a41ccde1614253187e3dbf9"
+)
+
+func TestParseChecksumFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"bare hash", hashHello + "\n", hashHello, false},
+		{"single entry", hashHello + "  other.tar.gz\n", hashHello, false},
+		{"matching entry", hashGoodbye + "  other.tar.gz\n" + hashHello + " *dist/file.tar.gz\n", hashHello, false},
+		{"upper case", strings.ToUpper(hashHello) + "  file.tar.gz", hashHello, false},
+		{"comments", "# release 1.0\n\n" + hashHello + "  file.tar.gz\n", hashHello, false},
+		{"missing entry", hashGoodbye + "  a\n" + hashHello + "  b\n", "", true},
+		{"not a hash", "d41d8cd98f00b204e9800998ecf8427e  file.tar.gz\n", "", true},
+		{"empty", "\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChecksumFile([]byte(tt.content), "file.tar.gz")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_checksumURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/file.tar.gz", testURLHandler(t, "./testdata/source-file01"))
+	sidecar := func(content string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// the checksum is fetched with the headers of the download
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, content)
+		}
+	}
+	mux.Handle("/good.sha256", sidecar(hashHello+"  file.tar.gz\n"))
+	mux.Handle("/bad.sha256", sidecar(hashGoodbye+"  file.tar.gz\n"))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	tests := []struct {
+		name        string
+		checksumURL string
+		wantErr     string
+	}{
+		{"match", srv.URL + "/good.sha256", ""},
+		{"mismatch", srv.URL + "/bad.sha256", "checksum mismatch"},
+		{"missing", srv.URL + "/missing.sha256", "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "file.tar.gz")
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":          srv.URL + "/file.tar.gz",
+				"filename":     filename,
+				"headers":      map[string]interface{}{"Authorization": "Bearer secret"},
+				"checksum_url": tt.checksumURL,
+			})
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			if tt.wantErr == "" {
+				if diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				if got := data.Get("checksum_sha256").(string); got != hashHello {
+					t.Fatalf("unexpected checksum_sha256 %q", got)
+				}
+				if b, _ := ioutil.ReadFile(filename); string(b) != "hello" {
+					t.Fatalf("unexpected content %q", b)
+				}
+				return
+			}
+			if !diags.HasError() || !strings.Contains(fmt.Sprint(diags), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, diags)
+			}
+			if _, err := os.Stat(filename); !os.IsNotExist(err) {
+				t.Fatalf("expected no download, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// downloadChunks returns the number of ranged requests to download resp
+// with, or 1 to read it as a single stream. Ranges are only used when the
+// server advertises them for a GET of the whole url with a known length,
+// and the body is written as it is.
+func downloadChunks(data *schema.ResourceData, req *http.Request, resp *http.Response) int {
+	n := data.Get("parallel_chunks").(int)
+	if n <= 1 || req.Method != http.MethodGet || data.Get("extract").(bool) || req.Header.Get("Range") != "" {
+		return 1
+	}
+	if !strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes") || resp.ContentLength <= 0 {
+		return 1
+	}
+	if ce := resp.Header.Get("Content-Encoding"); ce != "" && !strings.EqualFold(ce, "identity") {
+		// the ranges would be of the encoded body
+		return 1
+	}
+	if int64(n) > resp.ContentLength {
+		n = int(resp.ContentLength)
+	}
+	return n
+}
+
+// writeChunkedDownload downloads the body of resp into dest with n
+// parallel ranged requests, each written to its offset of the file. The
+// first range is read from resp itself. The assembled file is hashed and
+// checked like a single stream before it is put in place.
+func writeChunkedDownload(ctx context.Context, c *http.Client, req *http.Request, resp *http.Response, n int, data *schema.ResourceData, dest string, mode os.FileMode, cfg *providerConfig) error {
+	size := resp.ContentLength
+	if limit := int64(data.Get("max_bytes").(int)); limit > 0 && size > limit {
+		return fmt.Errorf("the response body is %d bytes, which exceeds max_bytes (%d)", size, limit)
+	}
+	if err := ensureParentDir(data, dest); err != nil {
+		return err
+	}
+	flag, err := openFlags(data, dest)
+	if err != nil {
+		return err
+	}
+	if mode == 0 {
+		mode = os.FileMode(0644)
+	}
+	logEvent("DEBUG", "downloading url in chunks", "url", req.URL.Redacted(), "chunks", n, "bytes", size)
+	return writeFileAtomic(dest, mode, flag, func(w io.Writer) error {
+		f, ok := w.(*os.File)
+		if !ok {
+			return fmt.Errorf("cannot write %q at offsets", dest)
+		}
+		if err := f.Truncate(size); err != nil {
+			return fmt.Errorf("could not allocate %d bytes for %q: %w", size, dest, err)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		chunkSize := (size + int64(n) - 1) / int64(n)
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			start := int64(i) * chunkSize
+			end := start + chunkSize
+			if end > size {
+				end = size
+			}
+			wg.Add(1)
+			go func(i int, start, end int64) {
+				defer wg.Done()
+				var body io.ReadCloser
+				if i == 0 {
+					body = resp.Body
+				} else if body, errs[i] = requestRange(ctx, c, req, resp, data, start, end); errs[i] != nil {
+					cancel()
+					return
+				}
+				defer body.Close()
+				if errs[i] = copyChunk(io.NewOffsetWriter(f, start), body, end-start, cfg.newCopyBuffer()); errs[i] != nil {
+					errs[i] = fmt.Errorf("error reading bytes %d-%d into %q: %w", start, end-1, dest, errs[i])
+					cancel()
+				}
+			}(i, start, end)
+		}
+		wg.Wait()
+		// the first error cancels the other chunks, so it is reported rather
+		// than the cancellations it caused
+		var first error
+		for _, err := range errs {
+			if err != nil && (first == nil || errors.Is(first, context.Canceled) && !errors.Is(err, context.Canceled)) {
+				first = err
+			}
+		}
+		if first != nil {
+			return first
+		}
+		tr, err := newSHA256Reader(io.NewSectionReader(f, 0, size), data)
+		if err != nil {
+			return err
+		}
+		if _, err := copyBuffer(ioutil.Discard, tr, cfg.newCopyBuffer()); err != nil {
+			return err
+		}
+		data.Set("content_sha256", tr.Sum())
+		data.Set("content_hash", tr.ContentHash())
+		return nil
+	})
+}
+
+// requestRange requests bytes start to end, exclusive, of the url of req.
+// If-Range makes sure the range is of the same content as resp. The request
+// is signed again, since its headers differ from the signed ones of req.
+func requestRange(ctx context.Context, c *http.Client, req *http.Request, resp *http.Response, data resourceGetter, start, end int64) (io.ReadCloser, error) {
+	r := req.Clone(ctx)
+	r.Header.Del("If-None-Match")
+	r.Header.Del("If-Modified-Since")
+	r.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end-1))
+	// If-Range needs a strong validator
+	if tag, weak := normalizeETag(resp.Header.Get("ETag")); tag != "" && !weak {
+		r.Header.Set("If-Range", tag)
+	} else if modified := resp.Header.Get("Last-Modified"); modified != "" {
+		r.Header.Set("If-Range", modified)
+	}
+	if err := signRequest(r, data); err != nil {
+		return nil, err
+	}
+	rangeResp, err := c.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting bytes %d-%d of %q: %w", start, end-1, req.URL.Redacted(), err)
+	}
+	if rangeResp.StatusCode != http.StatusPartialContent {
+		rangeResp.Body.Close()
+		// a 200 means If-Range did not match, so the content changed
+		return nil, fmt.Errorf("request for bytes %d-%d of %q returned %s instead of 206 Partial Content", start, end-1, req.URL.Redacted(), rangeResp.Status)
+	}
+	want := fmt.Sprintf("bytes %d-%d/%d", start, end-1, resp.ContentLength)
+	if got := rangeResp.Header.Get("Content-Range"); got != want {
+		rangeResp.Body.Close()
+		return nil, fmt.Errorf("request for bytes %d-%d of %q returned Content-Range %q, expected %q", start, end-1, req.URL.Redacted(), got, want)
+	}
+	// a server that ignores If-Range still sends the ETag of what it served
+	if ifRange, got := r.Header.Get("If-Range"), rangeResp.Header.Get("ETag"); strings.HasPrefix(ifRange, `"`) && got != "" && !etagStrongMatch(ifRange, got) {
+		rangeResp.Body.Close()
+		return nil, fmt.Errorf("request for bytes %d-%d of %q returned ETag %s, expected %s: the content changed during the download", start, end-1, req.URL.Redacted(), got, ifRange)
+	}
+	return rangeResp.Body, nil
+}
+
+// copyChunk copies exactly n bytes of body to w.
+func copyChunk(w io.Writer, body io.Reader, n int64, buf []byte) error {
+	copied, err := copyBuffer(w, io.LimitReader(body, n), buf)
+	if err != nil {
+		return err
+	}
+	if copied != n {
+		return fmt.Errorf("truncated: got %d bytes, expected %d", copied, n)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEnsureDownloadFile_parallelChunks(t *testing.T) {
+	content := make([]byte, 1<<20+7)
+	for i := range content {
+		content[i] = byte(i * 31 % 251)
+	}
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	var mu sync.Mutex
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		if r.URL.Path == "/no-ranges" {
+			w.Write(content)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		// serves Accept-Ranges, ranges and If-Range
+		http.ServeContent(w, r, "file", modified, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name       string
+		path       string
+		expected   string
+		wantRanges int
+		wantErr    string
+	}{
+		{"ranges", "/file", "", 3, ""},
+		{"no ranges", "/no-ranges", "", 0, ""},
+		{"checksum mismatch", "/file", hashHello, 3, "checksum mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges = nil
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":             srv.URL + tt.path,
+				"filename":        dest,
+				"parallel_chunks": 4,
+				"expected_sha256": tt.expected,
+			})
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			var withRange int
+			for _, r := range ranges {
+				if r != "" {
+					withRange++
+				}
+			}
+			if withRange != tt.wantRanges {
+				t.Fatalf("expected %d ranged requests, got %q", tt.wantRanges, ranges)
+			}
+			if tt.wantErr != "" {
+				if !diags.HasError() || !strings.Contains(diags[0].Summary, tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, diags)
+				}
+				if _, err := os.Stat(dest); !os.IsNotExist(err) {
+					t.Fatal("the destination was written")
+				}
+				return
+			}
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			b, err := ioutil.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, content) {
+				t.Fatal("the downloaded file does not match")
+			}
+			if got := data.Get("content_sha256").(string); got != hashBytes(content) {
+				t.Fatalf("unexpected content_sha256 %q", got)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_parallelChunksChanged(t *testing.T) {
+	// the content changes after the first response, so the ranges no longer
+	// match it
+	var mu sync.Mutex
+	version := "v1"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		v := version
+		version = "v2"
+		mu.Unlock()
+		w.Header().Set("ETag", `"`+v+`"`)
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(strings.Repeat(v, 1000)))
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":             srv.URL,
+		"filename":        dest,
+		"parallel_chunks": 2,
+	})
+	diags := ensureDownloadFile(context.Background(), data, 0, nil)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "instead of 206 Partial Content") {
+		t.Fatalf("expected a changed content error, got %v", diags)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatal("the destination was written")
+	}
+}
+
+func TestEnsureDownloadFile_parallelChunksIfRangeIgnored(t *testing.T) {
+	// the content changes after the first response and the server serves
+	// the ranges of the new content regardless of If-Range
+	var mu sync.Mutex
+	version := "v1"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		v := version
+		version = "v2"
+		mu.Unlock()
+		r.Header.Del("If-Range")
+		w.Header().Set("ETag", `"`+v+`"`)
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(strings.Repeat(v, 1000)))
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":             srv.URL,
+		"filename":        dest,
+		"parallel_chunks": 2,
+	})
+	diags := ensureDownloadFile(context.Background(), data, 0, nil)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "the content changed during the download") {
+		t.Fatalf("expected a changed content error, got %v", diags)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatal("the destination was written")
+	}
+}
+
+func TestEnsureDownloadFile_parallelChunksSigV4(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	var mu sync.Mutex
+	var ranges int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validSigV4(t, r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("Range") != "" {
+			mu.Lock()
+			ranges++
+			mu.Unlock()
+		}
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	// the stored etag is sent with the first request only
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":             srv.URL + "/bucket/file",
+		"filename":        dest,
+		"etag":            `"v1"`,
+		"parallel_chunks": 3,
+		"aws_sigv4": []interface{}{map[string]interface{}{
+			"access_key": testAWSCredentials.accessKey,
+			"secret_key": testAWSCredentials.secretKey,
+			"region":     "us-east-1",
+		}},
+	})
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if ranges != 2 {
+		t.Fatalf("expected 2 ranged requests, got %d", ranges)
+	}
+	if b, _ := ioutil.ReadFile(dest); !bytes.Equal(b, content) {
+		t.Fatal("the downloaded file does not match")
+	}
+}
+
+// validSigV4 signs the headers that r claims to have signed again, and
+// reports whether that gives the Authorization of r.
+func validSigV4(t *testing.T, r *http.Request) bool {
+	t.Helper()
+	m := regexp.MustCompile(`SignedHeaders=([a-z0-9;-]+),`).FindStringSubmatch(r.Header.Get("Authorization"))
+	if m == nil {
+		return false
+	}
+	now, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	signed, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range strings.Split(m[1], ";") {
+		if v, ok := r.Header[http.CanonicalHeaderKey(name)]; ok {
+			signed.Header[http.CanonicalHeaderKey(name)] = v
+		}
+	}
+	if err := signV4(signed, testAWSCredentials, "us-east-1", "s3", now); err != nil {
+		t.Fatal(err)
+	}
+	return signed.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
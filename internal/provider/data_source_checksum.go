@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"os"
+)
+
+func dataSourceChecksum() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceChecksumRead,
+		Schema:      dataSourceChecksumSchema(),
+	}
+}
+
+func dataSourceChecksumSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"path": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "Path of the file to hash",
+		},
+		"algorithm": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      "sha256",
+			ValidateFunc: validation.StringInSlice(hashAlgorithmNames(), false),
+			Description:  "Hash algorithm: one of md5, sha1, sha256 or sha512",
+		},
+		"hash": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Hex encoded hash of the file contents",
+		},
+		"size": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "Size of the file in bytes",
+		},
+	}
+}
+
+func dataSourceChecksumRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	path := configFromMeta(m).resolvePath(data.Get("path").(string))
+	newHash, err := newHashFunc(data.Get("algorithm").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return diag.Errorf("file %q does not exist", path)
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not stat file %q: %w", path, err))
+	}
+	if stat.IsDir() {
+		return diag.Errorf("%q is a directory", path)
+	}
+	hash, err := hashFileWith(path, newHash)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	id, err := fileToID(path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	data.Set("hash", hash)
+	data.Set("size", stat.Size())
+	return nil
+}
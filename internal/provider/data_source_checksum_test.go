@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAccDataSourceChecksum(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "synclocal" {
+}
+
+data "synclocal_checksum" "hello" {
+	path      = "./testdata/source-file01"
+	algorithm = "md5"
+}
+`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.synclocal_checksum.hello", "hash", "5d41402abc4b2a76b9719d911017c592"),
+					resource.TestCheckResourceAttr("data.synclocal_checksum.hello", "size", "5"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceChecksumRead(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		path      string
+		want      string
+		wantErr   string
+	}{
+		{"md5", "./testdata/source-file01", "5d41402abc4b2a76b9719d911017c592", ""},
+		{"sha1", "./testdata/source-file01", "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", ""},
+		{"sha256", "./testdata/source-file01", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", ""},
+		{"sha512", "./testdata/source-file01", "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043", ""},
+		{"crc32", "./testdata/source-file01", "", "unknown hash algorithm"},
+		{"sha256", filepath.Join(t.TempDir(), "missing"), "", "does not exist"},
+		{"sha256", "./testdata/source-dir", "", "is a directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm+"-"+filepath.Base(tt.path), func(t *testing.T) {
+			data := schema.TestResourceDataRaw(t, dataSourceChecksumSchema(), map[string]interface{}{
+				"path":      tt.path,
+				"algorithm": tt.algorithm,
+			})
+			diags := dataSourceChecksumRead(context.Background(), data, nil)
+			if tt.wantErr != "" {
+				if !diags.HasError() || !strings.Contains(diags[0].Summary, tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, diags)
+				}
+				return
+			}
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if got := data.Get("hash").(string); got != tt.want {
+				t.Fatalf("unexpected hash %q, want %q", got, tt.want)
+			}
+			if got := data.Get("size").(int); got != 5 {
+				t.Fatalf("unexpected size %d", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+)
+
+func dataSourceFile() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceFileRead,
+		Schema:      dataSourceFileSchema(),
+	}
+}
+
+func dataSourceFileSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"path": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "Path of the file to read",
+		},
+		"exists": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Whether the file exists. The other attributes are empty if it does not.",
+		},
+		"content_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash of the file contents",
+		},
+		"file_mode": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "File mode of the file (Octal String)",
+		},
+		"size": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "Size of the file in bytes",
+		},
+	}
+}
+
+func dataSourceFileRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	path := configFromMeta(m).resolvePath(data.Get("path").(string))
+	id, err := fileToID(path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		data.Set("exists", false)
+		data.Set("content_sha256", "")
+		data.Set("file_mode", "")
+		data.Set("size", 0)
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not stat file %q: %w", path, err))
+	}
+	if stat.IsDir() {
+		return diag.FromErr(fmt.Errorf("%q is a directory", path))
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.Set("exists", true)
+	data.Set("content_sha256", hash)
+	data.Set("file_mode", fmt.Sprintf("%04o", stat.Mode().Perm()))
+	data.Set("size", stat.Size())
+	return nil
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+	"syscall"
+	"time"
+)
+
+func dataSourceFileExists() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceFileExistsRead,
+		Schema:      dataSourceFileExistsSchema(),
+	}
+}
+
+func dataSourceFileExistsSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"path": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "Path of the file or directory to check",
+		},
+		"exists": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Whether the path exists. The other attributes are empty if it does not.",
+		},
+		"is_dir": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Whether the path is a directory",
+		},
+		"size": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "Size of the file in bytes",
+		},
+		"modified": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Modification time of the path in RFC 3339 format",
+		},
+	}
+}
+
+func dataSourceFileExistsRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	path := configFromMeta(m).resolvePath(data.Get("path").(string))
+	id, err := fileToID(path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	stat, err := os.Stat(path)
+	// a parent that is a file also means the path does not exist
+	if os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR) {
+		data.Set("exists", false)
+		data.Set("is_dir", false)
+		data.Set("size", 0)
+		data.Set("modified", "")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not stat %q: %w", path, err))
+	}
+	data.Set("exists", true)
+	data.Set("is_dir", stat.IsDir())
+	data.Set("size", stat.Size())
+	data.Set("modified", stat.ModTime().UTC().Format(time.RFC3339))
+	return nil
+}
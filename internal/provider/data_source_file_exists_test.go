@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDataSourceFileExistsRead(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(file, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		path         string
+		wantExists   bool
+		wantDir      bool
+		wantSize     int
+		wantModified string
+	}{
+		{"file", file, true, false, 5, "2020-01-02T03:04:05Z"},
+		{"directory", dir, true, true, -1, ""},
+		{"missing", filepath.Join(dir, "missing"), false, false, 0, ""},
+		{"below a file", filepath.Join(file, "child"), false, false, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := schema.TestResourceDataRaw(t, dataSourceFileExistsSchema(), map[string]interface{}{
+				"path": tt.path,
+			})
+			if diags := dataSourceFileExistsRead(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if data.Id() == "" {
+				t.Errorf("expected an id")
+			}
+			if got := data.Get("exists").(bool); got != tt.wantExists {
+				t.Errorf("unexpected exists %v", got)
+			}
+			if got := data.Get("is_dir").(bool); got != tt.wantDir {
+				t.Errorf("unexpected is_dir %v", got)
+			}
+			// the size of a directory depends on the file system
+			if got := data.Get("size").(int); tt.wantSize >= 0 && got != tt.wantSize {
+				t.Errorf("unexpected size %d", got)
+			}
+			got := data.Get("modified").(string)
+			switch {
+			case tt.wantModified != "" && got != tt.wantModified:
+				t.Errorf("unexpected modified %q", got)
+			case tt.wantExists && got == "":
+				t.Errorf("expected modified to be set")
+			case !tt.wantExists && got != "":
+				t.Errorf("unexpected modified %q for a missing path", got)
+			}
+		})
+	}
+}
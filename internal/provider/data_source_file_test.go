@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"path/filepath"
+	"testing"
+)
+
+func TestAccDataSourceFile(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "synclocal" {
+}
+
+data "synclocal_file" "hello" {
+	path = "./testdata/source-file01"
+}
+
+data "synclocal_file" "missing" {
+	path = "./testdata/does-not-exist"
+}
+`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.synclocal_file.hello", "exists", "true"),
+					resource.TestCheckResourceAttr("data.synclocal_file.hello", "content_sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"),
+					resource.TestCheckResourceAttr("data.synclocal_file.hello", "size", "5"),
+					resource.TestCheckResourceAttr("data.synclocal_file.missing", "exists", "false"),
+					resource.TestCheckResourceAttr("data.synclocal_file.missing", "content_sha256", ""),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceFileRead(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		wantExists bool
+		wantHash   string
+		wantSize   int
+	}{
+		{"exists", "./testdata/source-file02", true, "82e35a63ceba37e9646434c5dd412ea577147f1e4a41ccde1614253187e3dbf9", 7},
+		{"missing", filepath.Join(t.TempDir(), "missing"), false, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := schema.TestResourceDataRaw(t, dataSourceFileSchema(), map[string]interface{}{
+				"path": tt.path,
+			})
+			if diags := dataSourceFileRead(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if got := data.Get("exists").(bool); got != tt.wantExists {
+				t.Errorf("unexpected exists %v", got)
+			}
+			if got := data.Get("content_sha256").(string); got != tt.wantHash {
+				t.Errorf("unexpected content_sha256 %q", got)
+			}
+			if got := data.Get("size").(int); got != tt.wantSize {
+				t.Errorf("unexpected size %d", got)
+			}
+			if tt.wantExists && data.Get("file_mode").(string) == "" {
+				t.Errorf("expected file_mode to be set")
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+func dataSourceHTTP() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceHTTPRead,
+		Schema:      dataSourceHTTPSchema(),
+	}
+}
+
+func dataSourceHTTPSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"url": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "url to fetch",
+		},
+		"headers": {
+			Type:        schema.TypeMap,
+			Optional:    true,
+			Description: "HTTP headers to send with the request",
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"accept": acceptSchema(),
+		"bearer_token": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			Sensitive:     true,
+			ConflictsWith: []string{"username"},
+			Description:   "Token sent in a bearer Authorization header",
+		},
+		"username": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			RequiredWith: []string{"password"},
+			Description:  "Username for HTTP basic authentication",
+		},
+		"password": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Sensitive:    true,
+			RequiredWith: []string{"username"},
+			Description:  "Password for HTTP basic authentication",
+		},
+		"insecure_skip_verify": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Skip verification of the server TLS certificate. Only use this for testing.",
+		},
+		"max_redirects": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      10,
+			ValidateFunc: validation.IntAtLeast(0),
+			Description:  "Maximum number of redirects to follow. Set to 0 to disable redirects.",
+		},
+		"timeout":     timeoutSchema(),
+		"max_retries": maxRetriesSchema(),
+		"retry_wait":  retryWaitSchema(),
+		"force_body": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Populate body even if the response Content-Type is not textual",
+		},
+		"allow_any_status": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Do not fail on a non-2xx response status",
+		},
+		"body": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Response body. Only populated for textual content types unless force_body is set.",
+		},
+		"content_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash of the response body",
+		},
+		"status_code": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "HTTP status code of the response",
+		},
+		"response_headers": {
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Description: "Response headers. Repeated headers are joined with a comma.",
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+	}
+}
+
+func dataSourceHTTPRead(ctx context.Context, data *schema.ResourceData, m interface{}) (diags diag.Diagnostics) {
+	req, err := makeRequest(http.MethodGet, data, configFromMeta(m))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if v, ok := data.GetOk("bearer_token"); ok {
+		req.Header.Set("Authorization", "Bearer "+v.(string))
+	}
+	if v, ok := data.GetOk("username"); ok {
+		req.SetBasicAuth(v.(string), data.Get("password").(string))
+	}
+	c, diags := newHTTPClient(data, configFromMeta(m))
+	if diags.HasError() {
+		return diags
+	}
+	diags = append(diags, acceptWarning(data)...)
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return append(diags, diag.FromErr(fmt.Errorf("error making request to %q: %w", req.URL, err))...)
+	}
+	defer resp.Body.Close()
+	if (resp.StatusCode < 200 || resp.StatusCode > 299) && !data.Get("allow_any_status").(bool) {
+		return append(diags, diagResponseError(resp, "request to %q returned an unexpected response code: %s", req.URL, resp.Status)...)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return append(diags, diag.FromErr(fmt.Errorf("could not read response body from %q: %w", req.URL, err))...)
+	}
+	headers := make(map[string]interface{}, len(resp.Header))
+	for k, v := range resp.Header {
+		headers[k] = strings.Join(v, ", ")
+	}
+	var body string
+	if isTextual(resp.Header.Get("Content-Type")) || data.Get("force_body").(bool) {
+		body = string(b)
+	}
+	data.Set("body", body)
+	data.Set("content_sha256", hashBytes(b))
+	data.Set("status_code", resp.StatusCode)
+	data.Set("response_headers", headers)
+	data.SetId(req.URL.String())
+	return diags
+}
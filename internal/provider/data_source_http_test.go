@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccDataSourceHTTP(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "synclocal" {
+}
+
+data "synclocal_http" "hello" {
+	url          = "%s"
+	bearer_token = "secret"
+}
+`, srv.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.synclocal_http.hello", "body", "hello"),
+					resource.TestCheckResourceAttr("data.synclocal_http.hello", "content_sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"),
+					resource.TestCheckResourceAttr("data.synclocal_http.hello", "status_code", "200"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceHTTPRead(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+			w.Write([]byte(`{"a":1}`))
+		case "/binary":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0x00, 0x01})
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("missing"))
+		}
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name       string
+		path       string
+		raw        map[string]interface{}
+		wantErr    bool
+		wantBody   string
+		wantStatus int
+	}{
+		{name: "textual", path: "/text", wantBody: `{"a":1}`, wantStatus: 200},
+		{name: "binary", path: "/binary", wantBody: "", wantStatus: 200},
+		{name: "forced binary", path: "/binary", raw: map[string]interface{}{"force_body": true}, wantBody: "\x00\x01", wantStatus: 200},
+		{name: "not found", path: "/missing", wantErr: true},
+		{name: "allow not found", path: "/missing", raw: map[string]interface{}{"allow_any_status": true}, wantBody: "missing", wantStatus: 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{
+				"url":      srv.URL + tt.path,
+				"username": "user",
+				"password": "pass",
+			}
+			for k, v := range tt.raw {
+				raw[k] = v
+			}
+			data := schema.TestResourceDataRaw(t, dataSourceHTTPSchema(), raw)
+			diags := dataSourceHTTPRead(context.Background(), data, nil)
+			if diags.HasError() != tt.wantErr {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := data.Get("body").(string); got != tt.wantBody {
+				t.Errorf("unexpected body %q, want %q", got, tt.wantBody)
+			}
+			if got := data.Get("status_code").(int); got != tt.wantStatus {
+				t.Errorf("unexpected status_code %d, want %d", got, tt.wantStatus)
+			}
+			if got := data.Get("content_sha256").(string); got == "" {
+				t.Errorf("expected content_sha256 to be set")
+			}
+		})
+	}
+}
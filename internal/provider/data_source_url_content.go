@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net/http"
+)
+
+func dataSourceURLContent() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceURLContentRead,
+		Schema:      dataSourceURLContentSchema(),
+	}
+}
+
+// urlContentRequestKeys are the attributes of synclocal_url that
+// synclocal_url_content shares, so requests are made the same way.
+var urlContentRequestKeys = []string{
+	"headers",
+	"cookies",
+	"user_agent",
+	"aws_sigv4",
+	"oauth2",
+	"insecure_skip_verify",
+	"unix_socket",
+	"resolve",
+	"max_redirects",
+	"timeout",
+	"max_retries",
+	"retry_wait",
+}
+
+func dataSourceURLContentSchema() map[string]*schema.Schema {
+	s := map[string]*schema.Schema{
+		"url": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "url to check. A relative url is resolved against the provider base_url.",
+		},
+		"method": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      http.MethodGet,
+			ValidateFunc: validation.StringInSlice([]string{http.MethodGet, http.MethodHead}, false),
+			Description:  "GET to hash the response body, or HEAD to only read the headers",
+		},
+		"if_none_match": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "ETag sent in If-None-Match, such as the etag of a previous read. The server may answer 304 Not Modified instead of sending the content.",
+		},
+		"if_modified_since": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "HTTP date sent in If-Modified-Since, such as the last_modified of a previous read",
+		},
+		"content_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash of the response body. The body is hashed as it is received and not stored. Empty for a HEAD request or a 304 response.",
+		},
+		"etag": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "ETag of the resource. A 304 response without one keeps if_none_match.",
+		},
+		"last_modified": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Last-Modified date of the resource. A 304 response without one keeps if_modified_since.",
+		},
+		"content_length": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "Size of the resource in bytes: the size of the body for a GET, or the Content-Length of a HEAD. -1 if it is not known.",
+		},
+		"content_type": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Content-Type of the resource",
+		},
+	}
+	resource := resourceURLSchema()
+	for _, k := range urlContentRequestKeys {
+		s[k] = resource[k]
+	}
+	return s
+}
+
+func dataSourceURLContentRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	cfg := configFromMeta(m)
+	c, diags := newHTTPClient(data, cfg)
+	if diags.HasError() {
+		return diags
+	}
+	method := data.Get("method").(string)
+	req, err := makeRequest(method, data, cfg)
+	if err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	if v, ok := data.GetOk("if_none_match"); ok {
+		req.Header.Set("If-None-Match", v.(string))
+	}
+	if v, ok := data.GetOk("if_modified_since"); ok {
+		req.Header.Set("If-Modified-Since", v.(string))
+	}
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return append(diags, diag.FromErr(fmt.Errorf("error making request to %q: %w", req.URL, err))...)
+	}
+	defer resp.Body.Close()
+	var hash string
+	length := resp.ContentLength
+	etag := resp.Header.Get("ETag")
+	modified := normalizeHTTPDate(resp.Header.Get("Last-Modified"))
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		// the validators that were sent still describe the resource
+		length = -1
+		if etag == "" {
+			etag = data.Get("if_none_match").(string)
+		}
+		if modified == "" {
+			modified = data.Get("if_modified_since").(string)
+		}
+	case http.StatusOK:
+		if method == http.MethodHead {
+			break
+		}
+		h := sha256.New()
+		n, err := copyBuffer(h, resp.Body, cfg.newCopyBuffer())
+		if err != nil {
+			return append(diags, diag.FromErr(fmt.Errorf("error reading response from %q: %w", req.URL, err))...)
+		}
+		hash, length = hex.EncodeToString(h.Sum(nil)), n
+	default:
+		return append(diags, diagResponseError(resp, "request to %q returned an unexpected response code: %s", req.URL, resp.Status)...)
+	}
+	data.SetId(req.URL.String())
+	data.Set("content_sha256", hash)
+	data.Set("etag", etag)
+	data.Set("last_modified", modified)
+	data.Set("content_length", int(length))
+	data.Set("content_type", resp.Header.Get("Content-Type"))
+	return diags
+}
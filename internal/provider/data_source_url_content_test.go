@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestAccDataSourceURLContent(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "synclocal" {
+}
+
+data "synclocal_url_content" "file" {
+	url     = "%s"
+	headers = {
+		Authorization = "Bearer secret"
+	}
+}
+`, srv.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.synclocal_url_content.file", "content_sha256", hashHello),
+					resource.TestCheckResourceAttr("data.synclocal_url_content.file", "content_length", "5"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceURLContentRead(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	auth := map[string]interface{}{"Authorization": "Bearer secret"}
+	etag := strconv.Quote(hashHello)
+	tests := []struct {
+		name       string
+		raw        map[string]interface{}
+		wantErr    bool
+		wantHash   string
+		wantLength int
+		// the test handler sends no Last-Modified with a 304
+		wantModified bool
+	}{
+		{"get", map[string]interface{}{"url": srv.URL, "headers": auth}, false, hashHello, 5, true},
+		{"head", map[string]interface{}{"url": srv.URL, "headers": auth, "method": "HEAD"}, false, "", -1, true},
+		{"changed", map[string]interface{}{"url": srv.URL, "headers": auth, "if_none_match": `"old"`}, false, hashHello, 5, true},
+		{"not modified", map[string]interface{}{"url": srv.URL, "headers": auth, "if_none_match": etag}, false, "", -1, false},
+		{"unauthorized", map[string]interface{}{"url": srv.URL}, true, "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := schema.TestResourceDataRaw(t, dataSourceURLContentSchema(), tt.raw)
+			diags := dataSourceURLContentRead(context.Background(), data, nil)
+			if diags.HasError() != tt.wantErr {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := data.Get("content_sha256").(string); got != tt.wantHash {
+				t.Errorf("unexpected content_sha256 %q", got)
+			}
+			if got := data.Get("content_length").(int); got != tt.wantLength {
+				t.Errorf("unexpected content_length %d", got)
+			}
+			// a 304 keeps the etag that was sent, so it is the same either way
+			if got := data.Get("etag").(string); got != etag {
+				t.Errorf("unexpected etag %q", got)
+			}
+			if got := data.Get("last_modified").(string); (got != "") != tt.wantModified {
+				t.Errorf("unexpected last_modified %q", got)
+			}
+		})
+	}
+}
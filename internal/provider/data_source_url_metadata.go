@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func dataSourceURLMetadata() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceURLMetadataRead,
+		Schema:      dataSourceURLMetadataSchema(),
+	}
+}
+
+func dataSourceURLMetadataSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"url": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "url to inspect. A relative url is resolved against the provider base_url.",
+		},
+		"headers": {
+			Type:        schema.TypeMap,
+			Optional:    true,
+			Description: "HTTP headers to send with the request",
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"fallback_to_get": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "If the server does not support HEAD, read the metadata from a GET request for the first byte instead",
+		},
+		"allow_error": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Do not fail on a non-2xx response status",
+		},
+		"insecure_skip_verify": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Skip verification of the server TLS certificate. Only use this for testing.",
+		},
+		"max_redirects": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      10,
+			ValidateFunc: validation.IntAtLeast(0),
+			Description:  "Maximum number of redirects to follow. Set to 0 to disable redirects.",
+		},
+		"timeout":     timeoutSchema(),
+		"max_retries": maxRetriesSchema(),
+		"retry_wait":  retryWaitSchema(),
+		"status_code": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "HTTP status code of the response",
+		},
+		"etag": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "ETag of the resource",
+		},
+		"content_length": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "Size of the resource in bytes, or -1 if the server did not send it",
+		},
+		"content_type": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Content-Type of the resource",
+		},
+		"last_modified": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Last-Modified date of the resource",
+		},
+	}
+}
+
+func dataSourceURLMetadataRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	cfg := configFromMeta(m)
+	c, diags := newHTTPClient(data, cfg)
+	if diags.HasError() {
+		return diags
+	}
+	req, err := makeRequest(http.MethodHead, data, cfg)
+	if err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return append(diags, diag.FromErr(fmt.Errorf("error making request to %q: %w", req.URL, err))...)
+	}
+	defer resp.Body.Close()
+	if (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) && data.Get("fallback_to_get").(bool) {
+		resp.Body.Close()
+		if req, err = makeRequest(http.MethodGet, data, cfg); err != nil {
+			return append(diags, diag.FromErr(err)...)
+		}
+		req.Header.Set("Range", "bytes=0-0")
+		if resp, err = c.Do(req.WithContext(ctx)); err != nil {
+			return append(diags, diag.FromErr(fmt.Errorf("error making request to %q: %w", req.URL, err))...)
+		}
+		// the body is not needed, only the headers
+		defer resp.Body.Close()
+	}
+	if (resp.StatusCode < 200 || resp.StatusCode > 299) && !data.Get("allow_error").(bool) {
+		return append(diags, diagResponseError(resp, "request to %q returned an unexpected response code: %s", req.URL, resp.Status)...)
+	}
+	data.SetId(req.URL.String())
+	data.Set("status_code", resp.StatusCode)
+	data.Set("etag", resp.Header.Get("ETag"))
+	data.Set("content_length", int(responseLength(resp)))
+	data.Set("content_type", resp.Header.Get("Content-Type"))
+	data.Set("last_modified", normalizeHTTPDate(resp.Header.Get("Last-Modified")))
+	return diags
+}
+
+// responseLength returns the size of the resource. For a partial response
+// it is the complete length from Content-Range, if the server sent it.
+func responseLength(resp *http.Response) int64 {
+	if resp.StatusCode != http.StatusPartialContent {
+		return resp.ContentLength
+	}
+	cr := resp.Header.Get("Content-Range")
+	i := strings.LastIndexByte(cr, '/')
+	if i < 0 {
+		return -1
+	}
+	n, err := strconv.ParseInt(cr[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
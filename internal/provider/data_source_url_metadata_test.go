@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccDataSourceURLMetadata(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "synclocal" {
+}
+
+data "synclocal_url_metadata" "file" {
+	url     = "%s"
+	headers = {
+		Authorization = "Bearer secret"
+	}
+}
+`, srv.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.synclocal_url_metadata.file", "status_code", "200"),
+					resource.TestCheckResourceAttr("data.synclocal_url_metadata.file", "content_length", "5"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceURLMetadataRead(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if r.Header.Get("Range") != "bytes=0-0" {
+				t.Errorf("expected a ranged GET, got Range %q", r.Header.Get("Range"))
+			}
+			w.Header().Set("Content-Range", "bytes 0-0/1234")
+			w.Header().Set("ETag", `"ranged"`)
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("x"))
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Length", "42")
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		if r.Method != http.MethodHead {
+			t.Errorf("expected a HEAD request, got %s", r.Method)
+		}
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name       string
+		raw        map[string]interface{}
+		wantErr    bool
+		wantStatus int
+		wantLength int
+		wantETag   string
+	}{
+		{"head", map[string]interface{}{"url": srv.URL + "/file"}, false, 200, 42, `"v1"`},
+		{"no head", map[string]interface{}{"url": srv.URL + "/no-head"}, true, 0, 0, ""},
+		{"fallback", map[string]interface{}{"url": srv.URL + "/no-head", "fallback_to_get": true}, false, 206, 1234, `"ranged"`},
+		{"error", map[string]interface{}{"url": srv.URL + "/missing"}, true, 0, 0, ""},
+		{"allow error", map[string]interface{}{"url": srv.URL + "/missing", "allow_error": true}, false, 404, -1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := schema.TestResourceDataRaw(t, dataSourceURLMetadataSchema(), tt.raw)
+			diags := dataSourceURLMetadataRead(context.Background(), data, nil)
+			if diags.HasError() != tt.wantErr {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := data.Get("status_code").(int); got != tt.wantStatus {
+				t.Errorf("unexpected status_code %d", got)
+			}
+			if got := data.Get("content_length").(int); got != tt.wantLength {
+				t.Errorf("unexpected content_length %d", got)
+			}
+			if got := data.Get("etag").(string); got != tt.wantETag {
+				t.Errorf("unexpected etag %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/ulikunitz/xz"
+	"io"
+	"os"
+)
+
+// decompression is the decompress algorithm of a synclocal_file. The zero
+// value copies the source as it is.
+type decompression string
+
+const (
+	decompressNone  decompression = "none"
+	decompressGzip  decompression = "gzip"
+	decompressBzip2 decompression = "bzip2"
+	decompressXz    decompression = "xz"
+	decompressAuto  decompression = "auto"
+)
+
+var decompressions = []string{
+	string(decompressNone),
+	string(decompressGzip),
+	string(decompressBzip2),
+	string(decompressXz),
+	string(decompressAuto),
+}
+
+func decompressionFrom(data resourceGetter) decompression {
+	v, _ := data.Get("decompress").(string)
+	if v == "" {
+		return decompressNone
+	}
+	return decompression(v)
+}
+
+// detectCompression identifies the compression of content from its magic
+// bytes, and returns decompressNone if it is not one that is supported.
+func detectCompression(head []byte) decompression {
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return decompressGzip
+	case bytes.HasPrefix(head, []byte("BZh")):
+		return decompressBzip2
+	case bytes.HasPrefix(head, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return decompressXz
+	}
+	return decompressNone
+}
+
+// wrap returns r decompressed. auto detects the algorithm from the first
+// bytes of r, and returns r unchanged if it is not compressed.
+func (d decompression) wrap(r io.Reader) (io.Reader, error) {
+	if d == decompressAuto {
+		br := bufio.NewReader(r)
+		// the longest magic is the 6 bytes of xz
+		head, err := br.Peek(6)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		d, r = detectCompression(head), br
+	}
+	switch d {
+	case decompressGzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("not valid gzip data: %w", err)
+		}
+		return zr, nil
+	case decompressBzip2:
+		return bzip2.NewReader(r), nil
+	case decompressXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("not valid xz data: %w", err)
+		}
+		return xr, nil
+	}
+	return r, nil
+}
+
+// hashFile returns the SHA256 hash of filename after it is decompressed and
+// its line endings are converted by le, which is the content_sha256 a copy
+// of it would have.
+func (d decompression) hashFile(filename string, le lineEndings) (string, error) {
+	if d == decompressNone && !le.convert {
+		return hashFile(filename)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	r, err := d.wrap(f)
+	if err != nil {
+		return "", fmt.Errorf("could not read %q: %w", filename, err)
+	}
+	if r, err = le.wrap(r); err != nil {
+		return "", fmt.Errorf("could not read %q: %w", filename, err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("could not read %q: %w", filename, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnsureCopyFile_decompress(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		decompress string
+	}{
+		{"gzip", "./testdata/source-file02.gz", "gzip"},
+		{"bzip2", "./testdata/source-file02.bz2", "bzip2"},
+		{"xz", "./testdata/source-file02.xz", "xz"},
+		{"auto gzip", "./testdata/source-file02.gz", "auto"},
+		{"auto bzip2", "./testdata/source-file02.bz2", "auto"},
+		{"auto xz", "./testdata/source-file02.xz", "auto"},
+		{"auto uncompressed", "./testdata/source-file02", "auto"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest")
+			// copying twice checks the decompressed destination is seen as
+			// up to date
+			for i := 0; i < 2; i++ {
+				data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+					"source":      tt.source,
+					"destination": dest,
+					"decompress":  tt.decompress,
+					"backup":      true,
+				})
+				if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				if b, _ := ioutil.ReadFile(dest); string(b) != "goodbye" {
+					t.Fatalf("unexpected content %q", b)
+				}
+				if i == 1 {
+					if data.Get("backup_path").(string) != "" {
+						t.Fatal("destination was copied again")
+					}
+					continue
+				}
+				if got := data.Get("content_sha256").(string); got != hashGoodbye {
+					t.Fatalf("unexpected content_sha256 %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestEnsureCopyFile_decompressInvalid(t *testing.T) {
+	dir := t.TempDir()
+	corrupt, err := ioutil.ReadFile("./testdata/source-file02.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// flip a byte of the deflate stream so the header is fine but the
+	// checksum of the content is not
+	corrupt[len(corrupt)-10] ^= 0xff
+	corruptFile := filepath.Join(dir, "corrupt.gz")
+	if err := ioutil.WriteFile(corruptFile, corrupt, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		source     string
+		decompress string
+		wantErr    string
+	}{
+		{"not gzip", "./testdata/source-file02", "gzip", "not valid gzip data"},
+		{"not xz", "./testdata/source-file02.gz", "xz", "not valid xz data"},
+		{"not bzip2", "./testdata/source-file02", "bzip2", "bzip2"},
+		{"corrupt gzip", corruptFile, "gzip", "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest")
+			data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+				"source":      tt.source,
+				"destination": dest,
+				"decompress":  tt.decompress,
+			})
+			diags := ensureCopyFile(context.Background(), data, nil)
+			if !diags.HasError() {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(diags[0].Summary, tt.wantErr) {
+				t.Fatalf("unexpected error %q", diags[0].Summary)
+			}
+			if _, err := os.Stat(dest); !os.IsNotExist(err) {
+				t.Fatal("the destination was written")
+			}
+		})
+	}
+	if _, errs := resourceFileSchema()["decompress"].ValidateFunc("zstd", "decompress"); len(errs) == 0 {
+		t.Fatal("expected zstd to be rejected")
+	}
+}
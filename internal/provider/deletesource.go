@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+)
+
+// sourceMoved reports whether delete_source already removed source after it
+// was copied to dest. There is nothing left to compare the destination with,
+// so it is kept as it is.
+func sourceMoved(data resourceGetter, source, dest string) bool {
+	if v, _ := data.Get("delete_source").(bool); !v || source == "" {
+		return false
+	}
+	if _, err := os.Lstat(source); !os.IsNotExist(err) {
+		return false
+	}
+	_, err := os.Stat(dest)
+	return err == nil
+}
+
+// deleteSource removes source once dest is confirmed to have the content
+// sha256 it was copied with. Nothing is removed when they are the same file.
+// source_sha256 is recorded first, since it cannot be read again.
+func deleteSource(data *schema.ResourceData, cfg *providerConfig, source, dest, sha256 string) error {
+	if v, _ := data.Get("delete_source").(bool); !v {
+		return nil
+	}
+	srcStat, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("could not stat source %q: %w", source, err)
+	}
+	destStat, err := os.Stat(dest)
+	if err != nil {
+		return fmt.Errorf("could not stat destination %q: %w", dest, err)
+	}
+	if os.SameFile(srcStat, destStat) {
+		logEvent("DEBUG", "source is the destination, not deleting it", "source", source, "destination", dest)
+		return nil
+	}
+	destHash, err := hashFile(dest)
+	if err != nil {
+		return fmt.Errorf("could not verify destination %q: %w", dest, err)
+	}
+	if destHash != sha256 {
+		return fmt.Errorf("destination %q does not have the copied content, so source %q was not deleted", dest, source)
+	}
+	sourceHash, err := sourceSHA256(data, cfg)
+	if err != nil {
+		return err
+	}
+	data.Set("source_sha256", sourceHash)
+	if err := os.Remove(source); err != nil {
+		return fmt.Errorf("could not delete source %q: %w", source, err)
+	}
+	logEvent("INFO", "deleted source", "source", source, "destination", dest)
+	return nil
+}
@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResourceFileCreate_deleteSource(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	dest := filepath.Join(dir, "dest")
+	if err := ioutil.WriteFile(source, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	raw := map[string]interface{}{
+		"source":        source,
+		"destination":   dest,
+		"delete_source": true,
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), raw)
+	if diags := resourceFileCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("unexpected content %q", b)
+	}
+	if _, err := os.Stat(source); !os.IsNotExist(err) {
+		t.Fatalf("source was not deleted: %v", err)
+	}
+	if got := data.Get("source_sha256").(string); got != hashHello {
+		t.Fatalf("unexpected source_sha256 %q", got)
+	}
+	// without the source, refresh, plan and apply leave the destination be
+	if diags := resourceFileRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if data.Id() == "" {
+		t.Fatal("the destination was removed from state")
+	}
+	if got := data.Get("source_sha256").(string); got != hashHello {
+		t.Fatalf("unexpected source_sha256 %q after refresh", got)
+	}
+	diff, err := resourceFile().Diff(context.Background(), data.State(), terraform.NewResourceConfigRaw(raw), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, key := range []string{"content_sha256", "source_sha256"} {
+		if diff == nil {
+			break
+		}
+		if attr := diff.Attributes[key]; attr != nil {
+			t.Fatalf("expected %s to be unchanged, got %#v", key, attr)
+		}
+	}
+	if diags := resourceFileUpdate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("unexpected content %q after update", b)
+	}
+}
+
+func TestEnsureCopyFile_deleteSourceUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	dest := filepath.Join(dir, "dest")
+	for _, name := range []string{source, dest} {
+		if err := ioutil.WriteFile(name, []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":        source,
+		"destination":   dest,
+		"delete_source": true,
+	})
+	if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if _, err := os.Stat(source); !os.IsNotExist(err) {
+		t.Fatalf("source was not deleted: %v", err)
+	}
+}
+
+func TestEnsureCopyFile_deleteSourceSamePath(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	if err := ioutil.WriteFile(source, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dest := range []string{source, filepath.Join(dir, ".", "source")} {
+		data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+			"source":        source,
+			"destination":   dest,
+			"delete_source": true,
+		})
+		if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if b, _ := ioutil.ReadFile(source); string(b) != "hello" {
+			t.Fatalf("source copied onto itself was removed or changed: %q", b)
+		}
+	}
+}
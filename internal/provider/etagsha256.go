@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"hash"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// etagSHA256Matches reports whether etag_is_sha256 is set and a HEAD request
+// of rawURL returns the ETag of the stored content_sha256, while the
+// destination still has that hash. The GET can then be skipped, since the
+// content is already there. A HEAD that fails is only logged, since the GET
+// and mirror_urls may still succeed.
+func etagSHA256Matches(ctx context.Context, data *schema.ResourceData, rawURL string, mode os.FileMode, cfg *providerConfig) (bool, error) {
+	if !data.Get("etag_is_sha256").(bool) || data.Get("method").(string) != http.MethodGet || data.Get("extract").(bool) || data.Get("force_download").(bool) {
+		return false, nil
+	}
+	if _, _, ok := byteRange(data); ok {
+		// the etag is of the whole url, not of the range
+		return false, nil
+	}
+	stored := data.Get("content_sha256").(string)
+	if stored == "" {
+		// content_sha256 is unknown during an update, so the value of the
+		// previous download is used
+		old, _ := data.GetChange("content_sha256")
+		stored = old.(string)
+	}
+	if stored == "" {
+		return false, nil
+	}
+	if _, ok, err := localSource(rawURL, cfg); err != nil || ok {
+		return false, err
+	}
+	dest := cfg.resolvePath(data.Get(destinationKey(data)).(string))
+	if fileHash, err := hashFile(dest); err != nil || fileHash != stored {
+		return false, nil
+	}
+	req, err := makeRequestURL(http.MethodHead, rawURL, data, cfg)
+	if err != nil {
+		return false, err
+	}
+	c, diags := newHTTPClient(data, cfg)
+	if diags.HasError() {
+		return false, fmt.Errorf("%s", diags[0].Summary)
+	}
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		logEvent("DEBUG", "etag check failed, downloading the url", "url", req.URL.Redacted(), "error", err)
+		return false, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
+		logEvent("DEBUG", "etag check returned an unexpected response code", "url", req.URL.Redacted(), "status", resp.StatusCode)
+		return false, nil
+	}
+	if !etagIsSHA256(resp.Header.Get("ETag"), stored) {
+		return false, nil
+	}
+	contentHash, err := contentHashWith(data, stored, func(newHash func() hash.Hash) (string, error) {
+		return hashFileWith(dest, newHash)
+	})
+	if err != nil {
+		return false, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(dest, mode); err != nil {
+			return false, fmt.Errorf("failed to chmod %s %q: %w", mode, dest, err)
+		}
+	}
+	logEvent("INFO", "etag matches content_sha256, skipping download", "url", req.URL.Redacted(), "destination", dest)
+	if v := resp.Header.Get("ETag"); v != "" {
+		data.Set("etag", v)
+	}
+	data.Set("content_sha256", stored)
+	data.Set("content_hash", contentHash)
+	return true, nil
+}
+
+// etagIsSHA256 reports whether the entity tag etag is the hex encoded
+// sha256, ignoring its quotes and a weak prefix.
+func etagIsSHA256(etag, sha256 string) bool {
+	tag, _ := normalizeETag(etag)
+	tag = strings.Trim(tag, `"`)
+	return tag != "" && strings.EqualFold(tag, sha256)
+}
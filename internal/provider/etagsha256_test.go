@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestETagIsSHA256(t *testing.T) {
+	tests := []struct {
+		etag string
+		want bool
+	}{
+		{`"` + hashHello + `"`, true},
+		{`W/"` + hashHello + `"`, true},
+		{hashHello, true},
+		{`"` + hashGoodbye + `"`, false},
+		{`""`, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := etagIsSHA256(tt.etag, hashHello); got != tt.want {
+			t.Errorf("etagIsSHA256(%q) = %v, want %v", tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestEnsureDownloadFile_etagIsSHA256(t *testing.T) {
+	var gets, heads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"`+hashHello+`"`)
+		if r.Method == http.MethodHead {
+			heads++
+			return
+		}
+		gets++
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":            srv.URL,
+		"filename":       dest,
+		"etag_is_sha256": true,
+	})
+	// nothing is stored to compare the etag with yet
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if gets != 1 || heads != 0 {
+		t.Fatalf("expected a single GET, got %d GET and %d HEAD", gets, heads)
+	}
+	if got := data.Get("content_sha256").(string); got != hashHello {
+		t.Fatalf("unexpected content_sha256 %q", got)
+	}
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if gets != 1 || heads != 1 {
+		t.Fatalf("expected the download to be skipped after a HEAD, got %d GET and %d HEAD", gets, heads)
+	}
+	if got := data.Get("content_sha256").(string); got != hashHello {
+		t.Fatalf("unexpected content_sha256 %q after skipping the download", got)
+	}
+	// a destination that no longer has the hash is downloaded again
+	if err := ioutil.WriteFile(dest, []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if gets != 2 || heads != 1 {
+		t.Fatalf("expected a second GET, got %d GET and %d HEAD", gets, heads)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("unexpected content %q", b)
+	}
+}
+
+func TestEnsureDownloadFile_etagIsNotSHA256(t *testing.T) {
+	var gets, heads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Method == http.MethodHead {
+			heads++
+			return
+		}
+		gets++
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":            srv.URL,
+		"filename":       dest,
+		"etag_is_sha256": true,
+		"force_download": false,
+	})
+	for i := 0; i < 2; i++ {
+		if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+	}
+	if gets != 2 || heads != 1 {
+		t.Fatalf("expected the etag mismatch to download, got %d GET and %d HEAD", gets, heads)
+	}
+}
+
+func TestEnsureDownloadFile_etagCheckFails(t *testing.T) {
+	var gets, heads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			heads++
+			// drop the connection without a response
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		gets++
+		w.Header().Set("ETag", `"`+hashHello+`"`)
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":            srv.URL,
+		"filename":       dest,
+		"etag_is_sha256": true,
+	})
+	for i := 0; i < 2; i++ {
+		if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+	}
+	if gets != 2 || heads == 0 {
+		t.Fatalf("expected the failed etag check to download, got %d GET and %d HEAD", gets, heads)
+	}
+}
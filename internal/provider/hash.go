@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"hash"
+	"io"
+	"os"
+	"sort"
+)
+
+// hashAlgorithms are the supported checksum algorithms by name. md5 and sha1
+// are only offered to compare against upstream checksums that use them.
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// hashAlgorithmNames returns the names of hashAlgorithms in order.
+func hashAlgorithmNames() []string {
+	names := make([]string, 0, len(hashAlgorithms))
+	for name := range hashAlgorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newHashFunc(algorithm string) (func() hash.Hash, error) {
+	newHash, ok := hashAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash algorithm %q, expected one of %v", algorithm, hashAlgorithmNames())
+	}
+	return newHash, nil
+}
+
+// hashFileWith returns the hex encoded hash of the contents of filename
+// using a hash from newHash.
+func hashFileWith(filename string, newHash func() hash.Hash) (string, error) {
+	h := newHash()
+	fd, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+	if _, err := io.Copy(h, fd); err != nil {
+		return "", fmt.Errorf("could not hash file %q: %w", filename, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashAlgorithmSchema(forceNew bool) *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      "sha256",
+		ForceNew:     forceNew,
+		ValidateFunc: validation.StringInSlice(hashAlgorithmNames(), false),
+		Description:  "Hash algorithm used for content_hash: one of md5, sha1, sha256 or sha512",
+	}
+}
+
+func contentHashSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeString,
+		Computed:    true,
+		Description: "Hash of the contents using hash_algorithm. content_sha256 is kept as well, since it is used to detect changes.",
+	}
+}
+
+// contentHashWith returns the content_hash for hash_algorithm. sha256Hash is
+// reused when the algorithm is sha256; otherwise hashWith computes it.
+func contentHashWith(data resourceGetter, sha256Hash string, hashWith func(newHash func() hash.Hash) (string, error)) (string, error) {
+	algorithm := data.Get("hash_algorithm").(string)
+	if algorithm == "sha256" {
+		return sha256Hash, nil
+	}
+	newHash, err := newHashFunc(algorithm)
+	if err != nil {
+		return "", err
+	}
+	return hashWith(newHash)
+}
+
+// setContentComputed marks the content hashes as changing in the plan.
+func setContentComputed(diff *schema.ResourceDiff) error {
+	if err := diff.SetNewComputed("content_sha256"); err != nil {
+		return err
+	}
+	return diff.SetNewComputed("content_hash")
+}
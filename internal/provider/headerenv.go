@@ -0,0 +1,29 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// headerEnvPattern matches a header value that is only a reference to an
+// environment variable.
+var headerEnvPattern = regexp.MustCompile(`^\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// expandHeader returns the value of header name to send. With
+// header_env_expansion a value of ${env:NAME} is read from the environment
+// when the request is made, so it is never in the plan or state.
+func (c *providerConfig) expandHeader(name, value string) (string, error) {
+	if c == nil || !c.headerEnvExpansion {
+		return value, nil
+	}
+	m := headerEnvPattern.FindStringSubmatch(value)
+	if m == nil {
+		return value, nil
+	}
+	v, ok := os.LookupEnv(m[1])
+	if !ok {
+		return "", fmt.Errorf("header %q references environment variable %s, which is not set", name, m[1])
+	}
+	return v, nil
+}
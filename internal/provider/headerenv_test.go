@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMakeRequest_headerEnvExpansion(t *testing.T) {
+	t.Setenv("SYNCLOCAL_TEST_TOKEN", "Bearer secret")
+	t.Setenv("SYNCLOCAL_TEST_DEFAULT", "from-env")
+	configure := func(expand bool) *providerConfig {
+		meta, diags := providerConfigure("test")(context.Background(), schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+			"header_env_expansion": expand,
+			"default_headers": map[string]interface{}{
+				"X-Default": "${env:SYNCLOCAL_TEST_DEFAULT}",
+			},
+		}))
+		if diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		return meta.(*providerConfig)
+	}
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":         "http://example.com/file",
+		"destination": "dest",
+		"headers": map[string]interface{}{
+			"Authorization": "${env:SYNCLOCAL_TEST_TOKEN}",
+			"X-Literal":     "prefix ${env:SYNCLOCAL_TEST_TOKEN}",
+		},
+	})
+	req, err := makeRequest(http.MethodGet, data, configure(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"Authorization": "Bearer secret",
+		"X-Default":     "from-env",
+		// only a whole value is a reference
+		"X-Literal": "prefix ${env:SYNCLOCAL_TEST_TOKEN}",
+	}
+	for k, v := range want {
+		if got := req.Header.Get(k); got != v {
+			t.Fatalf("unexpected %s header %q, expected %q", k, got, v)
+		}
+	}
+	if got := data.Get("headers.Authorization").(string); got != "${env:SYNCLOCAL_TEST_TOKEN}" {
+		t.Fatalf("the expanded header was stored: %q", got)
+	}
+	// without header_env_expansion the value is sent as it is
+	req, err = makeRequest(http.MethodGet, data, configure(false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "${env:SYNCLOCAL_TEST_TOKEN}" {
+		t.Fatalf("unexpected Authorization header %q", got)
+	}
+}
+
+func TestMakeRequest_headerEnvExpansionUnset(t *testing.T) {
+	meta, diags := providerConfigure("test")(context.Background(), schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"header_env_expansion": true,
+	}))
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":         "http://example.com/file",
+		"destination": "dest",
+		"headers": map[string]interface{}{
+			"Authorization": "${env:SYNCLOCAL_TEST_UNSET_TOKEN}",
+		},
+	})
+	_, err := makeRequest(http.MethodGet, data, meta.(*providerConfig))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "SYNCLOCAL_TEST_UNSET_TOKEN") {
+		t.Fatalf("unexpected error %q", err)
+	}
+}
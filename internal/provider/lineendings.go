@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// sniffLen is the number of bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+// lineEndings is the line_endings conversion of a synclocal_file. The zero
+// value preserves line endings.
+type lineEndings struct {
+	crlf bool
+	// convert is false for "preserve"
+	convert bool
+	// text skips sniffing the content type of the source
+	text bool
+}
+
+func lineEndingsFrom(data resourceGetter) lineEndings {
+	v, _ := data.Get("line_endings").(string)
+	text, _ := data.Get("text").(bool)
+	switch v {
+	case "lf":
+		return lineEndings{convert: true, text: text}
+	case "crlf":
+		return lineEndings{convert: true, crlf: true, text: text}
+	default:
+		return lineEndings{}
+	}
+}
+
+// wrap returns r with its line endings converted, if the first bytes of r
+// look like text or text is set. Other content is returned unchanged.
+func (le lineEndings) wrap(r io.Reader) (io.Reader, error) {
+	if !le.convert {
+		return r, nil
+	}
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !le.text && !isTextual(http.DetectContentType(head)) {
+		return br, nil
+	}
+	return &lineEndingReader{r: br, crlf: le.crlf}, nil
+}
+
+// convertBytes returns b with its line endings converted.
+func (le lineEndings) convertBytes(b []byte) ([]byte, error) {
+	if !le.convert {
+		return b, nil
+	}
+	r, err := le.wrap(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// lineEndingReader rewrites CRLF and LF line endings to LF, or to CRLF if
+// crlf is set. A CR that is not followed by LF is not a line ending and is
+// kept as is.
+type lineEndingReader struct {
+	r    io.Reader
+	crlf bool
+	// cr is set if the last byte read was a CR. Converting to LF holds it
+	// back until the next byte shows whether it starts a CRLF.
+	cr  bool
+	buf []byte
+	out []byte
+	err error
+}
+
+func (l *lineEndingReader) Read(p []byte) (int, error) {
+	for len(l.out) == 0 {
+		if l.err != nil {
+			if l.cr && !l.crlf {
+				l.cr = false
+				l.out = append(l.buf[:0], '\r')
+				break
+			}
+			return 0, l.err
+		}
+		if l.buf == nil {
+			l.buf = make([]byte, 32*1024)
+		}
+		// the converted chunk is at most twice as long, so read half a buffer
+		// and convert into the rest
+		n, err := l.r.Read(l.buf[len(l.buf)/2:])
+		l.out = l.convert(l.buf[:0], l.buf[len(l.buf)/2:len(l.buf)/2+n])
+		l.err = err
+	}
+	n := copy(p, l.out)
+	l.out = l.out[n:]
+	return n, nil
+}
+
+// convert appends the converted src to dst. dst may start in the same
+// buffer before src, as long as there is room for it to double in length,
+// since it never overtakes the unread part of src.
+func (l *lineEndingReader) convert(dst, src []byte) []byte {
+	for _, b := range src {
+		if l.crlf {
+			if b == '\n' && !l.cr {
+				dst = append(dst, '\r')
+			}
+			dst = append(dst, b)
+			l.cr = b == '\r'
+			continue
+		}
+		if l.cr {
+			l.cr = false
+			if b != '\n' {
+				dst = append(dst, '\r')
+			}
+		}
+		if b == '\r' {
+			l.cr = true
+			continue
+		}
+		dst = append(dst, b)
+	}
+	return dst
+}
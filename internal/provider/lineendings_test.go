@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLineEndings_wrap(t *testing.T) {
+	const mixed = "one\r\ntwo\nthree\rfour\r\n\r\nfive\r"
+	tests := []struct {
+		name string
+		le   lineEndings
+		in   string
+		want string
+	}{
+		{"lf", lineEndings{convert: true}, mixed, "one\ntwo\nthree\rfour\n\nfive\r"},
+		{"crlf", lineEndings{convert: true, crlf: true}, mixed, "one\r\ntwo\r\nthree\rfour\r\n\r\nfive\r"},
+		{"preserve", lineEndings{}, mixed, mixed},
+		{"empty", lineEndings{convert: true, crlf: true}, "", ""},
+		{"binary", lineEndings{convert: true, crlf: true}, "\x00\x01\n\x02", "\x00\x01\n\x02"},
+		{"binary text", lineEndings{convert: true, crlf: true, text: true}, "\x00\x01\n\x02", "\x00\x01\r\n\x02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// one byte reads put a CR at the end of every chunk
+			for _, r := range []io.Reader{strings.NewReader(tt.in), iotest.OneByteReader(strings.NewReader(tt.in))} {
+				wrapped, err := tt.le.wrap(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := ioutil.ReadAll(wrapped)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != tt.want {
+					t.Fatalf("unexpected content %q, want %q", b, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLineEndings_roundTrip(t *testing.T) {
+	// larger than the reader buffer so chunks end in the middle of a CRLF
+	in := strings.Repeat("a\r\nbb\ncc\r", 10000)
+	lf := lineEndings{convert: true}
+	crlf := lineEndings{convert: true, crlf: true}
+	toCRLF, err := crlf.convertBytes([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Repeat("a\r\nbb\r\ncc\r", 10000); string(toCRLF) != want {
+		t.Fatal("unexpected crlf content")
+	}
+	toLF, err := lf.convertBytes(toCRLF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Repeat("a\nbb\ncc\r", 10000); string(toLF) != want {
+		t.Fatal("unexpected lf content")
+	}
+	back, err := crlf.convertBytes(toLF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(back) != string(toCRLF) {
+		t.Fatal("crlf content changed after a round trip through lf")
+	}
+}
+
+func TestEnsureCopyFile_lineEndings(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	if err := ioutil.WriteFile(source, []byte("one\r\ntwo\nthree\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		lineEndings string
+		want        string
+	}{
+		{"lf", "one\ntwo\nthree\n"},
+		{"crlf", "one\r\ntwo\r\nthree\r\n"},
+		{"preserve", "one\r\ntwo\nthree\r\n"},
+	}
+	dest := filepath.Join(dir, "dest")
+	for _, tt := range tests {
+		t.Run(tt.lineEndings, func(t *testing.T) {
+			// copying twice checks the converted destination is seen as up to date
+			for i := 0; i < 2; i++ {
+				data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+					"source":       source,
+					"destination":  dest,
+					"line_endings": tt.lineEndings,
+					"backup":       true,
+				})
+				if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				if b, _ := ioutil.ReadFile(dest); string(b) != tt.want {
+					t.Fatalf("unexpected content %q, want %q", b, tt.want)
+				}
+				if i == 1 {
+					if data.Get("backup_path").(string) != "" {
+						t.Fatal("destination was copied again")
+					}
+					continue
+				}
+				if got := data.Get("content_sha256").(string); got != hashBytes([]byte(tt.want)) {
+					t.Fatalf("unexpected content_sha256 %q", got)
+				}
+			}
+		})
+	}
+	t.Run("content", func(t *testing.T) {
+		data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+			"content":      "a\nb\r\n",
+			"destination":  dest,
+			"line_endings": "crlf",
+		})
+		if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if b, _ := ioutil.ReadFile(dest); string(b) != "a\r\nb\r\n" {
+			t.Fatalf("unexpected content %q", b)
+		}
+		if got := data.Get("content_sha256").(string); got != hashBytes([]byte("a\r\nb\r\n")) {
+			t.Fatalf("unexpected content_sha256 %q", got)
+		}
+	})
+}
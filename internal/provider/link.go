@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Values of the link attribute of synclocal_file.
+const (
+	linkCopy     = "copy"
+	linkHardlink = "hardlink"
+	linkSymlink  = "symlink"
+)
+
+// isLinked reports whether dest is the link to source that mode creates: the
+// same file as source for a hardlink, or a symlink that resolves to source.
+// A missing source or destination is not linked.
+func isLinked(mode, source, dest string) (bool, error) {
+	destStat, err := os.Lstat(dest)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not stat %q: %w", dest, err)
+	}
+	switch mode {
+	case linkHardlink:
+		srcStat, err := os.Stat(source)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("could not stat source file %q: %w", source, err)
+		}
+		return os.SameFile(srcStat, destStat), nil
+	case linkSymlink:
+		if destStat.Mode()&os.ModeSymlink == 0 {
+			return false, nil
+		}
+		target, err := os.Readlink(dest)
+		if err != nil {
+			return false, fmt.Errorf("could not read symlink %q: %w", dest, err)
+		}
+		resolved, err := resolveLinkTarget(dest, target)
+		if err != nil {
+			return false, err
+		}
+		abs, err := filepath.Abs(source)
+		if err != nil {
+			return false, err
+		}
+		return resolved == abs, nil
+	}
+	return false, nil
+}
+
+// ensureLink makes dest a hardlink or symlink to source, replacing whatever
+// is there unless overwrite is false.
+func ensureLink(data *schema.ResourceData, source, dest, mode string, flag int) diag.Diagnostics {
+	exclusive := flag&os.O_EXCL != 0
+	if !exclusive {
+		linked, err := isLinked(mode, source, dest)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if !linked {
+			if _, err := os.Lstat(dest); err == nil && !data.Get("overwrite").(bool) {
+				return diag.Errorf("destination %q already exists and is not a %s to %q, and overwrite is false", dest, mode, source)
+			}
+			if err := linkFile(mode, source, dest, false); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+	} else if err := linkFile(mode, source, dest, true); err != nil {
+		return diag.FromErr(err)
+	}
+	hash, err := hashFile(dest)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.Set("content_sha256", hash)
+	return nil
+}
+
+// linkFile creates the link at dest. Unless exclusive, the link is made
+// next to dest and renamed over it, so an existing dest is replaced
+// atomically.
+func linkFile(mode, source, dest string, exclusive bool) error {
+	name := dest
+	if !exclusive {
+		name = filepath.Join(filepath.Dir(dest), fmt.Sprintf(".%s.link-%d", filepath.Base(dest), time.Now().UnixNano()))
+	}
+	switch mode {
+	case linkHardlink:
+		if err := os.Link(source, name); err != nil {
+			if errors.Is(err, errCrossDevice) {
+				return fmt.Errorf("could not hardlink %q to %q: they are on different filesystems. Use link = \"copy\" or \"symlink\" instead", dest, source)
+			}
+			return fmt.Errorf("could not hardlink %q to %q: %w", dest, source, err)
+		}
+	case linkSymlink:
+		// the target is stored absolute, since source is relative to the
+		// working directory rather than to dest
+		target, err := filepath.Abs(source)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(target, name); err != nil {
+			return fmt.Errorf("could not create symlink %q: %w", dest, err)
+		}
+	default:
+		return fmt.Errorf("unknown link mode %q", mode)
+	}
+	if name == dest {
+		return nil
+	}
+	if err := os.Rename(name, dest); err != nil {
+		_ = os.Remove(name)
+		return fmt.Errorf("could not rename %q to %q: %w", name, dest, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestResourceFileCreate_link(t *testing.T) {
+	for _, link := range []string{linkHardlink, linkSymlink} {
+		t.Run(link, func(t *testing.T) {
+			if link == linkSymlink && runtime.GOOS == "windows" {
+				t.Skip("creating symlinks requires extra privileges on windows")
+			}
+			dir := t.TempDir()
+			source := filepath.Join(dir, "source")
+			dest := filepath.Join(dir, "dest")
+			if err := ioutil.WriteFile(source, []byte("hello"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			// an existing file is replaced by the link
+			if err := ioutil.WriteFile(dest, []byte("hello"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+				"source":      source,
+				"destination": dest,
+				"link":        link,
+			})
+			if diags := resourceFileCreate(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if linked, err := isLinked(link, source, dest); err != nil || !linked {
+				t.Fatalf("expected %q to be a %s to %q: %v", dest, link, source, err)
+			}
+			if got := data.Get("content_sha256").(string); got != hashBytes([]byte("hello")) {
+				t.Fatalf("unexpected content_sha256 %q", got)
+			}
+			// the destination follows the source
+			if err := ioutil.WriteFile(source, []byte("goodbye"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if b, _ := ioutil.ReadFile(dest); string(b) != "goodbye" {
+				t.Fatalf("unexpected content %q", b)
+			}
+			if diags := resourceFileRead(context.Background(), data, nil); diags.HasError() || data.Id() == "" {
+				t.Fatalf("expected the link to be kept: %v", diags)
+			}
+			// a copy in place of the link is drift
+			if err := os.Remove(dest); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(dest, []byte("goodbye"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if diags := resourceFileRead(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if data.Id() != "" {
+				t.Fatal("expected the resource to be removed once the link is replaced")
+			}
+		})
+	}
+}
+
+func TestEnsureCopyFile_linkNoOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dest")
+	if err := ioutil.WriteFile(dest, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "./testdata/source-file01",
+		"destination": dest,
+		"link":        linkHardlink,
+		"overwrite":   false,
+	})
+	diags := ensureCopyFile(context.Background(), data, nil)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "overwrite is false") {
+		t.Fatalf("expected an overwrite error, got %v", diags)
+	}
+	if linked, _ := isLinked(linkHardlink, "./testdata/source-file01", dest); linked {
+		t.Fatal("the destination was replaced")
+	}
+}
+
+func TestLinkFile_crossDevice(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "source")
+	if err := ioutil.WriteFile(source, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// /dev/shm is usually a tmpfs, separate from the temp directory
+	dir, err := ioutil.TempDir("/dev/shm", "synclocal")
+	if err != nil {
+		t.Skipf("no second filesystem: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	err = linkFile(linkHardlink, source, filepath.Join(dir, "dest"), false)
+	if err == nil {
+		t.Skip("the temp directory and /dev/shm are on the same filesystem")
+	}
+	if !strings.Contains(err.Error(), "different filesystems") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the temporary link name is not left behind either
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("unexpected files left in %q: %v", dir, entries)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package provider
+
+import "syscall"
+
+// errCrossDevice is returned by os.Link when source and destination are on
+// different filesystems.
+const errCrossDevice = syscall.EXDEV
@@ -0,0 +1,9 @@
+//go:build windows
+
+package provider
+
+import "syscall"
+
+// errCrossDevice is ERROR_NOT_SAME_DEVICE, returned by os.Link when source
+// and destination are on different volumes.
+const errCrossDevice = syscall.Errno(17)
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// logEvent writes msg to the provider log with fields as key=value pairs,
+// in the order given. The SDK forwards log lines to the Terraform log by
+// their [LEVEL] prefix, so TF_LOG=DEBUG shows every level.
+//
+// Values must not be secrets; headers are logged by name only.
+func logEvent(level, msg string, fields ...interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] synclocal: %s", level, msg)
+	for i := 0; i+1 < len(fields); i += 2 {
+		switch v := fields[i+1].(type) {
+		case string:
+			fmt.Fprintf(&b, " %s=%q", fields[i], v)
+		case os.FileMode:
+			fmt.Fprintf(&b, " %s=%04o", fields[i], v.Perm())
+		default:
+			fmt.Fprintf(&b, " %s=%v", fields[i], v)
+		}
+	}
+	log.Print(b.String())
+}
+
+// headerNames returns the sorted names of h, without their values.
+func headerNames(h http.Header) string {
+	names := make([]string, 0, len(h))
+	for k := range h {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += n
+	return n, err
+}
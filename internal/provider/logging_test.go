@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureLog redirects the standard logger to a buffer for the rest of the
+// test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	flags := log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	})
+	return &buf
+}
+
+func checkLogLines(t *testing.T, logs string, want []string, secrets []string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(logs, w) {
+			t.Errorf("log does not contain %q:\n%s", w, logs)
+		}
+	}
+	for _, s := range secrets {
+		if strings.Contains(logs, s) {
+			t.Errorf("log contains the secret %q:\n%s", s, logs)
+		}
+	}
+}
+
+func TestEnsureDownloadFile_logging(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	logs := captureLog(t)
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":         srv.URL + "/file",
+		"filename":    filepath.Join(t.TempDir(), "dest-file"),
+		"file_mode":   "0640",
+		"headers":     map[string]interface{}{"Authorization": "Bearer secret"},
+		"max_retries": 1,
+		"retry_wait":  "1ms",
+	})
+	mode, err := getFileMode(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := ensureDownloadFile(context.Background(), data, mode, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	checkLogLines(t, logs.String(), []string{
+		`[DEBUG] synclocal: sending request method="GET" url="` + srv.URL + `/file" request_headers="Accept,Authorization"`,
+		`[DEBUG] synclocal: retrying request url="` + srv.URL + `/file" attempt=1 max_retries=1 reason="503 Service Unavailable"`,
+		`[DEBUG] synclocal: received response url="` + srv.URL + `/file" status=200 response_headers="Content-Length,Content-Type,Date,Etag"`,
+		`[INFO] synclocal: downloaded url url="` + srv.URL + `/file"`,
+		`bytes=5 file_mode=0640`,
+	}, []string{"Bearer secret"})
+}
+
+func TestEnsureCopyFile_logging(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dest")
+	logs := captureLog(t)
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "./testdata/source-file01",
+		"destination": dest,
+		"file_mode":   "0600",
+	})
+	if diags := resourceFileCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	checkLogLines(t, logs.String(), []string{
+		`[DEBUG] synclocal: copying file source="./testdata/source-file01" destination="` + dest + `"`,
+		`[INFO] synclocal: copied file source="./testdata/source-file01" destination="` + dest + `" bytes=5 file_mode=0600`,
+		`[DEBUG] synclocal: destination is up to date source="./testdata/source-file01" destination="` + dest + `"`,
+	}, nil)
+}
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strings"
+)
+
+func managedSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Computed:    true,
+		Description: "Whether the destination was written by this resource. An imported destination is not managed until an apply changes its content. Destroy only removes a managed destination, and a file that was replaced since it was written is kept.",
+	}
+}
+
+// managedStateUpgraders mark the state of resources created before managed
+// was tracked as managed, which keeps the old behaviour of removing their
+// destination on destroy. Attributes added since then are set to their
+// default, as setImportDefaults does, since a ForceNew attribute missing from
+// the state would otherwise replace every resource on the next plan.
+func managedStateUpgraders(sm map[string]*schema.Schema) []schema.StateUpgrader {
+	return []schema.StateUpgrader{{
+		Version: 0,
+		Type:    (&schema.Resource{Schema: sm}).CoreConfigSchema().ImpliedType(),
+		Upgrade: func(ctx context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+			if rawState == nil {
+				return rawState, nil
+			}
+			rawState["managed"] = true
+			for k, s := range sm {
+				if v, ok := rawState[k]; s.Default != nil && (!ok || v == nil) {
+					rawState[k] = s.Default
+				}
+			}
+			return rawState, nil
+		},
+	}}
+}
+
+// setManaged marks the destination as managed after an update that changed
+// content_sha256, meaning the provider wrote it.
+func setManaged(data *schema.ResourceData, previousSHA256 string) {
+	if data.Get("content_sha256").(string) != previousSHA256 {
+		data.Set("managed", true)
+	}
+}
+
+// keepOnDelete returns a warning if the destination name must not be removed
+// on destroy: it was imported and never written, or it no longer has the
+// content_sha256 that was written. checkHash is false for destinations that
+// content_sha256 is not the hash of, such as directories.
+func keepOnDelete(data *schema.ResourceData, name string, checkHash bool) diag.Diagnostics {
+	if !data.Get("managed").(bool) {
+		return diag.Diagnostics{{
+			Severity:      diag.Warning,
+			Summary:       "destination was not removed",
+			Detail:        fmt.Sprintf("%q was imported and not written by this resource, so it is left in place.", name),
+			AttributePath: cty.GetAttrPath("managed"),
+		}}
+	}
+	want := data.Get("content_sha256").(string)
+	if !checkHash || want == "" {
+		return nil
+	}
+	got, err := hashFile(name)
+	if err != nil || strings.EqualFold(got, want) {
+		// a destination that cannot be hashed is removed as before
+		return nil
+	}
+	return diag.Diagnostics{{
+		Severity: diag.Warning,
+		Summary:  "destination was not removed",
+		Detail:   fmt.Sprintf("%q was replaced since it was written (sha256 %s, expected %s), so it is left in place.", name, got, want),
+	}}
+}
@@ -0,0 +1,244 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResourceDelete_imported(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource *schema.Resource
+		importer schema.StateContextFunc
+		delete   schema.DeleteContextFunc
+	}{
+		{"synclocal_file", resourceFile(), resourceFileImport, resourceFileDelete},
+		{"synclocal_url", resourceURL(), resourceURLImport, resourceURLDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			if err := ioutil.WriteFile(dest, []byte("hello"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			data := tt.resource.TestResourceData()
+			data.SetId(dest)
+			imported, err := tt.importer(context.Background(), data, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if imported[0].Get("managed").(bool) {
+				t.Fatal("imported destination is managed")
+			}
+			diags := tt.delete(context.Background(), imported[0], nil)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if len(diags) == 0 || diags[0].Severity != diag.Warning {
+				t.Fatalf("expected a warning, got %v", diags)
+			}
+			if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+				t.Fatalf("imported destination was removed or changed: %q", b)
+			}
+		})
+	}
+}
+
+func TestResourceFileUpdate_importedWritten(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dest-file")
+	if err := ioutil.WriteFile(dest, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := resourceFile().TestResourceData()
+	data.SetId(dest)
+	imported, err := resourceFileImport(context.Background(), data, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the first apply replaces the content, after which it is managed
+	raw := map[string]interface{}{
+		"source":      "./testdata/source-file02",
+		"destination": dest,
+	}
+	state := imported[0].State()
+	diff, err := resourceFile().Diff(context.Background(), state, terraform.NewResourceConfigRaw(raw), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = schema.InternalMap(resourceFileSchema()).Data(state, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceFileUpdate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if !data.Get("managed").(bool) {
+		t.Fatal("written destination is not managed")
+	}
+	if diags := resourceFileDelete(context.Background(), data, nil); len(diags) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("destination was not removed: %v", err)
+	}
+}
+
+func TestResourceDelete_replacedOutOfBand(t *testing.T) {
+	dir := t.TempDir()
+	fileDest := filepath.Join(dir, "file-dest")
+	fileData := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "./testdata/source-file01",
+		"destination": fileDest,
+	})
+	if diags := resourceFileCreate(context.Background(), fileData, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	urlDest := filepath.Join(dir, "url-dest")
+	urlData := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      srv.URL,
+		"filename": urlDest,
+		"headers":  map[string]interface{}{"Authorization": "Bearer secret"},
+	})
+	if diags := resourceURLCreate(context.Background(), urlData, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	tests := []struct {
+		name   string
+		dest   string
+		data   *schema.ResourceData
+		delete schema.DeleteContextFunc
+	}{
+		{"synclocal_file", fileDest, fileData, resourceFileDelete},
+		{"synclocal_url", urlDest, urlData, resourceURLDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.data.Get("managed").(bool) {
+				t.Fatal("created destination is not managed")
+			}
+			if err := ioutil.WriteFile(tt.dest, []byte("replaced"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			diags := tt.delete(context.Background(), tt.data, nil)
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if len(diags) == 0 || diags[0].Severity != diag.Warning {
+				t.Fatalf("expected a warning, got %v", diags)
+			}
+			if b, _ := ioutil.ReadFile(tt.dest); string(b) != "replaced" {
+				t.Fatalf("replaced destination was removed or changed: %q", b)
+			}
+		})
+	}
+}
+
+func TestManagedStateUpgrader(t *testing.T) {
+	upgraders := resourceFile().StateUpgraders
+	if len(upgraders) != 1 {
+		t.Fatalf("unexpected state upgraders %v", upgraders)
+	}
+	state, err := upgraders[0].Upgrade(context.Background(), map[string]interface{}{"id": "file:///dest"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state["managed"] != true {
+		t.Fatalf("state from before managed was tracked is not managed: %v", state)
+	}
+}
+
+func TestManagedStateUpgrader_noReplace(t *testing.T) {
+	// state written before the schema version was set, with only the
+	// attributes the resources had then
+	tests := []struct {
+		name     string
+		resource *schema.Resource
+		state    map[string]interface{}
+		config   map[string]interface{}
+		defaults []string
+	}{
+		{
+			"synclocal_url",
+			resourceURL(),
+			map[string]interface{}{
+				"id":             "file:///dest",
+				"url":            "https://example.com/file",
+				"filename":       "/dest",
+				"etag":           `"abc"`,
+				"content_sha256": hashHello,
+			},
+			map[string]interface{}{
+				"url":      "https://example.com/file",
+				"filename": "/dest",
+			},
+			[]string{"method", "extract", "range_start", "range_end"},
+		},
+		{
+			"synclocal_file",
+			resourceFile(),
+			map[string]interface{}{
+				"id":             "file:///dest",
+				"source":         "./testdata/source-file01",
+				"destination":    "/dest",
+				"content_sha256": hashHello,
+			},
+			map[string]interface{}{
+				"source":      "./testdata/source-file01",
+				"destination": "/dest",
+			},
+			[]string{"recursive", "source_glob", "link"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := planUpgradedState(t, tt.resource, tt.state, tt.config)
+			if diff.RequiresNew() {
+				t.Fatalf("the upgraded state is replaced: %v", diff)
+			}
+			for _, k := range tt.defaults {
+				if d, ok := diff.Attributes[k]; ok {
+					t.Errorf("unexpected diff of %s: %q => %q", k, d.Old, d.New)
+				}
+			}
+		})
+	}
+}
+
+// planUpgradedState upgrades rawState and plans config against it, as
+// terraform does on the first plan with a new provider version.
+func planUpgradedState(t *testing.T, r *schema.Resource, rawState, config map[string]interface{}) *terraform.InstanceDiff {
+	t.Helper()
+	upgraded, err := r.StateUpgraders[0].Upgrade(context.Background(), rawState, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(upgraded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, err := ctyjson.Unmarshal(b, r.CoreConfigSchema().ImpliedType())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := r.ShimInstanceStateFromValue(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := r.SimpleDiff(context.Background(), state, terraform.NewResourceConfigRaw(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return diff
+}
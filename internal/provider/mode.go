@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parseFileMode parses an octal permission string such as "644", "0644" or
+// "0o644". Only permission, setuid, setgid and sticky bits are allowed.
+func parseFileMode(s string) (os.FileMode, error) {
+	digits := s
+	if strings.HasPrefix(digits, "0o") || strings.HasPrefix(digits, "0O") {
+		digits = digits[2:]
+	}
+	m, err := strconv.ParseUint(digits, 8, 32)
+	if err != nil || digits == "" {
+		return 0, fmt.Errorf("%q is not an octal number", s)
+	}
+	if m > 07777 {
+		return 0, fmt.Errorf("%q is out of range, the maximum is 07777", s)
+	}
+	mode := os.FileMode(m & 0777)
+	if m&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if m&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if m&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+func validateFileMode(v interface{}, path cty.Path) diag.Diagnostics {
+	s, ok := v.(string)
+	if !ok {
+		return diag.Diagnostics{{Severity: diag.Error, Summary: "expected a string", AttributePath: path}}
+	}
+	if _, err := parseFileMode(s); err != nil {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "invalid file mode",
+			Detail:        err.Error() + `. Use an octal string such as "0644".`,
+			AttributePath: path,
+		}}
+	}
+	return nil
+}
+
+// suppressEquivalentFileMode suppresses the diff between modes that only
+// differ in how they are written, such as "644" and "0644".
+func suppressEquivalentFileMode(k, old, new string, d *schema.ResourceData) bool {
+	oldMode, err := parseFileMode(old)
+	if err != nil {
+		return false
+	}
+	newMode, err := parseFileMode(new)
+	if err != nil {
+		return false
+	}
+	return oldMode == newMode
+}
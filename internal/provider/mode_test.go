@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"github.com/hashicorp/go-cty/cty"
+	"os"
+	"testing"
+)
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"644", 0644, false},
+		{"0644", 0644, false},
+		{"0o644", 0644, false},
+		{"0O755", 0755, false},
+		{"4755", os.ModeSetuid | 0755, false},
+		{"7777", os.ModeSetuid | os.ModeSetgid | os.ModeSticky | 0777, false},
+		{"", 0, true},
+		{"0o", 0, true},
+		{"0x644", 0, true},
+		{"689", 0, true},
+		{"rw-r--r--", 0, true},
+		{"10000", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseFileMode(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseFileMode(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseFileMode(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateFileMode(t *testing.T) {
+	path := cty.GetAttrPath("file_mode")
+	for _, v := range []string{"644", "0644", "0o644", "0"} {
+		if diags := validateFileMode(v, path); diags.HasError() {
+			t.Errorf("validateFileMode(%q) returned %v", v, diags)
+		}
+	}
+	for _, v := range []string{"", "888", "0x1ff", "17777", "-644"} {
+		diags := validateFileMode(v, path)
+		if !diags.HasError() {
+			t.Errorf("validateFileMode(%q) expected an error", v)
+			continue
+		}
+		if !diags[0].AttributePath.Equals(path) {
+			t.Errorf("validateFileMode(%q) attribute path = %#v", v, diags[0].AttributePath)
+		}
+	}
+}
+
+func TestSuppressEquivalentFileMode(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     bool
+	}{
+		{"644", "0644", true},
+		{"0644", "0o644", true},
+		{"0755", "755", true},
+		{"0644", "0755", false},
+		{"", "0644", false},
+		{"0644", "", false},
+		{"bogus", "bogus", false},
+	}
+	for _, tt := range tests {
+		if got := suppressEquivalentFileMode("file_mode", tt.old, tt.new, nil); got != tt.want {
+			t.Errorf("suppressEquivalentFileMode(%q, %q) = %v, want %v", tt.old, tt.new, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/bgentry/go-netrc/netrc"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// defaultNetrcPath returns the netrc file used when netrc_path is not set:
+// $NETRC, or .netrc in the home directory (_netrc on windows).
+func defaultNetrcPath() (string, error) {
+	if v := os.Getenv("NETRC"); v != "" {
+		return v, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+	return filepath.Join(home, name), nil
+}
+
+// loadNetrc parses the netrc file at path. A missing file is only an error
+// if the path was configured explicitly.
+func loadNetrc(path string, explicit bool) (*netrc.Netrc, error) {
+	n, err := netrc.ParseFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read netrc file %q: %w", path, err)
+	}
+	return n, nil
+}
+
+// setNetrcAuth adds Basic auth from the netrc machine matching the request
+// host, or its default entry. Requests that already carry credentials are
+// left alone.
+func (c *providerConfig) setNetrcAuth(req *http.Request) {
+	if c == nil || c.netrc == nil || req.URL.User != nil || req.Header.Get("Authorization") != "" {
+		return
+	}
+	m := c.netrc.FindMachine(req.URL.Hostname())
+	if m == nil || (m.Login == "" && m.Password == "") {
+		return
+	}
+	req.SetBasicAuth(m.Login, m.Password)
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+const testNetrc = `machine files.example.com
+	login alice
+	password s3cret
+
+machine nologin.example.com
+
+default login anonymous password guest
+`
+
+func TestSetNetrcAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netrc")
+	if err := ioutil.WriteFile(path, []byte(testNetrc), 0600); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"use_netrc":  true,
+		"netrc_path": path,
+	})
+	meta, diags := providerConfigure("test")(context.Background(), data)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	cfg := configFromMeta(meta)
+	tests := []struct {
+		name     string
+		url      string
+		headers  map[string]interface{}
+		user     string
+		password string
+		auth     string
+	}{
+		{name: "machine", url: "https://files.example.com/file.txt", user: "alice", password: "s3cret"},
+		{name: "machine with port", url: "https://files.example.com:8443/file.txt", user: "alice", password: "s3cret"},
+		{name: "default", url: "https://other.example.com/file.txt", user: "anonymous", password: "guest"},
+		{name: "no credentials", url: "https://nologin.example.com/file.txt"},
+		{name: "explicit header", url: "https://files.example.com/file.txt", headers: map[string]interface{}{"Authorization": "Bearer token"}, auth: "Bearer token"},
+		{name: "userinfo", url: "https://bob:pw@files.example.com/file.txt", user: "bob", password: "pw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{"url": tt.url, "filename": "out"}
+			if tt.headers != nil {
+				raw["headers"] = tt.headers
+			}
+			rd := schema.TestResourceDataRaw(t, resourceURLSchema(), raw)
+			req, err := makeRequest(http.MethodGet, rd, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.auth != "" {
+				if got := req.Header.Get("Authorization"); got != tt.auth {
+					t.Fatalf("Authorization = %q, want %q", got, tt.auth)
+				}
+				return
+			}
+			user, password, ok := req.BasicAuth()
+			if tt.user == "" {
+				if ok {
+					t.Fatalf("unexpected basic auth for %q", user)
+				}
+				return
+			}
+			if !ok && req.URL.User != nil {
+				// the transport sends userinfo as basic auth
+				user = req.URL.User.Username()
+				password, _ = req.URL.User.Password()
+				ok = true
+			}
+			if !ok || user != tt.user || password != tt.password {
+				t.Fatalf("basic auth = %q:%q (%v), want %q:%q", user, password, ok, tt.user, tt.password)
+			}
+		})
+	}
+}
+
+func TestProviderConfigure_netrcPath(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	t.Setenv("NETRC", missing)
+	tests := []struct {
+		name    string
+		raw     map[string]interface{}
+		wantErr bool
+	}{
+		{"disabled", map[string]interface{}{"netrc_path": missing}, false},
+		{"missing default", map[string]interface{}{"use_netrc": true}, false},
+		{"missing explicit", map[string]interface{}{"use_netrc": true, "netrc_path": missing}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := schema.TestResourceDataRaw(t, Provider().Schema, tt.raw)
+			meta, diags := providerConfigure("test")(context.Background(), data)
+			if diags.HasError() != tt.wantErr {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if !tt.wantErr && configFromMeta(meta).netrc != nil {
+				t.Fatalf("expected no netrc to be loaded")
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package provider
+
+import "syscall"
+
+// oNoFollow makes os.OpenFile fail if the final path component is a symlink.
+const oNoFollow = syscall.O_NOFOLLOW
@@ -0,0 +1,6 @@
+//go:build windows
+
+package provider
+
+// oNoFollow is not available on Windows; no_follow is rejected instead.
+const oNoFollow = 0
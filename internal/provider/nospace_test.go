@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// fullWriter writes up to n bytes to w, then fails like a full filesystem.
+type fullWriter struct {
+	w io.Writer
+	n int
+}
+
+func (f *fullWriter) Write(p []byte) (int, error) {
+	if len(p) > f.n {
+		written, _ := f.w.Write(p[:f.n])
+		f.n -= written
+		return written, &os.PathError{Op: "write", Path: "dest", Err: syscall.ENOSPC}
+	}
+	written, err := f.w.Write(p)
+	f.n -= written
+	return written, err
+}
+
+func TestWriteFileAtomic_noSpace(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ENOSPC is not returned on windows")
+	}
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dest-file")
+	flag := os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	err := writeFileAtomic(dest, 0644, flag, func(w io.Writer) error {
+		_, err := copyBuffer(&fullWriter{w: w, n: 4}, bytes.NewReader([]byte("hello world")), make([]byte, 8))
+		if err != nil {
+			return fmt.Errorf("error reading request body into %q: %w", dest, err)
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.HasPrefix(err.Error(), "destination filesystem is out of space") {
+		t.Fatalf("unexpected error %q", err)
+	}
+	if !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("error does not wrap ENOSPC: %v", err)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("partial file was not removed: %v", entries)
+	}
+	// other errors are returned as they are
+	err = writeFileAtomic(dest, 0644, flag, func(w io.Writer) error {
+		return fmt.Errorf("write failed")
+	})
+	if err == nil || err.Error() != "write failed" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package provider
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isNoSpace reports whether err was caused by a full filesystem.
+func isNoSpace(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package provider
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isNoSpace reports whether err is ERROR_DISK_FULL or
+// ERROR_HANDLE_DISK_FULL, returned when the volume is full.
+func isNoSpace(err error) bool {
+	return errors.Is(err, syscall.Errno(112)) || errors.Is(err, syscall.Errno(39))
+}
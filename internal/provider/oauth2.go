@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oauth2ExpiryMargin is how long before it expires a cached token is
+// replaced, so it does not expire while a download is in flight.
+const oauth2ExpiryMargin = 30 * time.Second
+
+func oauth2Schema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"token_url": {
+			Type:             schema.TypeString,
+			Required:         true,
+			ValidateDiagFunc: validateURL,
+			Description:      "Token endpoint of the authorization server",
+		},
+		"client_id": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "OAuth2 client ID",
+		},
+		"client_secret": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Sensitive:   true,
+			Description: "OAuth2 client secret",
+		},
+		"scopes": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Description: "Scopes to request with the token",
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"auth_style": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      "header",
+			ValidateFunc: validation.StringInSlice([]string{"header", "body"}, false),
+			Description:  "How the client credentials are sent to the token endpoint: header uses HTTP Basic auth, body sends them as form parameters",
+		},
+	}
+}
+
+// oauth2Client is the oauth2 block of a resource. It is also the key of
+// the token cache, so resources sharing a client share its token.
+type oauth2Client struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       string
+	authStyle    string
+}
+
+type oauth2Token struct {
+	accessToken string
+	// expiry is zero if the server did not say when the token expires
+	expiry time.Time
+}
+
+func (t oauth2Token) valid(now time.Time) bool {
+	return t.accessToken != "" && (t.expiry.IsZero() || now.Add(oauth2ExpiryMargin).Before(t.expiry))
+}
+
+// oauth2TokenCache holds the tokens fetched during a run of the provider.
+type oauth2TokenCache struct {
+	mu     sync.Mutex
+	tokens map[oauth2Client]oauth2Token
+}
+
+// oauth2TokenError is returned when the token endpoint fails, so it can be
+// reported separately from the download itself.
+type oauth2TokenError struct {
+	tokenURL string
+	err      error
+}
+
+func (e *oauth2TokenError) Error() string {
+	return fmt.Sprintf("could not get an OAuth2 token from %q: %s", e.tokenURL, e.err)
+}
+
+func (e *oauth2TokenError) Unwrap() error {
+	return e.err
+}
+
+// setOAuth2Token sets a bearer token from the oauth2 block of data on req,
+// if the block is set. Tokens are cached on the provider until they expire.
+func setOAuth2Token(req *http.Request, data resourceGetter, cfg *providerConfig) error {
+	v, ok := data.GetOk("oauth2")
+	if !ok {
+		return nil
+	}
+	blocks := v.([]interface{})
+	if len(blocks) == 0 || blocks[0] == nil {
+		return nil
+	}
+	block := blocks[0].(map[string]interface{})
+	var scopes []string
+	for _, s := range block["scopes"].([]interface{}) {
+		scopes = append(scopes, s.(string))
+	}
+	client := oauth2Client{
+		tokenURL:     block["token_url"].(string),
+		clientID:     block["client_id"].(string),
+		clientSecret: block["client_secret"].(string),
+		scopes:       strings.Join(scopes, " "),
+		authStyle:    block["auth_style"].(string),
+	}
+	httpClient, diags := newHTTPClient(data, cfg)
+	if diags.HasError() {
+		return fmt.Errorf("%s", diags[0].Summary)
+	}
+	token, err := cfg.oauth2Token(client, httpClient)
+	if err != nil {
+		return &oauth2TokenError{tokenURL: client.tokenURL, err: err}
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// oauth2Token returns the cached token of client, or fetches a new one.
+func (c *providerConfig) oauth2Token(client oauth2Client, httpClient *http.Client) (string, error) {
+	if c == nil || c.oauth2Tokens == nil {
+		token, err := fetchOAuth2Token(client, httpClient)
+		return token.accessToken, err
+	}
+	cache := c.oauth2Tokens
+	// the lock is held while fetching so that parallel downloads with the
+	// same client wait for one token instead of each requesting their own
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if token, ok := cache.tokens[client]; ok && token.valid(time.Now()) {
+		return token.accessToken, nil
+	}
+	token, err := fetchOAuth2Token(client, httpClient)
+	if err != nil {
+		return "", err
+	}
+	if cache.tokens == nil {
+		cache.tokens = make(map[oauth2Client]oauth2Token)
+	}
+	cache.tokens[client] = token
+	return token.accessToken, nil
+}
+
+// fetchOAuth2Token requests a token with the client credentials grant
+// (RFC 6749 section 4.4).
+func fetchOAuth2Token(client oauth2Client, httpClient *http.Client) (oauth2Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if client.scopes != "" {
+		form.Set("scope", client.scopes)
+	}
+	if client.authStyle == "body" {
+		form.Set("client_id", client.clientID)
+		form.Set("client_secret", client.clientSecret)
+	}
+	req, err := http.NewRequest(http.MethodPost, client.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oauth2Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if client.authStyle != "body" {
+		req.SetBasicAuth(url.QueryEscape(client.clientID), url.QueryEscape(client.clientSecret))
+	}
+	issued := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return oauth2Token{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil {
+		return oauth2Token{}, fmt.Errorf("could not read the response: %w", err)
+	}
+	var result struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	jsonErr := json.Unmarshal(body, &result)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if jsonErr == nil && result.Error != "" {
+			msg := fmt.Sprintf("%s: %s", resp.Status, result.Error)
+			if result.ErrorDescription != "" {
+				msg += ": " + result.ErrorDescription
+			}
+			return oauth2Token{}, fmt.Errorf("%s", msg)
+		}
+		return oauth2Token{}, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(strings.ToValidUTF8(string(body), "")))
+	}
+	if jsonErr != nil {
+		return oauth2Token{}, fmt.Errorf("the response is not valid JSON: %w", jsonErr)
+	}
+	if result.AccessToken == "" {
+		return oauth2Token{}, fmt.Errorf("the response has no access_token")
+	}
+	if result.TokenType != "" && !strings.EqualFold(result.TokenType, "bearer") {
+		return oauth2Token{}, fmt.Errorf("unsupported token_type %q", result.TokenType)
+	}
+	token := oauth2Token{accessToken: result.AccessToken}
+	if result.ExpiresIn != "" {
+		seconds, err := result.ExpiresIn.Int64()
+		if err != nil {
+			return oauth2Token{}, fmt.Errorf("expires_in %q is not a number", result.ExpiresIn)
+		}
+		if seconds > 0 {
+			token.expiry = issued.Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestTokenServer returns a token endpoint that issues "token-<n>" to
+// client "id" with secret "secret", and counts the tokens it issued.
+func newTestTokenServer(t *testing.T, issued *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		id, secret, ok := r.BasicAuth()
+		if !ok {
+			id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("grant_type") != "client_credentials" || id != "id" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"unknown client"}`)
+			return
+		}
+		if got := r.PostForm.Get("scope"); got != "read write" {
+			t.Errorf("unexpected scope %q", got)
+		}
+		n := atomic.AddInt32(issued, 1)
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, n)
+	}))
+}
+
+func testOAuth2Data(t *testing.T, rawURL, tokenURL, secret, authStyle string) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      rawURL,
+		"filename": filepath.Join(t.TempDir(), "dest-file"),
+		"oauth2": []interface{}{map[string]interface{}{
+			"token_url":     tokenURL,
+			"client_id":     "id",
+			"client_secret": secret,
+			"scopes":        []interface{}{"read", "write"},
+			"auth_style":    authStyle,
+		}},
+	})
+}
+
+func TestEnsureDownloadFile_oauth2(t *testing.T) {
+	for _, authStyle := range []string{"header", "body"} {
+		t.Run(authStyle, func(t *testing.T) {
+			var issued int32
+			tokenSrv := newTestTokenServer(t, &issued)
+			defer tokenSrv.Close()
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer token-1" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte("hello"))
+			}))
+			defer srv.Close()
+			data := testOAuth2Data(t, srv.URL, tokenSrv.URL, "secret", authStyle)
+			if diags := ensureDownloadFile(context.Background(), data, 0, &providerConfig{oauth2Tokens: &oauth2TokenCache{}}); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if b, _ := ioutil.ReadFile(data.Get("filename").(string)); string(b) != "hello" {
+				t.Fatalf("unexpected content %q", b)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_oauth2Cached(t *testing.T) {
+	var issued int32
+	tokenSrv := newTestTokenServer(t, &issued)
+	defer tokenSrv.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	cfg := &providerConfig{oauth2Tokens: &oauth2TokenCache{}}
+	for i := 0; i < 3; i++ {
+		data := testOAuth2Data(t, srv.URL, tokenSrv.URL, "secret", "header")
+		if diags := ensureDownloadFile(context.Background(), data, 0, cfg); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+	}
+	if issued := atomic.LoadInt32(&issued); issued != 1 {
+		t.Fatalf("expected the token to be cached, got %d token requests", issued)
+	}
+	// an expired token is replaced
+	for k, token := range cfg.oauth2Tokens.tokens {
+		token.expiry = time.Now()
+		cfg.oauth2Tokens.tokens[k] = token
+	}
+	data := testOAuth2Data(t, srv.URL, tokenSrv.URL, "secret", "header")
+	if diags := ensureDownloadFile(context.Background(), data, 0, cfg); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if issued := atomic.LoadInt32(&issued); issued != 2 {
+		t.Fatalf("expected the expired token to be replaced, got %d token requests", issued)
+	}
+}
+
+func TestEnsureDownloadFile_oauth2TokenError(t *testing.T) {
+	var issued int32
+	tokenSrv := newTestTokenServer(t, &issued)
+	defer tokenSrv.Close()
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+	}))
+	defer srv.Close()
+	data := testOAuth2Data(t, srv.URL, tokenSrv.URL, "wrong", "header")
+	diags := ensureDownloadFile(context.Background(), data, 0, &providerConfig{oauth2Tokens: &oauth2TokenCache{}})
+	if !diags.HasError() {
+		t.Fatal("expected an error")
+	}
+	if diags[0].Summary != "OAuth2 token request failed" {
+		t.Fatalf("unexpected summary %q", diags[0].Summary)
+	}
+	if !strings.Contains(diags[0].Detail, "invalid_client: unknown client") {
+		t.Fatalf("unexpected detail %q", diags[0].Detail)
+	}
+	if downloads != 0 {
+		t.Fatal("the download was requested without a token")
+	}
+}
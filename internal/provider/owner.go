@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// resolveOwnership returns the uid and gid named by the owner and group
+// attributes, or -1 for an attribute that is not set.
+func resolveOwnership(data resourceGetter) (uid, gid int, err error) {
+	uid, gid = -1, -1
+	if v, ok := data.GetOk("owner"); ok {
+		if uid, err = lookupUID(v.(string)); err != nil {
+			return
+		}
+	}
+	if v, ok := data.GetOk("group"); ok {
+		if gid, err = lookupGID(v.(string)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func lookupUID(owner string) (int, error) {
+	if id, err := strconv.Atoi(owner); err == nil {
+		return id, nil
+	}
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return 0, fmt.Errorf("could not find owner %q: %w", owner, err)
+	}
+	id, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return 0, fmt.Errorf("owner %q does not have a numeric uid: %q", owner, u.Uid)
+	}
+	return id, nil
+}
+
+func lookupGID(group string) (int, error) {
+	if id, err := strconv.Atoi(group); err == nil {
+		return id, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, fmt.Errorf("could not find group %q: %w", group, err)
+	}
+	id, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return 0, fmt.Errorf("group %q does not have a numeric gid: %q", group, g.Gid)
+	}
+	return id, nil
+}
+
+// ownershipChanged reports whether the destination is not owned by the
+// configured owner and group.
+func ownershipChanged(data resourceGetter, cfg *providerConfig) (bool, error) {
+	uid, gid, err := resolveOwnership(data)
+	if err != nil {
+		return false, err
+	}
+	if uid == -1 && gid == -1 {
+		return false, nil
+	}
+	stat, err := os.Stat(cfg.resolvePath(data.Get("destination").(string)))
+	if err != nil {
+		return false, nil
+	}
+	fileUID, fileGID, ok := fileOwner(stat)
+	if !ok {
+		return false, nil
+	}
+	return (uid != -1 && uid != fileUID) || (gid != -1 && gid != fileGID), nil
+}
+
+// ensureOwnership changes the owner and group of the destination, of every
+// entry below it for a recursive copy, or of the copied files for a
+// source_glob copy.
+func ensureOwnership(data *schema.ResourceData, cfg *providerConfig) diag.Diagnostics {
+	uid, gid, err := resolveOwnership(data)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	dest := cfg.resolvePath(data.Get("destination").(string))
+	if !chownSupported {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "owner and group are not supported on this platform",
+			Detail:   fmt.Sprintf("could not change ownership of %q. Remove owner and group from this resource.", dest),
+		}}
+	}
+	if data.Get("recursive").(bool) {
+		err = filepath.WalkDir(dest, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Lchown(path, uid, gid)
+		})
+	} else if data.Get("source_glob").(bool) {
+		for _, f := range stringList(data.Get("files")) {
+			if err = os.Chown(f, uid, gid); err != nil {
+				break
+			}
+		}
+	} else {
+		err = os.Chown(dest, uid, gid)
+	}
+	return chownDiagnostics(dest, err)
+}
+
+// chownDiagnostics reports an error changing the ownership of path, with a
+// hint if it is a lack of privileges.
+func chownDiagnostics(path string, err error) diag.Diagnostics {
+	if errors.Is(err, fs.ErrPermission) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "insufficient privileges to change ownership",
+			Detail:   fmt.Sprintf("could not change ownership of %q: %s. Run terraform as a user that can chown files, or remove owner and group from this resource.", path, err),
+		}}
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not change ownership of %q: %w", path, err))
+	}
+	return nil
+}
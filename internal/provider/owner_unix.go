@@ -0,0 +1,19 @@
+//go:build !windows
+
+package provider
+
+import (
+	"os"
+	"syscall"
+)
+
+const chownSupported = true
+
+// fileOwner returns the uid and gid of fi.
+func fileOwner(fi os.FileInfo) (uid, gid int, ok bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
@@ -0,0 +1,92 @@
+//go:build !windows
+
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	if err := ioutil.WriteFile(dest, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		owner, group string
+		uid, gid     int
+	}{
+		{"65534", "65534", 65534, 65534},
+		{"root", "", 0, 65534},
+		{"", "0", 0, 0},
+	}
+	for _, tt := range tests {
+		data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+			"source":      "./testdata/source-file01",
+			"destination": dest,
+			"owner":       tt.owner,
+			"group":       tt.group,
+		})
+		if diags := ensureOwnership(data, nil); diags.HasError() {
+			t.Fatalf("owner %q group %q: unexpected error: %v", tt.owner, tt.group, diags)
+		}
+		stat, err := os.Stat(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uid, gid, _ := fileOwner(stat); uid != tt.uid || gid != tt.gid {
+			t.Fatalf("owner %q group %q: got %d:%d, want %d:%d", tt.owner, tt.group, uid, gid, tt.uid, tt.gid)
+		}
+		if changed, err := ownershipChanged(data, nil); err != nil || changed {
+			t.Fatalf("owner %q group %q: expected ownership to match: %v", tt.owner, tt.group, err)
+		}
+	}
+}
+
+func TestResolveOwnership_unknown(t *testing.T) {
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "./testdata/source-file01",
+		"destination": "dest-file",
+		"owner":       "no-such-user-synclocal",
+	})
+	if _, _, err := resolveOwnership(data); err == nil {
+		t.Fatalf("expected unknown owner to fail")
+	}
+}
+
+func TestWriteFileAtomic_keepsOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	if err := ioutil.WriteFile(dest, []byte("goodbye"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(dest, 65534, 65534); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "./testdata/source-file01",
+		"destination": dest,
+	})
+	if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("the destination was not overwritten: %q", b)
+	}
+	stat, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid, gid, _ := fileOwner(stat); uid != 65534 || gid != 65534 {
+		t.Fatalf("the overwrite changed the owner to %d:%d", uid, gid)
+	}
+}
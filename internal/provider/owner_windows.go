@@ -0,0 +1,13 @@
+//go:build windows
+
+package provider
+
+import "os"
+
+// chownSupported is false since os.Chown always fails on Windows.
+const chownSupported = false
+
+// fileOwner is not available on Windows.
+func fileOwner(fi os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
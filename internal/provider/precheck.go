@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"log"
+	"net/http"
+)
+
+// precheckURLs sends a HEAD request to the url and each of the mirror_urls
+// with the headers, auth and timeouts of the resource, and returns a warning
+// for each that is unreachable or does not respond with a 2xx status.
+// file:// urls are not checked.
+func precheckURLs(ctx context.Context, data resourceGetter, cfg *providerConfig) diag.Diagnostics {
+	urls := []string{data.Get("url").(string)}
+	for _, v := range data.Get("mirror_urls").([]interface{}) {
+		urls = append(urls, v.(string))
+	}
+	var diags diag.Diagnostics
+	for _, rawURL := range urls {
+		if err := precheckURL(ctx, rawURL, data, cfg); err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  fmt.Sprintf("precheck of %q failed", rawURL),
+				Detail:   err.Error(),
+			})
+		}
+	}
+	return diags
+}
+
+func precheckURL(ctx context.Context, rawURL string, data resourceGetter, cfg *providerConfig) error {
+	if _, ok, err := localSource(rawURL, cfg); err != nil || ok {
+		return err
+	}
+	req, err := makeRequestURL(http.MethodHead, rawURL, data, cfg)
+	if err != nil {
+		return err
+	}
+	c, diags := newHTTPClient(data, cfg)
+	if diags.HasError() {
+		return fmt.Errorf("%s", diags[0].Summary)
+	}
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("the url is unreachable: %w", err)
+	}
+	resp.Body.Close()
+	// the stored validators are sent, so an unchanged file is a 304
+	if resp.StatusCode == http.StatusNotModified || (resp.StatusCode >= 200 && resp.StatusCode <= 299) {
+		return nil
+	}
+	return fmt.Errorf("HEAD %s returned %s", req.URL.Redacted(), resp.Status)
+}
+
+// readPrecheck returns the precheck warnings of an existing resource.
+// Refresh runs as part of plan, and unlike CustomizeDiff, Read can return
+// warnings that the plan shows.
+func readPrecheck(ctx context.Context, data *schema.ResourceData, cfg *providerConfig) diag.Diagnostics {
+	if !data.Get("precheck").(bool) || data.Get("url").(string) == "" {
+		return nil
+	}
+	return precheckURLs(ctx, data, cfg)
+}
+
+// logPrecheck writes the precheck warnings to the Terraform log. The plugin
+// SDK only passes errors from CustomizeDiff on to the plan, so a resource
+// that has not been read yet can only report them here without failing it.
+func logPrecheck(diags diag.Diagnostics) {
+	for _, d := range diags {
+		log.Printf("[WARN] %s: %s", d.Summary, d.Detail)
+	}
+}
@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrecheckURLs(t *testing.T) {
+	ok := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":         failing.URL,
+		"mirror_urls": []interface{}{ok.URL, closed.URL},
+		"filename":    "unused",
+		"headers":     map[string]interface{}{"Authorization": "Bearer secret"},
+	})
+	diags := precheckURLs(context.Background(), data, nil)
+	if len(diags) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", diags)
+	}
+	for i, want := range []string{failing.URL, closed.URL} {
+		if diags[i].Severity != diag.Warning {
+			t.Fatalf("expected a warning, got %v", diags[i])
+		}
+		if !strings.Contains(diags[i].Summary, want) {
+			t.Fatalf("unexpected summary %q, want it to name %q", diags[i].Summary, want)
+		}
+	}
+	if !strings.Contains(diags[0].Detail, "503") {
+		t.Fatalf("unexpected detail %q", diags[0].Detail)
+	}
+	// without the Authorization header the protected server is not ok either
+	data = schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      ok.URL,
+		"filename": "unused",
+	})
+	if diags := precheckURLs(context.Background(), data, nil); len(diags) != 1 {
+		t.Fatalf("expected a warning for the unauthorized request, got %v", diags)
+	}
+}
+
+func TestResourceURLDiff_precheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	for _, precheck := range []bool{false, true} {
+		buf.Reset()
+		config := terraform.NewResourceConfigRaw(map[string]interface{}{
+			"url":      srv.URL,
+			"filename": "unused",
+			"precheck": precheck,
+		})
+		if _, err := resourceURL().Diff(context.Background(), nil, config, nil); err != nil {
+			t.Fatalf("precheck=%v: the plan failed: %v", precheck, err)
+		}
+		logged := strings.Contains(buf.String(), "[WARN] precheck of")
+		if logged != precheck {
+			t.Fatalf("precheck=%v: unexpected log output %q", precheck, buf.String())
+		}
+	}
+}
+
+func TestResourceURLRead_precheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	for _, precheck := range []bool{false, true} {
+		data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+			"url":      srv.URL,
+			"filename": filepath.Join(t.TempDir(), "dest-file"),
+			"precheck": precheck,
+		})
+		if diags := resourceURLCreate(context.Background(), data, nil); diags.HasError() {
+			t.Fatalf("precheck=%v: unexpected error: %v", precheck, diags)
+		}
+		diags := resourceURLRead(context.Background(), data, nil)
+		if diags.HasError() {
+			t.Fatalf("precheck=%v: the refresh failed: %v", precheck, diags)
+		}
+		if data.Id() == "" {
+			t.Fatalf("precheck=%v: the file was removed from state", precheck)
+		}
+		warned := len(diags) == 1 && diags[0].Severity == diag.Warning && strings.Contains(diags[0].Detail, "503")
+		if warned != precheck || (!precheck && len(diags) != 0) {
+			t.Fatalf("precheck=%v: unexpected diagnostics %v", precheck, diags)
+		}
+	}
+}
+
+func TestResourceURLDiff_precheckExisting(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	raw := map[string]interface{}{
+		"url":      srv.URL,
+		"filename": "unused",
+		"precheck": true,
+	}
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), raw)
+	data.SetId("file:///unused")
+	if _, err := resourceURL().Diff(context.Background(), data.State(), terraform.NewResourceConfigRaw(raw), nil); err != nil {
+		t.Fatalf("the plan failed: %v", err)
+	}
+	if requests != 0 {
+		t.Fatalf("expected the unchanged url to be left to Read, got %d requests", requests)
+	}
+}
@@ -1,15 +1,425 @@
 package provider
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/bgentry/go-netrc/netrc"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"golang.org/x/net/http/httpproxy"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
 )
 
+// defaultCopyBufferSize is the buffer size used for copies when
+// copy_buffer_size is not configured.
+const defaultCopyBufferSize = 1 << 20
+
+// tlsVersions maps the values of min_tls_version to their crypto/tls
+// versions.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
 // Provider -
 func Provider() *schema.Provider {
+	return New("dev")()
+}
+
+// New returns a function creating the provider for the given release
+// version, which is sent in the default User-Agent.
+func New(version string) func() *schema.Provider {
+	return func() *schema.Provider {
+		return newProvider(version)
+	}
+}
+
+func newProvider(version string) *schema.Provider {
 	return &schema.Provider{
+		Schema: map[string]*schema.Schema{
+			"user_agent": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "User-Agent sent with url requests. Defaults to terraform-provider-synclocal/<version>.",
+			},
+			"copy_buffer_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      defaultCopyBufferSize,
+				ValidateFunc: validation.IntAtLeast(4096),
+				Description:  "Size in bytes of the buffer used to copy and download files",
+			},
+			"base_url": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Base url that relative url attributes are resolved against",
+			},
+			"working_directory": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Directory that relative paths of resources and data sources are resolved against. It must exist. Defaults to the working directory of Terraform. Absolute paths are not affected.",
+			},
+			"allowed_root": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Directory that resources may write to. A resource whose destination resolves outside of it, after relative paths, .. elements and symlinks are resolved, is rejected during plan, or during apply if the destination is not known until then. It must exist.",
+			},
+			"request_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateDuration,
+				Description:  "Default time limit for url requests, including retries, such as \"5m\". Requests have no time limit by default.",
+			},
+			"max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Default number of times to retry a url request that fails with a connection error, a 429 or a 5xx status",
+			},
+			"read_strategy": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice(readStrategies, false),
+				Description:  "Default read_strategy of synclocal_url resources. When neither is set, a refresh hashes the file and downloads the url again if it changed.",
+			},
+			"max_concurrent_requests": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of url requests in flight at once across all resources and data sources, regardless of -parallelism. A download holds its slot until the response is written. 0 does not limit requests.",
+			},
+			"force_http2": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Attempt HTTP/2 with https servers that support it. Set to false to only use HTTP/1.1.",
+			},
+			"max_idle_conns": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      defaultMaxIdleConns,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Maximum number of idle connections kept open for reuse, in total and to each host. Connections are shared by all resources and data sources, except those that set unix_socket, resolve or insecure_skip_verify.",
+			},
+			"retry_wait": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "1s",
+				ValidateFunc: validateDuration,
+				Description:  "Default time to wait between retries of a url request",
+			},
+			"min_tls_version": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "1.2",
+				ValidateFunc: validation.StringInSlice([]string{"1.2", "1.3"}, false),
+				Description:  "Minimum TLS version of https requests, 1.2 or 1.3",
+			},
+			"proxy_url": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Proxy for url requests, such as \"http://proxy.example.com:3128\". Defaults to the HTTP_PROXY and HTTPS_PROXY environment variables.",
+			},
+			"no_proxy": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Comma separated hosts, domains and CIDR ranges that bypass proxy_url, in the format of NO_PROXY. Defaults to the NO_PROXY environment variable.",
+			},
+			"use_netrc": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Send Basic auth from a netrc file with url requests that have no Authorization header",
+			},
+			"netrc_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Path of the netrc file used with use_netrc. Defaults to the NETRC environment variable, or .netrc in the home directory (_netrc on windows).",
+			},
+			"header_env_expansion": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Expand header values of the form ${env:NAME} from the environment when a request is made, so tokens are not stored in the plan or state. Escape the reference from Terraform as $${env:NAME}. A reference to an unset variable is an error.",
+			},
+			"default_file_mode": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validateFileMode,
+				Description:      "File mode (Octal String) of files written by resources that do not set file_mode, in place of 0664. A synclocal_file copy still mirrors its source.",
+			},
+			"default_headers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Description: "HTTP headers sent with every url request. Headers set on a resource take precedence.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
 		ResourcesMap: map[string]*schema.Resource{
-			"synclocal_file": resourceFile(),
-			"synclocal_url":  resourceURL(),
+			"synclocal_file":             resourceFile(),
+			"synclocal_url":              resourceURL(),
+			"synclocal_manifest":         resourceManifest(),
+			"synclocal_symlink":          resourceSymlink(),
+			"synclocal_directory":        resourceDirectory(),
+			"synclocal_archive_extract":  resourceArchiveExtract(),
+			"synclocal_append":           resourceAppend(),
+			"synclocal_template":         resourceTemplate(),
+			"synclocal_sftp":             resourceSFTP(),
+			"synclocal_copy_dir":         resourceCopyDir(),
+			"synclocal_file_permissions": resourceFilePermissions(),
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"synclocal_http":         dataSourceHTTP(),
+			"synclocal_file":         dataSourceFile(),
+			"synclocal_checksum":     dataSourceChecksum(),
+			"synclocal_url_metadata": dataSourceURLMetadata(),
+			"synclocal_file_exists":  dataSourceFileExists(),
+			"synclocal_url_content":  dataSourceURLContent(),
 		},
+		ConfigureContextFunc: providerConfigure(version),
+	}
+}
+
+// providerConfig is the configured provider, passed to resources as meta.
+type providerConfig struct {
+	copyBufferSize int
+	defaultHeaders map[string]string
+	baseURL        *url.URL
+	userAgent      string
+	requestTimeout time.Duration
+	maxRetries     int
+	retryWait      time.Duration
+	// proxy is nil to use the proxy environment variables
+	proxy func(*url.URL) (*url.URL, error)
+	// netrc is nil unless use_netrc is set and the file exists
+	netrc *netrc.Netrc
+	// oauth2Tokens caches the tokens of oauth2 blocks for the run
+	oauth2Tokens *oauth2TokenCache
+	// workingDir is the absolute working_directory, or empty to use the
+	// process working directory
+	workingDir string
+	// minTLSVersion is the crypto/tls version of min_tls_version
+	minTLSVersion uint16
+	// allowedRoot is the absolute allowed_root with its symlinks resolved,
+	// or empty to allow any destination
+	allowedRoot string
+	// requests is nil unless max_concurrent_requests is set
+	requests requestLimiter
+	// defaultReadStrategy is the provider read_strategy, or empty to download
+	// during refresh
+	defaultReadStrategy string
+	// headerEnvExpansion expands ${env:NAME} header values
+	headerEnvExpansion bool
+	// transport is shared by url requests so connections are reused
+	transport *http.Transport
+	// defaultFileMode is the default_file_mode, or 0 to use
+	// defaultContentFileMode
+	defaultFileMode os.FileMode
+}
+
+func providerConfigure(version string) schema.ConfigureContextFunc {
+	return func(ctx context.Context, data *schema.ResourceData) (interface{}, diag.Diagnostics) {
+		return configureProvider(version, data)
+	}
+}
+
+func configureProvider(version string, data *schema.ResourceData) (interface{}, diag.Diagnostics) {
+	cfg := &providerConfig{
+		copyBufferSize:     data.Get("copy_buffer_size").(int),
+		defaultHeaders:     make(map[string]string),
+		userAgent:          "terraform-provider-synclocal/" + version,
+		maxRetries:         data.Get("max_retries").(int),
+		retryWait:          defaultRetryWait,
+		oauth2Tokens:       &oauth2TokenCache{},
+		requests:           newRequestLimiter(data.Get("max_concurrent_requests").(int)),
+		headerEnvExpansion: data.Get("header_env_expansion").(bool),
+	}
+	if v, ok := data.GetOk("request_timeout"); ok {
+		d, err := time.ParseDuration(v.(string))
+		if err != nil {
+			return nil, diag.Errorf("request_timeout %q is not a valid duration: %s", v, err)
+		}
+		cfg.requestTimeout = d
+	}
+	if v, ok := data.GetOk("min_tls_version"); ok {
+		version, ok := tlsVersions[v.(string)]
+		if !ok {
+			return nil, diag.Errorf("min_tls_version %q is not supported; use 1.2 or 1.3", v)
+		}
+		cfg.minTLSVersion = version
+	}
+	if v, ok := data.GetOk("proxy_url"); ok {
+		u, err := url.Parse(v.(string))
+		if err != nil || u.Host == "" {
+			return nil, diag.Errorf("proxy_url %q must be a url with a host", v)
+		}
+		proxy := httpproxy.FromEnvironment()
+		proxy.HTTPProxy = u.String()
+		proxy.HTTPSProxy = u.String()
+		if v, ok := data.GetOk("no_proxy"); ok {
+			proxy.NoProxy = v.(string)
+		}
+		cfg.proxy = proxy.ProxyFunc()
+	}
+	if v, ok := data.GetOk("default_file_mode"); ok {
+		mode, err := parseFileMode(v.(string))
+		if err != nil {
+			return nil, diag.Errorf("default_file_mode is not a valid octal number: %s", err)
+		}
+		cfg.defaultFileMode = mode
+	}
+	if v, ok := data.GetOk("read_strategy"); ok {
+		cfg.defaultReadStrategy = v.(string)
+	}
+	if v, ok := data.GetOk("retry_wait"); ok {
+		d, err := time.ParseDuration(v.(string))
+		if err != nil {
+			return nil, diag.Errorf("retry_wait %q is not a valid duration: %s", v, err)
+		}
+		cfg.retryWait = d
+	}
+	if v, ok := data.GetOk("user_agent"); ok {
+		cfg.userAgent = v.(string)
+	}
+	for k, v := range data.Get("default_headers").(map[string]interface{}) {
+		cfg.defaultHeaders[k] = v.(string)
+	}
+	if data.Get("use_netrc").(bool) {
+		path, explicit := data.Get("netrc_path").(string), true
+		if path == "" {
+			var err error
+			if path, err = defaultNetrcPath(); err != nil {
+				return nil, diag.Errorf("could not find the netrc file: %s", err)
+			}
+			explicit = false
+		}
+		n, err := loadNetrc(path, explicit)
+		if err != nil {
+			return nil, diag.FromErr(err)
+		}
+		cfg.netrc = n
+	}
+	if v, ok := data.GetOk("base_url"); ok {
+		u, err := url.Parse(v.(string))
+		if err != nil {
+			return nil, diag.Errorf("base_url %q is not a valid url: %s", v, err)
+		}
+		if !u.IsAbs() || u.Host == "" {
+			return nil, diag.Errorf("base_url %q must be an absolute url with a scheme and host", v)
+		}
+		cfg.baseURL = u
+	}
+	if v, ok := data.GetOk("working_directory"); ok {
+		dir, err := filepath.Abs(v.(string))
+		if err != nil {
+			return nil, diag.Errorf("working_directory %q is not a valid path: %s", v, err)
+		}
+		if stat, err := os.Stat(dir); err != nil {
+			return nil, diag.Errorf("working_directory %q: %s", v, err)
+		} else if !stat.IsDir() {
+			return nil, diag.Errorf("working_directory %q is not a directory", v)
+		}
+		cfg.workingDir = dir
+	}
+	if v, ok := data.GetOk("allowed_root"); ok {
+		root, err := resolveAllowedRoot(cfg.resolvePath(v.(string)))
+		if err != nil {
+			return nil, diag.Errorf("allowed_root %q: %s", v, err)
+		}
+		cfg.allowedRoot = root
+	}
+	cfg.transport = cfg.newTransport(data.Get("force_http2").(bool), data.Get("max_idle_conns").(int))
+	return cfg, nil
+}
+
+// configFromMeta returns the provider configuration from meta. It returns
+// nil if the provider was not configured, which is valid for every method.
+func configFromMeta(m interface{}) *providerConfig {
+	c, _ := m.(*providerConfig)
+	return c
+}
+
+// resolveURL resolves rawURL against base_url if it is relative. Absolute
+// urls are returned unchanged.
+func (c *providerConfig) resolveURL(rawURL string) (string, error) {
+	if c == nil || c.baseURL == nil {
+		return rawURL, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	if u.IsAbs() {
+		return rawURL, nil
+	}
+	return c.baseURL.ResolveReference(u).String(), nil
+}
+
+// resolvePath resolves path against working_directory if it is relative.
+// Absolute paths are returned unchanged, as are all paths when
+// working_directory is not set.
+func (c *providerConfig) resolvePath(path string) string {
+	if c == nil || c.workingDir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.workingDir, path)
+}
+
+// checkRelativeURL returns an error if rawURL is relative and there is no
+// base_url to resolve it against.
+func (c *providerConfig) checkRelativeURL(rawURL string) error {
+	if c != nil && c.baseURL != nil {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.IsAbs() {
+		return nil
+	}
+	return fmt.Errorf("url %q has no scheme and the provider has no base_url to resolve it against", rawURL)
+}
+
+// proxyFunc returns the Proxy func for the http transport.
+func (c *providerConfig) proxyFunc() func(*http.Request) (*url.URL, error) {
+	if c == nil || c.proxy == nil {
+		return http.ProxyFromEnvironment
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		return c.proxy(req.URL)
+	}
+}
+
+// tlsMinVersion returns the minimum TLS version of https requests.
+func (c *providerConfig) tlsMinVersion() uint16 {
+	if c == nil || c.minTLSVersion == 0 {
+		return tls.VersionTLS12
+	}
+	return c.minTLSVersion
+}
+
+// fileMode returns the mode of a file written by a resource without a
+// file_mode.
+func (c *providerConfig) fileMode() os.FileMode {
+	if c == nil || c.defaultFileMode == 0 {
+		return defaultContentFileMode
+	}
+	return c.defaultFileMode
+}
+
+// newCopyBuffer allocates a buffer for a single copy operation.
+func (c *providerConfig) newCopyBuffer() []byte {
+	size := defaultCopyBufferSize
+	if c != nil && c.copyBufferSize > 0 {
+		size = c.copyBufferSize
 	}
+	return make([]byte, size)
 }
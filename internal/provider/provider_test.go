@@ -1,7 +1,16 @@
 package provider
 
 import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
@@ -29,3 +38,278 @@ func TestProvider_impl(t *testing.T) {
 func testAccPreCheck(t *testing.T) {
 
 }
+
+func TestProviderConfigure(t *testing.T) {
+	p := Provider()
+	data := schema.TestResourceDataRaw(t, p.Schema, map[string]interface{}{
+		"copy_buffer_size": 8192,
+	})
+	meta, diags := providerConfigure("test")(context.Background(), data)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := len(configFromMeta(meta).newCopyBuffer()); got != 8192 {
+		t.Fatalf("unexpected buffer size %d", got)
+	}
+	if got := len(configFromMeta(nil).newCopyBuffer()); got != defaultCopyBufferSize {
+		t.Fatalf("unexpected default buffer size %d", got)
+	}
+}
+
+func TestProviderConfigure_baseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{name: "relative path", baseURL: "https://registry.example.com/files/", url: "app/v1.tar.gz", want: "https://registry.example.com/files/app/v1.tar.gz"},
+		{name: "rooted path", baseURL: "https://registry.example.com/files/", url: "/other/v1.tar.gz", want: "https://registry.example.com/other/v1.tar.gz"},
+		{name: "absolute override", baseURL: "https://registry.example.com/files/", url: "https://mirror.example.com/v1.tar.gz", want: "https://mirror.example.com/v1.tar.gz"},
+		{name: "relative base", baseURL: "files/", wantErr: true},
+		{name: "invalid base", baseURL: "https://[::1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+				"base_url": tt.baseURL,
+			})
+			meta, diags := providerConfigure("test")(context.Background(), data)
+			if diags.HasError() != tt.wantErr {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := configFromMeta(meta).resolveURL(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("resolveURL(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderConfigure_workingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, wd := range []string{filepath.Join(dir, "missing"), file} {
+		data := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+			"working_directory": wd,
+		})
+		if _, diags := providerConfigure("test")(context.Background(), data); !diags.HasError() {
+			t.Fatalf("expected working_directory %q to be rejected", wd)
+		}
+	}
+	data := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"working_directory": dir,
+	})
+	meta, diags := providerConfigure("test")(context.Background(), data)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	cfg := configFromMeta(meta)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"dest", filepath.Join(dir, "dest")},
+		{filepath.Join("sub", "..", "dest"), filepath.Join(dir, "dest")},
+		{file, file},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := cfg.resolvePath(tt.path); got != tt.want {
+			t.Errorf("resolvePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	// without working_directory paths are left to the process working directory
+	if got := configFromMeta(nil).resolvePath("dest"); got != "dest" {
+		t.Fatalf("unexpected path %q", got)
+	}
+}
+
+func TestProviderConfigure_minTLSVersion(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    uint16
+		wantErr bool
+	}{
+		{"", tls.VersionTLS12, false},
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"1.1", 0, true},
+	}
+	for _, tt := range tests {
+		raw := map[string]interface{}{}
+		if tt.value != "" {
+			raw["min_tls_version"] = tt.value
+		}
+		meta, diags := providerConfigure("test")(context.Background(), schema.TestResourceDataRaw(t, Provider().Schema, raw))
+		if tt.wantErr {
+			if !diags.HasError() {
+				t.Errorf("expected min_tls_version %q to be rejected", tt.value)
+			}
+			continue
+		}
+		if diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if got := configFromMeta(meta).tlsMinVersion(); got != tt.want {
+			t.Errorf("min_tls_version %q: got %x, want %x", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestProviderConfigure_httpSettings(t *testing.T) {
+	data := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"request_timeout": "2m",
+		"max_retries":     3,
+		"retry_wait":      "250ms",
+	})
+	meta, diags := providerConfigure("test")(context.Background(), data)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	cfg := configFromMeta(meta)
+	tests := []struct {
+		name string
+		raw  map[string]interface{}
+		want httpSettings
+	}{
+		{"inherited", map[string]interface{}{}, httpSettings{timeout: 2 * time.Minute, maxRetries: 3, retryWait: 250 * time.Millisecond}},
+		{"explicit", map[string]interface{}{"timeout": "10s", "max_retries": 0, "retry_wait": "1s"}, httpSettings{timeout: 10 * time.Second, maxRetries: 0, retryWait: time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.raw["url"] = "https://example.com/file"
+			tt.raw["filename"] = "file"
+			got, err := cfg.httpSettings(schema.TestResourceDataRaw(t, resourceURLSchema(), tt.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("unexpected settings %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	// an unconfigured provider uses the defaults
+	got, err := configFromMeta(nil).httpSettings(schema.TestResourceDataRaw(t, dataSourceHTTPSchema(), map[string]interface{}{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (httpSettings{retryWait: defaultRetryWait}) {
+		t.Fatalf("unexpected default settings %+v", got)
+	}
+}
+
+func TestProviderConfigure_proxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a proxied request carries the absolute url of the target
+		proxied = append(proxied, r.URL.String())
+		w.Write([]byte("from proxy"))
+	}))
+	defer proxy.Close()
+	data := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"proxy_url": proxy.URL,
+		"no_proxy":  "direct.invalid",
+	})
+	meta, diags := providerConfigure("test")(context.Background(), data)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	res := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      "http://files.invalid/file.txt",
+		"filename": dest,
+	})
+	if diags := ensureDownloadFile(context.Background(), res, 0, configFromMeta(meta)); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if len(proxied) != 1 || proxied[0] != "http://files.invalid/file.txt" {
+		t.Fatalf("expected the request to go through the proxy, got %v", proxied)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "from proxy" {
+		t.Fatalf("unexpected content %q", b)
+	}
+
+	// hosts in no_proxy are requested directly
+	res = schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":         "http://direct.invalid/file.txt",
+		"filename":    dest,
+		"max_retries": 0,
+	})
+	if diags := ensureDownloadFile(context.Background(), res, 0, configFromMeta(meta)); !diags.HasError() {
+		t.Fatalf("expected the direct request to fail")
+	}
+	if len(proxied) != 1 {
+		t.Fatalf("expected no_proxy host to bypass the proxy, got %v", proxied)
+	}
+}
+
+func TestProviderConfigure_defaultFileMode(t *testing.T) {
+	data := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"default_file_mode": "0999",
+	})
+	if _, diags := providerConfigure("test")(context.Background(), data); !diags.HasError() {
+		t.Fatal("expected default_file_mode 0999 to be rejected")
+	}
+	data = schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"default_file_mode": "0600",
+	})
+	meta, diags := providerConfigure("test")(context.Background(), data)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	cfg := configFromMeta(meta)
+	tests := []struct {
+		name     string
+		fileMode string
+		cfg      *providerConfig
+		want     os.FileMode
+	}{
+		{"provider default", "", cfg, 0600},
+		{"resource file_mode", "0640", cfg, 0640},
+		{"no provider default", "", nil, defaultContentFileMode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{
+				"url":      "http://example.com/file",
+				"filename": "dest",
+			}
+			if tt.fileMode != "" {
+				raw["file_mode"] = tt.fileMode
+			}
+			got, err := getFileMode(schema.TestResourceDataRaw(t, resourceURLSchema(), raw), tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("unexpected mode %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	// inline content of synclocal_file uses the default too
+	dest := filepath.Join(t.TempDir(), "dest")
+	res := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"content":     "hello",
+		"destination": dest,
+	})
+	if diags := resourceFileCreate(context.Background(), res, cfg); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if stat, err := os.Stat(dest); err != nil || stat.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected mode of %q: %v %v", dest, stat, err)
+	}
+}
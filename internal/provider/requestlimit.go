@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// requestLimiter bounds the number of url requests in flight across every
+// resource of the provider. A nil limiter does not limit requests.
+type requestLimiter chan struct{}
+
+func newRequestLimiter(n int) requestLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return make(requestLimiter, n)
+}
+
+// acquire waits for a free slot, or returns the error of ctx if it is done
+// first.
+func (l requestLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l requestLimiter) release() {
+	if l != nil {
+		<-l
+	}
+}
+
+// limitTransport holds a slot of limiter from the start of a request until
+// its response body is closed, so a download counts until it is written.
+type limitTransport struct {
+	base    http.RoundTripper
+	limiter requestLimiter
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.acquire(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.limiter.release()
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: t.limiter.release}
+	return resp, nil
+}
+
+// releaseBody calls release once when it is closed.
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	meta, diags := providerConfigure("test")(context.Background(), schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"max_concurrent_requests": 2,
+	}))
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+			"url":      fmt.Sprintf("%s/file%d", srv.URL, i),
+			"filename": filepath.Join(dir, fmt.Sprintf("dest-%d", i)),
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if diags := resourceURLCreate(context.Background(), data, meta); diags.HasError() {
+				t.Errorf("unexpected error: %v", diags)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxInFlight > 2 {
+		t.Fatalf("%d requests were in flight at once, want at most 2", maxInFlight)
+	}
+	if maxInFlight < 2 {
+		t.Fatalf("requests were not concurrent")
+	}
+}
+
+func TestRequestLimiter_cancel(t *testing.T) {
+	l := newRequestLimiter(1)
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the wait to end with the context, got %v", err)
+	}
+	l.release()
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// an unlimited limiter never waits
+	var unlimited requestLimiter
+	for i := 0; i < 3; i++ {
+		if err := unlimited.acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
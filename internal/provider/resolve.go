@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// validateResolve checks that each key of a resolve map is a host:port and
+// each value is an IP address.
+func validateResolve(v interface{}, k string) (warnings []string, errors []error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be a map", k)}
+	}
+	for hostPort, ip := range m {
+		host, port, err := net.SplitHostPort(hostPort)
+		if err != nil || host == "" {
+			errors = append(errors, fmt.Errorf("%s key %q must be a host:port, such as example.com:443 or [::1]:443", k, hostPort))
+			continue
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			errors = append(errors, fmt.Errorf("%s key %q has an invalid port", k, hostPort))
+		}
+		if s, _ := ip.(string); net.ParseIP(s) == nil {
+			errors = append(errors, fmt.Errorf("%s value %q for %q is not an IP address", k, s, hostPort))
+		}
+	}
+	return
+}
+
+// resolveOverrides returns the dial address of each host:port in resolve,
+// keyed by the lower case host:port. It is empty for resources without it.
+func resolveOverrides(data resourceGetter) map[string]string {
+	v, ok := data.GetOk("resolve")
+	if !ok {
+		return nil
+	}
+	overrides := make(map[string]string)
+	for hostPort, ip := range v.(map[string]interface{}) {
+		_, port, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			continue
+		}
+		overrides[strings.ToLower(hostPort)] = net.JoinHostPort(ip.(string), port)
+	}
+	return overrides
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// resolveDialer returns dial with the addresses in overrides replaced by
+// their IP, like the --resolve option of curl. Other addresses are resolved
+// with DNS as usual. The url host is still used for the Host header and to
+// verify the TLS certificate.
+func resolveDialer(dial dialFunc, overrides map[string]string) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if pinned, ok := overrides[strings.ToLower(addr)]; ok {
+			logEvent("DEBUG", "dialing resolve address", "address", addr, "resolved", pinned)
+			addr = pinned
+		}
+		return dial(ctx, network, addr)
+	}
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       map[string]interface{}
+		wantErr bool
+	}{
+		{"ipv4", map[string]interface{}{"example.com:443": "10.0.0.5"}, false},
+		{"ipv6", map[string]interface{}{"[::1]:443": "::1", "example.com:80": "fd00::5"}, false},
+		{"no port", map[string]interface{}{"example.com": "10.0.0.5"}, true},
+		{"bad port", map[string]interface{}{"example.com:https": "10.0.0.5"}, true},
+		{"no host", map[string]interface{}{":443": "10.0.0.5"}, true},
+		{"not an ip", map[string]interface{}{"example.com:443": "mirror.example.com"}, true},
+		{"bracketed value", map[string]interface{}{"example.com:443": "[::1]"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := validateResolve(tt.m, "resolve")
+			if (len(errs) != 0) != tt.wantErr {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_resolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		ip      string
+	}{
+		{"ipv4", "tcp4", "127.0.0.1"},
+		{"ipv6", "tcp6", "::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := net.Listen(tt.network, net.JoinHostPort(tt.ip, "0"))
+			if err != nil {
+				t.Skipf("%s loopback is not available: %s", tt.network, err)
+			}
+			var host string
+			srv := &httptest.Server{
+				Listener: l,
+				Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					host = r.Host
+					w.Write([]byte("hello"))
+				})},
+			}
+			srv.Start()
+			defer srv.Close()
+			_, port, _ := net.SplitHostPort(l.Addr().String())
+			// the host does not resolve, so the download only works through
+			// resolve
+			pinned := net.JoinHostPort("mirror.invalid", port)
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":      "http://" + pinned + "/file",
+				"filename": dest,
+				"resolve":  map[string]interface{}{"MIRROR.invalid:" + port: tt.ip},
+			})
+			if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+				t.Fatalf("unexpected content %q", b)
+			}
+			if host != pinned {
+				t.Fatalf("unexpected Host %q", host)
+			}
+		})
+	}
+}
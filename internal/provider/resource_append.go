@@ -0,0 +1,231 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	defaultAppendMarker    = "# BEGIN synclocal managed block"
+	defaultAppendMarkerEnd = "# END synclocal managed block"
+)
+
+func resourceAppend() *schema.Resource {
+	return &schema.Resource{
+		ReadContext:   resourceAppendRead,
+		CreateContext: resourceAppendCreate,
+		UpdateContext: resourceAppendUpdate,
+		DeleteContext: resourceAppendDelete,
+		CustomizeDiff: func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+			return configFromMeta(m).checkAllowedRoot(diff, "path")
+		},
+		Schema: resourceAppendSchema(),
+	}
+}
+
+func resourceAppendSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"path": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "File to append the block to. It is created if it does not exist.",
+		},
+		"content": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "Content of the managed block. A trailing newline is added if it is missing.",
+		},
+		"marker": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ForceNew:     true,
+			Default:      defaultAppendMarker,
+			ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringDoesNotContainAny("\r\n")),
+			Description:  "Line that starts the managed block",
+		},
+		"marker_end": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ForceNew:     true,
+			Default:      defaultAppendMarkerEnd,
+			ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringDoesNotContainAny("\r\n")),
+			Description:  "Line that ends the managed block",
+		},
+	}
+}
+
+// managedBlock locates the lines from marker through markerEnd in text.
+// start and end are the byte offsets of the block including its markers.
+type managedBlock struct {
+	start, end int
+	content    string
+}
+
+// findManagedBlock returns the first block delimited by the marker lines,
+// or nil if marker is not in text.
+func findManagedBlock(text, marker, markerEnd string) (*managedBlock, error) {
+	var block *managedBlock
+	var contentStart int
+	for offset := 0; offset < len(text); {
+		next := strings.IndexByte(text[offset:], '\n')
+		lineEnd := len(text)
+		if next >= 0 {
+			lineEnd = offset + next + 1
+		}
+		line := strings.TrimRight(text[offset:lineEnd], "\r\n")
+		switch {
+		case block == nil && line == marker:
+			block = &managedBlock{start: offset}
+			contentStart = lineEnd
+		case block != nil && line == markerEnd:
+			block.end = lineEnd
+			block.content = text[contentStart:offset]
+			return block, nil
+		}
+		offset = lineEnd
+	}
+	if block != nil {
+		return nil, fmt.Errorf("found %q without a following %q", marker, markerEnd)
+	}
+	return nil, nil
+}
+
+// withNewline returns s ending in a newline, unless it is empty.
+func withNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
+
+// readAppendTarget returns the text and mode of path. A file that does not
+// exist yet is empty and created with mode.
+func readAppendTarget(path string, mode os.FileMode) (string, os.FileMode, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", mode, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("could not read %q: %w", path, err)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("could not stat %q: %w", path, err)
+	}
+	return string(b), stat.Mode(), nil
+}
+
+// ensureAppendBlock replaces the managed block in path with content, or
+// appends it if the file has none.
+func ensureAppendBlock(data *schema.ResourceData, cfg *providerConfig) error {
+	path := cfg.resolvePath(data.Get("path").(string))
+	marker := data.Get("marker").(string)
+	markerEnd := data.Get("marker_end").(string)
+	text, mode, err := readAppendTarget(path, cfg.fileMode())
+	if err != nil {
+		return err
+	}
+	blockText := marker + "\n" + withNewline(data.Get("content").(string)) + markerEnd + "\n"
+	block, err := findManagedBlock(text, marker, markerEnd)
+	if err != nil {
+		return fmt.Errorf("could not update %q: %w", path, err)
+	}
+	if block != nil {
+		text = text[:block.start] + blockText + text[block.end:]
+	} else {
+		text = withNewline(text) + blockText
+	}
+	return writeFileAtomic(path, mode, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, func(w io.Writer) error {
+		_, err := io.WriteString(w, text)
+		return err
+	})
+}
+
+func resourceAppendCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "path"); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := ensureAppendBlock(data, configFromMeta(m)); err != nil {
+		return diag.FromErr(err)
+	}
+	id, err := fileToID(configFromMeta(m).resolvePath(data.Get("path").(string)))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	return nil
+}
+
+func resourceAppendUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "path"); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := ensureAppendBlock(data, configFromMeta(m)); err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func resourceAppendRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	path, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		data.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not read %q: %w", path, err))
+	}
+	block, err := findManagedBlock(string(b), data.Get("marker").(string), data.Get("marker_end").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not read %q: %w", path, err))
+	}
+	if block == nil {
+		// the block was removed outside of terraform; append it again
+		data.SetId("")
+		return nil
+	}
+	// keep the configured content if it only lacks the trailing newline
+	if block.content != withNewline(data.Get("content").(string)) {
+		data.Set("content", block.content)
+	}
+	return nil
+}
+
+func resourceAppendDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	path, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	text, mode, err := readAppendTarget(path, configFromMeta(m).fileMode())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	block, err := findManagedBlock(text, data.Get("marker").(string), data.Get("marker_end").(string))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not remove the block from %q: %w", path, err))
+	}
+	if block == nil {
+		return nil
+	}
+	text = text[:block.start] + text[block.end:]
+	err = writeFileAtomic(path, mode, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, func(w io.Writer) error {
+		_, err := io.WriteString(w, text)
+		return err
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
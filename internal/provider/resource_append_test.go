@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestAccResourceAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := ioutil.WriteFile(path, []byte("127.0.0.1 localhost\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := func(content string) string {
+		return fmt.Sprintf(`
+provider "synclocal" {
+}
+
+resource "synclocal_append" "hosts" {
+	path    = %q
+	content = %q
+}
+`, path, content)
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy: func(s *terraform.State) error {
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if string(b) != "127.0.0.1 localhost\n" {
+				return fmt.Errorf("managed block was not removed: %q", b)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config("10.0.0.1 app"),
+				Check:  testAccCheckFileContent(path, "127.0.0.1 localhost\n"+defaultAppendMarker+"\n10.0.0.1 app\n"+defaultAppendMarkerEnd+"\n"),
+			},
+			{
+				Config: config("10.0.0.2 app\n"),
+				Check:  testAccCheckFileContent(path, "127.0.0.1 localhost\n"+defaultAppendMarker+"\n10.0.0.2 app\n"+defaultAppendMarkerEnd+"\n"),
+			},
+		},
+	})
+}
+
+func testAccCheckFileContent(path, want string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if string(b) != want {
+			return fmt.Errorf("unexpected content of %q: %q, want %q", path, b, want)
+		}
+		return nil
+	}
+}
+
+func TestResourceAppend(t *testing.T) {
+	const begin, end = defaultAppendMarker, defaultAppendMarkerEnd
+	tests := []struct {
+		name     string
+		existing *string
+		content  string
+		want     string
+		removed  string
+	}{
+		{
+			name:     "missing file",
+			existing: nil,
+			content:  "line",
+			want:     begin + "\nline\n" + end + "\n",
+			removed:  "",
+		},
+		{
+			name:     "insert",
+			existing: stringPtr("before\n"),
+			content:  "line\n",
+			want:     "before\n" + begin + "\nline\n" + end + "\n",
+			removed:  "before\n",
+		},
+		{
+			name:     "no trailing newline",
+			existing: stringPtr("before"),
+			content:  "line",
+			want:     "before\n" + begin + "\nline\n" + end + "\n",
+			removed:  "before\n",
+		},
+		{
+			name:     "update in place",
+			existing: stringPtr("before\n" + begin + "\nold\n" + end + "\nafter\n"),
+			content:  "new 1\nnew 2",
+			want:     "before\n" + begin + "\nnew 1\nnew 2\n" + end + "\nafter\n",
+			removed:  "before\nafter\n",
+		},
+		{
+			name:     "crlf markers",
+			existing: stringPtr("before\r\n" + begin + "\r\nold\r\n" + end + "\r\nafter\r\n"),
+			content:  "new",
+			want:     "before\r\n" + begin + "\nnew\n" + end + "\nafter\r\n",
+			removed:  "before\r\nafter\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file")
+			if tt.existing != nil {
+				if err := ioutil.WriteFile(path, []byte(*tt.existing), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			data := schema.TestResourceDataRaw(t, resourceAppendSchema(), map[string]interface{}{
+				"path":    path,
+				"content": tt.content,
+			})
+			if diags := resourceAppendCreate(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if b, _ := ioutil.ReadFile(path); string(b) != tt.want {
+				t.Fatalf("unexpected content %q, want %q", b, tt.want)
+			}
+			if tt.existing != nil && runtime.GOOS != "windows" {
+				if stat, err := os.Stat(path); err != nil || stat.Mode().Perm() != 0600 {
+					t.Fatalf("the file mode was not kept: %v %v", stat.Mode(), err)
+				}
+			}
+			if diags := resourceAppendRead(context.Background(), data, nil); diags.HasError() || data.Id() == "" {
+				t.Fatalf("unexpected read result: %v", diags)
+			}
+			if got := data.Get("content").(string); got != tt.content {
+				t.Fatalf("read changed content to %q", got)
+			}
+			if diags := resourceAppendDelete(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if b, _ := ioutil.ReadFile(path); string(b) != tt.removed {
+				t.Fatalf("unexpected content after delete %q, want %q", b, tt.removed)
+			}
+		})
+	}
+}
+
+func TestResourceAppendRead_drift(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	data := schema.TestResourceDataRaw(t, resourceAppendSchema(), map[string]interface{}{
+		"path":    path,
+		"content": "line",
+	})
+	if diags := resourceAppendCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	edited := defaultAppendMarker + "\nedited\n" + defaultAppendMarkerEnd + "\n"
+	if err := ioutil.WriteFile(path, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceAppendRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := data.Get("content").(string); got != "edited\n" {
+		t.Fatalf("content = %q, want the edited block", got)
+	}
+	if err := ioutil.WriteFile(path, []byte("no block\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceAppendRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if data.Id() != "" {
+		t.Fatalf("expected a removed block to remove the resource from state")
+	}
+}
+
+func TestFindManagedBlock_unterminated(t *testing.T) {
+	_, err := findManagedBlock("a\n"+defaultAppendMarker+"\nb\n", defaultAppendMarker, defaultAppendMarkerEnd)
+	if err == nil {
+		t.Fatalf("expected an error for a block without an end marker")
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+)
+
+func resourceArchiveExtract() *schema.Resource {
+	return &schema.Resource{
+		ReadContext:   resourceArchiveExtractRead,
+		CreateContext: resourceArchiveExtractCreate,
+		UpdateContext: resourceArchiveExtractUpdate,
+		DeleteContext: resourceArchiveExtractDelete,
+		CustomizeDiff: func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+			cfg := configFromMeta(m)
+			if err := cfg.checkAllowedRoot(diff, "destination"); err != nil {
+				return err
+			}
+			sourceHash, err := hashFile(cfg.resolvePath(diff.Get("source").(string)))
+			if err != nil {
+				// the archive may be created during apply
+				return diff.SetNewComputed("source_sha256")
+			}
+			if sourceHash != diff.Get("source_sha256").(string) {
+				if err := diff.SetNewComputed("source_sha256"); err != nil {
+					return err
+				}
+				return diff.SetNewComputed("content_sha256")
+			}
+			return nil
+		},
+		Schema: resourceArchiveExtractSchema(),
+	}
+}
+
+func resourceArchiveExtractSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"source": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "Path of the archive. tar, tar.gz and zip archives are detected from their contents or extension.",
+		},
+		"destination": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "Directory to extract the archive into",
+		},
+		"source_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash of the archive that was extracted",
+		},
+		"content_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash over the relative path and hash of every extracted entry",
+		},
+	}
+}
+
+// ensureExtractArchive replaces the contents of destination with the
+// extracted archive.
+func ensureExtractArchive(data *schema.ResourceData, cfg *providerConfig) diag.Diagnostics {
+	source := cfg.resolvePath(data.Get("source").(string))
+	dest := cfg.resolvePath(data.Get("destination").(string))
+	sourceHash, err := hashFile(source)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not hash archive %q: %w", source, err))
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		return diag.FromErr(fmt.Errorf("could not clear destination %q: %w", dest, err))
+	}
+	if err := extractArchiveFile(source, dest, cfg.newCopyBuffer()); err != nil {
+		return diag.FromErr(err)
+	}
+	contentHash, err := hashTree(dest)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.Set("source_sha256", sourceHash)
+	data.Set("content_sha256", contentHash)
+	return nil
+}
+
+func resourceArchiveExtractCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination"); err != nil {
+		return diag.FromErr(err)
+	}
+	if diags := ensureExtractArchive(data, configFromMeta(m)); diags.HasError() {
+		return diags
+	}
+	id, err := fileToID(configFromMeta(m).resolvePath(data.Get("destination").(string)))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	return nil
+}
+
+func resourceArchiveExtractUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination"); err != nil {
+		return diag.FromErr(err)
+	}
+	return ensureExtractArchive(data, configFromMeta(m))
+}
+
+func resourceArchiveExtractRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	dest, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	contentHash, err := hashTree(dest)
+	if os.IsNotExist(err) {
+		data.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if stored, ok := data.GetOk("content_sha256"); ok && stored.(string) != contentHash {
+		// the extracted files were modified outside of terraform; extract again
+		data.SetId("")
+		return nil
+	}
+	return nil
+}
+
+func resourceArchiveExtractDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	dest, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		return diag.FromErr(fmt.Errorf("could not remove directory %q: %w", dest, err))
+	}
+	return nil
+}
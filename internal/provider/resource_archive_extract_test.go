@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAccResourceArchiveExtract(t *testing.T) {
+	archive := testTarGz(t, []testArchiveEntry{
+		{name: "a.txt", body: "alpha\n"},
+		{name: "sub/b.txt", body: "bravo\n"},
+	})
+	if err := ioutil.WriteFile("./testdata/dest-archive.tar.gz", archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("./testdata/dest-archive.tar.gz")
+	want, err := hashTree("./testdata/source-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "synclocal" {
+}
+
+resource "synclocal_archive_extract" "archive" {
+	source      = "./testdata/dest-archive.tar.gz"
+	destination = "./testdata/dest-archive"
+}
+`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("synclocal_archive_extract.archive", "content_sha256", want),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceArchiveExtract(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "archive.zip")
+	if err := ioutil.WriteFile(source, testZip(t, []testArchiveEntry{{name: "a.txt", body: "alpha\n"}}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "out")
+	data := schema.TestResourceDataRaw(t, resourceArchiveExtractSchema(), map[string]interface{}{
+		"source":      source,
+		"destination": dest,
+	})
+	if diags := resourceArchiveExtractCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dest, "a.txt")); err != nil || string(b) != "alpha\n" {
+		t.Fatalf("unexpected extracted content %q: %v", b, err)
+	}
+
+	// modified extracted files are detected as drift
+	if err := ioutil.WriteFile(filepath.Join(dest, "a.txt"), []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceArchiveExtractRead(context.Background(), data, nil); diags.HasError() || data.Id() != "" {
+		t.Fatalf("expected drift to clear the id")
+	}
+
+	id, _ := fileToID(dest)
+	data.SetId(id)
+	if diags := resourceArchiveExtractDelete(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected delete error: %v", diags)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("expected extracted tree to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func resourceCopyDir() *schema.Resource {
+	return &schema.Resource{
+		ReadContext:   resourceCopyDirRead,
+		CreateContext: resourceCopyDirCreate,
+		UpdateContext: resourceCopyDirUpdate,
+		DeleteContext: resourceCopyDirDelete,
+		Timeouts:      copyTimeouts(),
+		CustomizeDiff: func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+			cfg := configFromMeta(m)
+			if err := cfg.checkAllowedRoot(diff, "destination_dir"); err != nil {
+				return err
+			}
+			if !diff.NewValueKnown("source_dir") || !diff.NewValueKnown("include") || !diff.NewValueKnown("exclude") {
+				return setCopyDirComputed(diff)
+			}
+			source := cfg.resolvePath(diff.Get("source_dir").(string))
+			files, err := copyDirFiles(source, stringList(diff.Get("include")), stringList(diff.Get("exclude")))
+			if err != nil {
+				// the source may be created during apply
+				return setCopyDirComputed(diff)
+			}
+			if strings.Join(files, "\n") != strings.Join(stringList(diff.Get("files")), "\n") {
+				return setCopyDirComputed(diff)
+			}
+			sourceHash, err := hashDirFiles(source, files)
+			if err != nil {
+				return setCopyDirComputed(diff)
+			}
+			destHash, err := hashDirFiles(cfg.resolvePath(diff.Get("destination_dir").(string)), files)
+			if err != nil || destHash != sourceHash {
+				return setCopyDirComputed(diff)
+			}
+			return nil
+		},
+		Schema: resourceCopyDirSchema(),
+	}
+}
+
+func resourceCopyDirSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"source_dir": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "Directory to copy files from",
+		},
+		"destination_dir": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "Directory to copy files into. It is created if it does not exist.",
+		},
+		"include": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Description: "Glob patterns of the files to copy. A pattern with a slash matches the path relative to source_dir, such as \"bin/*\"; one without matches the file name, such as \"*.conf\". All files are copied if it is empty.",
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validateCopyDirPattern,
+			},
+		},
+		"exclude": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Description: "Glob patterns of files and directories not to copy, matched like include. Exclusions win over include, and an excluded directory excludes everything below it.",
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validateCopyDirPattern,
+			},
+		},
+		"preserve_modes": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     true,
+			Description: "Give each file the mode of its source. Otherwise files get the provider default_file_mode, or 0664.",
+		},
+		"files": {
+			Type:        schema.TypeList,
+			Computed:    true,
+			Description: "Paths of the copied files relative to destination_dir, with forward slashes",
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"written_files": {
+			Type:        schema.TypeList,
+			Computed:    true,
+			Description: "Paths of files that this resource wrote, which excludes files that were already in destination_dir with the same content. Only these files are removed on destroy, or when they are no longer selected.",
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"content_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash over the relative path and hash of every copied file. It changes when any of them drifts from the source.",
+		},
+	}
+}
+
+func validateCopyDirPattern(v interface{}, k string) (warnings []string, errors []error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+	if _, err := path.Match(s, ""); err != nil {
+		errors = append(errors, fmt.Errorf("%s %q is not a valid glob pattern: %w", k, s, err))
+	}
+	return
+}
+
+func setCopyDirComputed(diff *schema.ResourceDiff) error {
+	if err := diff.SetNewComputed("files"); err != nil {
+		return err
+	}
+	if err := diff.SetNewComputed("written_files"); err != nil {
+		return err
+	}
+	return diff.SetNewComputed("content_sha256")
+}
+
+// matchCopyDirPattern reports whether the slash separated path rel matches
+// pattern. A pattern without a slash is matched against the base name.
+func matchCopyDirPattern(pattern, rel string) bool {
+	name := rel
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(rel)
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+func matchAnyCopyDirPattern(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if matchCopyDirPattern(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyDirFiles returns the sorted slash separated paths of the files below
+// root that are selected by include and exclude.
+func copyDirFiles(root string, include, exclude []string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			if !d.IsDir() {
+				return fmt.Errorf("%q is not a directory", root)
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchAnyCopyDirPattern(exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if len(include) == 0 || matchAnyCopyDirPattern(include, rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// hashDirFiles hashes the relative path and hash of each of files below
+// root, in order.
+func hashDirFiles(root string, files []string) (string, error) {
+	h := sha256.New()
+	for _, rel := range files {
+		fileHash, err := hashFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %s\n", rel, fileHash)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ensureCopyDir copies the selected files that differ from the source, and
+// removes the files it wrote in an earlier apply that are no longer
+// selected. A file already in destination_dir with the content of the
+// source is not written, so it is not in written_files and is left alone.
+func ensureCopyDir(ctx context.Context, data *schema.ResourceData, cfg *providerConfig) diag.Diagnostics {
+	source := cfg.resolvePath(data.Get("source_dir").(string))
+	dest := cfg.resolvePath(data.Get("destination_dir").(string))
+	files, err := copyDirFiles(source, stringList(data.Get("include")), stringList(data.Get("exclude")))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not list source_dir %q: %w", source, err))
+	}
+	var mode os.FileMode
+	if !data.Get("preserve_modes").(bool) {
+		mode = cfg.fileMode()
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return diag.FromErr(fmt.Errorf("could not create directory %q: %w", dest, err))
+	}
+	buf := cfg.newCopyBuffer()
+	oldWritten, _ := data.GetChange("written_files")
+	written := make(map[string]bool)
+	for _, rel := range stringList(oldWritten) {
+		written[rel] = true
+	}
+	selected := make(map[string]bool, len(files))
+	var writtenFiles []string
+	for _, rel := range files {
+		selected[rel] = true
+		src := filepath.Join(source, filepath.FromSlash(rel))
+		dst := filepath.Join(dest, filepath.FromSlash(rel))
+		srcHash, err := hashFile(src)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if dstHash, err := hashFile(dst); err == nil && dstHash == srcHash {
+			if written[rel] {
+				writtenFiles = append(writtenFiles, rel)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return diag.FromErr(fmt.Errorf("could not create directory %q: %w", filepath.Dir(dst), err))
+		}
+		if err := copyFile(ctx, src, dst, mode, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, buf); err != nil {
+			return diag.FromErr(err)
+		}
+		writtenFiles = append(writtenFiles, rel)
+	}
+	for _, rel := range stringList(oldWritten) {
+		if selected[rel] {
+			continue
+		}
+		name := filepath.Join(dest, filepath.FromSlash(rel))
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return diag.FromErr(fmt.Errorf("could not remove file %q: %w", name, err))
+		}
+	}
+	logEvent("INFO", "copied directory", "source", source, "destination", dest, "files", len(files))
+	data.Set("files", files)
+	data.Set("written_files", writtenFiles)
+	return nil
+}
+
+func resourceCopyDirCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination_dir"); err != nil {
+		return diag.FromErr(err)
+	}
+	cfg := configFromMeta(m)
+	if diags := timeoutDiagnostics(ctx, ensureCopyDir(ctx, data, cfg), data, schema.TimeoutCreate); diags.HasError() {
+		return diags
+	}
+	id, err := fileToID(cfg.resolvePath(data.Get("destination_dir").(string)))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	return resourceCopyDirRead(ctx, data, m)
+}
+
+func resourceCopyDirUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination_dir"); err != nil {
+		return diag.FromErr(err)
+	}
+	if diags := timeoutDiagnostics(ctx, ensureCopyDir(ctx, data, configFromMeta(m)), data, schema.TimeoutUpdate); diags.HasError() {
+		return diags
+	}
+	return resourceCopyDirRead(ctx, data, m)
+}
+
+func resourceCopyDirRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	dest, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if _, err := os.Stat(dest); os.IsNotExist(err) {
+		data.SetId("")
+		return nil
+	}
+	destHash, err := hashDirFiles(dest, stringList(data.Get("files")))
+	if err != nil {
+		// a copied file is missing or unreadable, which the next plan
+		// replaces
+		destHash = ""
+	}
+	data.Set("content_sha256", destHash)
+	return nil
+}
+
+func resourceCopyDirDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	dest, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	// other files in the directory, and the directories, are left alone
+	for _, rel := range stringList(data.Get("written_files")) {
+		name := filepath.Join(dest, filepath.FromSlash(rel))
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return diag.FromErr(fmt.Errorf("could not remove file %q: %w", name, err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testCopyDirSource creates a source tree for synclocal_copy_dir.
+func testCopyDirSource(t *testing.T) string {
+	t.Helper()
+	source := t.TempDir()
+	for name, content := range map[string]string{
+		"app.conf":        "conf",
+		"README.md":       "readme",
+		"bin/tool":        "tool",
+		"bin/tool.debug":  "debug",
+		"etc/extra.conf":  "extra",
+		".git/config":     "git",
+		"docs/index.html": "docs",
+	} {
+		p := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return source
+}
+
+func TestResourceCopyDir_filters(t *testing.T) {
+	source := testCopyDirSource(t)
+	tests := []struct {
+		name    string
+		include []interface{}
+		exclude []interface{}
+		want    []string
+	}{
+		{"all", nil, nil, []string{".git/config", "README.md", "app.conf", "bin/tool", "bin/tool.debug", "docs/index.html", "etc/extra.conf"}},
+		{"include", []interface{}{"*.conf", "bin/*"}, nil, []string{"app.conf", "bin/tool", "bin/tool.debug", "etc/extra.conf"}},
+		{"exclude", nil, []interface{}{".git", "*.debug", "docs"}, []string{"README.md", "app.conf", "bin/tool", "etc/extra.conf"}},
+		{"include and exclude", []interface{}{"*.conf", "bin/*"}, []interface{}{"*.debug", "etc"}, []string{"app.conf", "bin/tool"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest")
+			raw := map[string]interface{}{
+				"source_dir":      source,
+				"destination_dir": dest,
+			}
+			if tt.include != nil {
+				raw["include"] = tt.include
+			}
+			if tt.exclude != nil {
+				raw["exclude"] = tt.exclude
+			}
+			data := schema.TestResourceDataRaw(t, resourceCopyDirSchema(), raw)
+			if diags := resourceCopyDirCreate(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if got := stringList(data.Get("files")); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("unexpected files %v, want %v", got, tt.want)
+			}
+			written, err := copyDirFiles(dest, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(written, tt.want) {
+				t.Fatalf("unexpected files in destination %v, want %v", written, tt.want)
+			}
+			want, _ := hashDirFiles(source, tt.want)
+			if got := data.Get("content_sha256").(string); got != want {
+				t.Fatalf("unexpected content_sha256 %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestResourceCopyDir_drift(t *testing.T) {
+	source := testCopyDirSource(t)
+	dest := filepath.Join(t.TempDir(), "dest")
+	raw := map[string]interface{}{
+		"source_dir":      source,
+		"destination_dir": dest,
+		"include":         []interface{}{"*.conf"},
+	}
+	data := schema.TestResourceDataRaw(t, resourceCopyDirSchema(), raw)
+	if diags := resourceCopyDirCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	created := data.Get("content_sha256").(string)
+	config := terraform.NewResourceConfigRaw(raw)
+	diff, err := resourceCopyDir().Diff(context.Background(), data.State(), config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != nil && diff.Attributes["content_sha256"] != nil {
+		t.Fatalf("expected no changes, got %#v", diff.Attributes["content_sha256"])
+	}
+	// a single changed file is drift that the next apply reverts
+	changed := filepath.Join(dest, "etc", "extra.conf")
+	if err := ioutil.WriteFile(changed, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceCopyDirRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if data.Get("content_sha256").(string) == created {
+		t.Fatal("content_sha256 did not change with the destination")
+	}
+	state := data.State()
+	diff, err = resourceCopyDir().Diff(context.Background(), state, config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == nil || diff.Attributes["content_sha256"] == nil || !diff.Attributes["content_sha256"].NewComputed {
+		t.Fatalf("expected content_sha256 to change, got %#v", diff)
+	}
+	data, err = schema.InternalMap(resourceCopyDirSchema()).Data(state, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceCopyDirUpdate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(changed); string(b) != "extra" {
+		t.Fatalf("unexpected content %q after update", b)
+	}
+	if got := data.Get("content_sha256").(string); got != created {
+		t.Fatalf("unexpected content_sha256 %q after update, want %q", got, created)
+	}
+}
+
+func TestResourceCopyDirDelete(t *testing.T) {
+	source := testCopyDirSource(t)
+	dest := t.TempDir()
+	other := filepath.Join(dest, "other")
+	if err := ioutil.WriteFile(other, []byte("not copied"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// a selected file that is already there with the same content is not
+	// written, so it is not removed either
+	same := filepath.Join(dest, "bin", "tool")
+	if err := os.MkdirAll(filepath.Dir(same), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(same, []byte("tool"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceCopyDirSchema(), map[string]interface{}{
+		"source_dir":      source,
+		"destination_dir": dest,
+		"include":         []interface{}{"bin/*"},
+	})
+	if diags := resourceCopyDirCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := stringList(data.Get("written_files")); !reflect.DeepEqual(got, []string{"bin/tool.debug"}) {
+		t.Fatalf("unexpected written_files %v", got)
+	}
+	if diags := resourceCopyDirDelete(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	remaining, err := copyDirFiles(dest, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(remaining, []string{"bin/tool", "other"}) {
+		t.Fatalf("unexpected files after destroy %v", remaining)
+	}
+}
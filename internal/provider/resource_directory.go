@@ -0,0 +1,173 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+func resourceDirectory() *schema.Resource {
+	return &schema.Resource{
+		ReadContext:   resourceDirectoryRead,
+		CreateContext: resourceDirectoryCreate,
+		UpdateContext: resourceDirectoryUpdate,
+		DeleteContext: resourceDirectoryDelete,
+		CustomizeDiff: func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+			return configFromMeta(m).checkAllowedRoot(diff, "path")
+		},
+		Schema: resourceDirectorySchema(),
+	}
+}
+
+func resourceDirectorySchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"path": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "Path of the directory",
+		},
+		"dir_mode": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Default:     "0755",
+			Description: "File mode for the directory (Octal String)",
+		},
+		"recursive": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Create missing parent directories as well. Parents are created with dir_mode, subject to the umask.",
+		},
+		"delete_only_if_empty": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Only remove the directory on destroy if it is empty. By default the directory and its contents are removed.",
+		},
+	}
+}
+
+func getDirMode(data resourceGetter) (os.FileMode, error) {
+	m, err := strconv.ParseUint(data.Get("dir_mode").(string), 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("dir_mode is not a valid octal number: %w", err)
+	}
+	return os.FileMode(m), nil
+}
+
+func resourceDirectoryCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "path"); err != nil {
+		return diag.FromErr(err)
+	}
+	path := configFromMeta(m).resolvePath(data.Get("path").(string))
+	mode, err := getDirMode(data)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if data.Get("recursive").(bool) {
+		err = os.MkdirAll(path, mode)
+	} else {
+		err = os.Mkdir(path, mode)
+	}
+	if err != nil && !os.IsExist(err) {
+		return diag.FromErr(fmt.Errorf("could not create directory %q: %w", path, err))
+	}
+	if diags := ensureDirMode(path, mode); diags.HasError() {
+		return diags
+	}
+	id, err := fileToID(path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	return resourceDirectoryRead(ctx, data, m)
+}
+
+func resourceDirectoryUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "path"); err != nil {
+		return diag.FromErr(err)
+	}
+	mode, err := getDirMode(data)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if diags := ensureDirMode(configFromMeta(m).resolvePath(data.Get("path").(string)), mode); diags.HasError() {
+		return diags
+	}
+	return resourceDirectoryRead(ctx, data, m)
+}
+
+// ensureDirMode sets the mode of the directory, which Mkdir leaves subject
+// to the umask. It fails if path exists but is not a directory.
+func ensureDirMode(path string, mode os.FileMode) diag.Diagnostics {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not stat directory %q: %w", path, err))
+	}
+	if !stat.IsDir() {
+		return diag.Errorf("%q exists and is not a directory", path)
+	}
+	if stat.Mode().Perm() == mode.Perm() || runtime.GOOS == "windows" {
+		return nil
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return diag.FromErr(fmt.Errorf("could not set mode of directory %q: %w", path, err))
+	}
+	return nil
+}
+
+func resourceDirectoryRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	path, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) || (err == nil && !stat.IsDir()) {
+		data.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not stat directory %q: %w", path, err))
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	// keep the configured spelling of dir_mode unless the mode changed
+	if mode, err := getDirMode(data); err != nil || mode.Perm() != stat.Mode().Perm() {
+		data.Set("dir_mode", fmt.Sprintf("%04o", stat.Mode().Perm()))
+	}
+	return nil
+}
+
+func resourceDirectoryDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	path, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if !data.Get("delete_only_if_empty").(bool) {
+		if err := os.RemoveAll(path); err != nil {
+			return diag.FromErr(fmt.Errorf("could not remove directory %q: %w", path, err))
+		}
+		return nil
+	}
+	entries, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not read directory %q: %w", path, err))
+	}
+	if len(entries) > 0 {
+		// leave a directory that still has contents in place
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("could not remove directory %q: %w", path, err))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestAccResourceDirectory(t *testing.T) {
+	config := func(mode string) string {
+		return `
+provider "synclocal" {
+}
+
+resource "synclocal_directory" "dir" {
+	path      = "./testdata/dest-directory/nested"
+	recursive = true
+	dir_mode  = "` + mode + `"
+}
+`
+	}
+	defer os.RemoveAll("./testdata/dest-directory")
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config("0755"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckFileMode("./testdata/dest-directory/nested", 0755),
+				),
+			},
+			{
+				Config: config("0700"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("synclocal_directory.dir", "dir_mode", "0700"),
+					testAccCheckFileMode("./testdata/dest-directory/nested", 0700),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceDirectory_modeReconcile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	data := schema.TestResourceDataRaw(t, resourceDirectorySchema(), map[string]interface{}{
+		"path":      dir,
+		"recursive": true,
+		"dir_mode":  "755",
+	})
+	if diags := resourceDirectoryCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := data.Get("dir_mode").(string); got != "755" {
+		t.Fatalf("expected configured dir_mode to be kept, got %q", got)
+	}
+
+	// drift is reported by Read and corrected by Update
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceDirectoryRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected read error: %v", diags)
+	}
+	if got := data.Get("dir_mode").(string); got != "0700" {
+		t.Fatalf("expected dir_mode drift to be read, got %q", got)
+	}
+	data.Set("dir_mode", "0750")
+	if diags := resourceDirectoryUpdate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected update error: %v", diags)
+	}
+	stat, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode().Perm() != 0750 {
+		t.Fatalf("unexpected mode %s", stat.Mode().Perm())
+	}
+}
+
+func TestResourceDirectoryDelete_onlyIfEmpty(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "keep"), []byte("hello"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := fileToID(dir)
+	data := schema.TestResourceDataRaw(t, resourceDirectorySchema(), map[string]interface{}{
+		"path":                 dir,
+		"delete_only_if_empty": true,
+	})
+	data.SetId(id)
+	if diags := resourceDirectoryDelete(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("expected non-empty directory to be kept: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "keep")); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceDirectoryDelete(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("expected empty directory to be removed, got %v", err)
+	}
+}
@@ -1,17 +1,26 @@
 package provider
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"hash"
 	"io"
+	"io/fs"
 	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 func resourceFile() *schema.Resource {
@@ -20,18 +29,98 @@ func resourceFile() *schema.Resource {
 		CreateContext: resourceFileCreate,
 		UpdateContext: resourceFileUpdate,
 		DeleteContext: resourceFileDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceFileImport,
+		},
+		Timeouts:       copyTimeouts(),
+		SchemaVersion:  1,
+		StateUpgraders: managedStateUpgraders(resourceFileSchema()),
 		CustomizeDiff: func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
-			destHash, err := hashFile(diff.Get("destination").(string))
+			cfg := configFromMeta(m)
+			if err := cfg.checkAllowedRoot(diff, "destination"); err != nil {
+				return err
+			}
+			if diff.HasChange("hash_algorithm") {
+				if err := diff.SetNewComputed("content_hash"); err != nil {
+					return err
+				}
+			}
+			source := cfg.resolvePath(diff.Get("source").(string))
+			dest := cfg.resolvePath(diff.Get("destination").(string))
+			if diff.NewValueKnown("source") && sourceMoved(diff, source, dest) {
+				return nil
+			}
+			if err := customizeSourceSHA256(diff, cfg); err != nil {
+				return err
+			}
+			recursive := diff.Get("recursive").(bool)
+			if diff.Get("source_glob").(bool) {
+				return customizeDiffGlob(diff, cfg)
+			}
+			destHash, err := hashPath(dest, recursive)
 			if os.IsNotExist(err) {
-				return diff.SetNewComputed("content_sha256")
+				return setContentComputed(diff)
 			}
 
-			srcHash, err := hashFile(diff.Get("source").(string))
+			if !diff.NewValueKnown("content") || !diff.NewValueKnown("content_base64") {
+				return setContentComputed(diff)
+			}
+			content, inline, err := inlineContent(diff)
 			if err != nil {
 				return err
 			}
+			le := lineEndingsFrom(diff)
+			var srcHash string
+			if inline {
+				if content, err = le.convertBytes(content); err != nil {
+					return err
+				}
+				srcHash = hashBytes(content)
+			} else if recursive {
+				if srcHash, err = hashPath(source, recursive); err != nil {
+					return err
+				}
+			} else if srcHash, err = decompressionFrom(diff).hashFile(source, le); err != nil {
+				return err
+			}
+			if destHash != srcHash && !recursive {
+				var same bool
+				if inline {
+					same, err = newlineOnlyChange(diff, bytes.NewReader(content), dest)
+				} else {
+					same, err = newlineOnlyChangeFile(diff, source, dest)
+				}
+				if err != nil {
+					return err
+				}
+				if same {
+					destHash = srcHash
+				}
+			}
 			if destHash != srcHash {
-				return diff.SetNewComputed("content_sha256")
+				if !diff.Get("overwrite").(bool) {
+					return noOverwriteError(dest)
+				}
+				return setContentComputed(diff)
+			}
+			changed, err := ownershipChanged(diff, cfg)
+			if err != nil {
+				return err
+			}
+			if changed {
+				return setContentComputed(diff)
+			}
+			if changed, err = modeChanged(diff, cfg); err != nil {
+				return err
+			}
+			if changed {
+				return setContentComputed(diff)
+			}
+			if changed, err = xattrsChanged(diff, cfg); err != nil {
+				return err
+			}
+			if changed {
+				return setContentComputed(diff)
 			}
 			return nil
 		},
@@ -42,35 +131,309 @@ func resourceFile() *schema.Resource {
 func resourceFileSchema() map[string]*schema.Schema {
 	return map[string]*schema.Schema{
 		"source": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ExactlyOneOf: []string{"source", "content", "content_base64"},
+			Description:  "source file path",
+		},
+		"content": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Content to write to the destination instead of copying source",
+		},
+		"content_base64": {
 			Type:        schema.TypeString,
-			Required:    true,
-			Description: "source file path",
+			Optional:    true,
+			Description: "Base64 encoded content to write to the destination instead of copying source. Use this for binary content.",
 		},
 		"destination": {
-			Type:        schema.TypeString,
-			Required:    true,
-			Description: "Destination file path",
-			ForceNew:    true,
+			Type:             schema.TypeString,
+			Required:         true,
+			Description:      "Destination file path",
+			ForceNew:         true,
+			DiffSuppressFunc: suppressEquivalentPathCase,
 		},
 		"file_mode": {
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateDiagFunc: validateFileMode,
+			DiffSuppressFunc: suppressEquivalentFileMode,
+			Description:      "File mode for the destination (Octal String). Mirrors the source file if not provided, or is the provider default_file_mode (0664 unless set) for inline content.",
+		},
+		"content_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash of the file contents. For a recursive copy this is a hash over the relative path and hash of every entry in the tree.",
+		},
+		"managed": managedSchema(),
+		"source_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash of the source, or of the inline content, hashed the same way as content_sha256 but before line_endings are converted. It differs from content_sha256 when the destination was converted or has drifted from the source. Empty if the source cannot be read.",
+		},
+		"expected_sha256": {
 			Type:        schema.TypeString,
 			Optional:    true,
-			Description: "File mode for the destination (Octal String). Mirrors the source file if not provided.",
+			Description: "Refuse to copy unless the source has this SHA256 hash. It is compared the same way as content_sha256, so for a recursive or source_glob copy it is the aggregate hash. The source is checked before line_endings are converted.",
 		},
-		"content_sha256": {
+		"hash_algorithm": hashAlgorithmSchema(false),
+		"content_hash":   contentHashSchema(),
+		"recursive": {
+			Type:          schema.TypeBool,
+			Optional:      true,
+			Default:       false,
+			ForceNew:      true,
+			ConflictsWith: []string{"content", "content_base64", "source_glob"},
+			Description:   "Mirror the source directory tree into destination. Files and directories in destination that are not in source are removed.",
+		},
+		"owner": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Owner of the destination, as a user name or numeric uid. Not supported on Windows.",
+		},
+		"group": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Group of the destination, as a group name or numeric gid. Not supported on Windows.",
+		},
+		"preserve_timestamps": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Set the modification time of the destination to that of the source",
+		},
+		"preserve_xattrs": {
+			Type:          schema.TypeBool,
+			Optional:      true,
+			Default:       false,
+			ConflictsWith: []string{"content", "content_base64", "recursive", "source_glob"},
+			Description:   "Copy the extended attributes of the source, such as SELinux labels or the macOS quarantine flag, to the destination. They are copied again when they differ. Attributes that only the destination has are kept. Only supported on Linux and macOS; elsewhere a warning is reported.",
+		},
+		"source_glob": {
+			Type:          schema.TypeBool,
+			Optional:      true,
+			Default:       false,
+			ForceNew:      true,
+			ConflictsWith: []string{"content", "content_base64", "backup"},
+			Description:   "Treat source as a glob pattern and copy every matching file into the destination directory",
+		},
+		"files": {
+			Type:        schema.TypeList,
+			Computed:    true,
+			Description: "Paths of the files written by a source_glob copy",
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"normalize_path_case": normalizePathCaseSchema(),
+		"create_parents":      createParentsSchema(),
+		"dir_mode":            dirModeSchema(),
+		"overwrite": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     true,
+			Description: "Replace the destination if it exists with different content. If false, planning and applying fail instead.",
+		},
+		"backup": {
+			Type:          schema.TypeBool,
+			Optional:      true,
+			Default:       false,
+			ConflictsWith: []string{"recursive"},
+			Description:   "Copy an existing destination with different content to backup_path before the resource first overwrites it, on create or after an import. Later updates do not take a backup, so it is always of the file found before the resource wrote it.",
+		},
+		"backup_suffix": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      ".bak",
+			ValidateFunc: validation.StringIsNotEmpty,
+			Description:  "Suffix appended to destination to form the backup path",
+		},
+		"backup_path": {
 			Type:        schema.TypeString,
 			Computed:    true,
-			Description: "SHA256 hash of the file contents",
+			Description: "Path of the backup of the destination as it was before the resource first wrote it, if any",
+		},
+		"restore_backup_on_destroy": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "On destroy, move the backup back to the destination instead of removing the destination. By default the destination is removed and the backup is left in place.",
 		},
+		"keep_on_destroy":  keepOnDestroySchema(),
+		"no_follow":        noFollowSchema(),
+		"exclusive_create": exclusiveCreateSchema(),
+		"line_endings": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			Default:       "preserve",
+			ValidateFunc:  validation.StringInSlice([]string{"lf", "crlf", "preserve"}, false),
+			ConflictsWith: []string{"recursive", "source_glob"},
+			Description:   "Convert the line endings of text content to lf or crlf while it is copied. A CR that is not followed by LF is kept. content_sha256 is the hash of the converted content.",
+		},
+		"decompress": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			Default:       string(decompressNone),
+			ValidateFunc:  validation.StringInSlice(decompressions, false),
+			ConflictsWith: []string{"content", "content_base64", "recursive", "source_glob"},
+			Description:   "Decompress the source while it is copied: none, gzip, bzip2, xz, or auto to detect the algorithm from the first bytes of the source and copy it as is if it is not compressed. content_sha256 is the hash of the decompressed content, while source_sha256 and expected_sha256 are of the compressed source.",
+		},
+		"link": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			Default:       linkCopy,
+			ForceNew:      true,
+			ValidateFunc:  validation.StringInSlice([]string{linkCopy, linkHardlink, linkSymlink}, false),
+			ConflictsWith: []string{"content", "content_base64", "recursive", "source_glob", "line_endings", "decompress", "file_mode", "owner", "group", "backup", "preserve_timestamps", "preserve_xattrs", "no_follow"},
+			Description:   "How the destination is made from source: copy, hardlink or symlink. A hardlink shares the data and permissions of source and must be on the same filesystem. A symlink stores the absolute path of source. The destination is replaced if it is not already that link.",
+		},
+		"text": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Treat the source as text for line_endings and ignore_trailing_newline. By default they only apply if the start of the content is detected as text.",
+		},
+		"ignore_trailing_newline": {
+			Type:          schema.TypeBool,
+			Optional:      true,
+			Default:       false,
+			ConflictsWith: []string{"recursive", "source_glob", "link"},
+			Description:   "Do not update the destination if it only differs from the content by a single trailing LF or CRLF. This only applies if both are detected as text, or text is set. content_sha256 stays the hash of the destination as it is.",
+		},
+		"delete_source": {
+			Type:          schema.TypeBool,
+			Optional:      true,
+			Default:       false,
+			ConflictsWith: []string{"content", "content_base64", "recursive", "source_glob", "link"},
+			Description:   "Remove source once it is copied and the destination is verified to have its content, which moves the file. Nothing is removed if source and destination are the same file. Once source is gone the destination is no longer compared against it.",
+		},
+	}
+}
+
+func keepOnDestroySchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "Leave the destination in place when the resource is destroyed. Only the Terraform state is removed.",
+	}
+}
+
+func noFollowSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "Refuse to write through a symlink at the destination path (O_NOFOLLOW). Not supported on Windows.",
+	}
+}
+
+func exclusiveCreateSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "Fail if the destination already exists when the resource is created (O_EXCL). Later updates overwrite the file as usual.",
+	}
+}
+
+func createParentsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     true,
+		Description: "Create missing parent directories of the destination",
+	}
+}
+
+func dirModeSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Default:     "0755",
+		Description: "File mode for parent directories created by create_parents (Octal String)",
+	}
+}
+
+func normalizePathCaseSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "Resolve the destination path to the case stored on disk when computing the resource ID. Enable this on case-insensitive filesystems (macOS, Windows) so paths that differ only by case refer to the same resource.",
+	}
+}
+
+// resourceFileImport adopts an existing destination given by its path or
+// file:// ID. source is not known until the next apply, which reconciles it.
+func resourceFileImport(ctx context.Context, data *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	path, err := importPath(data.Id(), configFromMeta(m))
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not import %q: %w", path, err)
+	}
+	recursive := stat.IsDir()
+	hash, err := hashPath(path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	id, err := fileToID(path)
+	if err != nil {
+		return nil, err
+	}
+	setImportDefaults(data, resourceFileSchema())
+	data.SetId(id)
+	data.Set("destination", path)
+	data.Set("recursive", recursive)
+	data.Set("content_sha256", hash)
+	data.Set("content_hash", hash)
+	data.Set("managed", false)
+	return []*schema.ResourceData{data}, nil
+}
+
+// importPath returns the absolute path from an import ID, which is either a
+// path or a file:// resource ID. A relative path is resolved against
+// working_directory.
+func importPath(id string, cfg *providerConfig) (string, error) {
+	if strings.HasPrefix(id, "file:") {
+		return idToFile(id)
+	}
+	return filepath.Abs(cfg.resolvePath(id))
+}
+
+// setImportDefaults sets attributes with a default value, since imported
+// state would otherwise differ from the configuration on the next plan.
+func setImportDefaults(data *schema.ResourceData, sm map[string]*schema.Schema) {
+	for k, s := range sm {
+		if s.Default != nil {
+			data.Set(k, s.Default)
+		}
 	}
 }
 
 func resourceFileDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if data.Get("keep_on_destroy").(bool) {
+		return nil
+	}
 	id := data.Id()
 	name, err := idToFile(id)
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	if !data.Get("managed").(bool) {
+		return keepOnDelete(data, name, false)
+	}
+	if backup, ok := data.GetOk("backup_path"); ok && data.Get("restore_backup_on_destroy").(bool) {
+		if _, err := os.Stat(backup.(string)); err == nil {
+			if err := os.Rename(backup.(string), name); err != nil {
+				return diag.FromErr(fmt.Errorf("could not restore backup %q to %q: %w", backup, name, err))
+			}
+			return nil
+		}
+	}
 	_, err = os.Stat(name)
 	if os.IsNotExist(err) {
 		return nil
@@ -78,6 +441,26 @@ func resourceFileDelete(ctx context.Context, data *schema.ResourceData, m interf
 	if err != nil {
 		return diag.FromErr(fmt.Errorf("could not stat file %q: %w", name, err))
 	}
+	// content_sha256 is only the hash of the destination for a single copy
+	single := !data.Get("recursive").(bool) && !data.Get("source_glob").(bool) && data.Get("link").(string) == linkCopy
+	if diags := keepOnDelete(data, name, single); diags != nil {
+		return diags
+	}
+	if data.Get("recursive").(bool) {
+		if err := os.RemoveAll(name); err != nil {
+			return diag.FromErr(fmt.Errorf("could not remove directory %q: %w", name, err))
+		}
+		return nil
+	}
+	if data.Get("source_glob").(bool) {
+		// only the copied files are removed; the directory may hold others
+		for _, f := range stringList(data.Get("files")) {
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				return diag.FromErr(fmt.Errorf("could not remove file %q: %w", f, err))
+			}
+		}
+		return nil
+	}
 	if err := os.Remove(name); err != nil {
 		return diag.FromErr(fmt.Errorf("could not remove file %q: %w", name, err))
 	}
@@ -85,12 +468,29 @@ func resourceFileDelete(ctx context.Context, data *schema.ResourceData, m interf
 }
 
 func resourceFileRead(ctx context.Context, data *schema.ResourceData, m interface{}) (diags diag.Diagnostics) {
+	cfg := configFromMeta(m)
 	file, err := idToFile(data.Id())
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	fileHash, err := hashFile(file)
-
+	if link := data.Get("link").(string); link != linkCopy {
+		linked, err := isLinked(link, cfg.resolvePath(data.Get("source").(string)), file)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if !linked {
+			// removed, or replaced by something that is not the link
+			data.SetId("")
+			return nil
+		}
+	}
+	hashWith := func(newHash func() hash.Hash) (string, error) {
+		if data.Get("source_glob").(bool) {
+			return hashFileSetWith(stringList(data.Get("files")), newHash)
+		}
+		return hashPathWith(file, data.Get("recursive").(bool), newHash)
+	}
+	fileHash, err := hashWith(sha256.New)
 	if os.IsNotExist(err) {
 		data.SetId("")
 		return nil
@@ -98,41 +498,112 @@ func resourceFileRead(ctx context.Context, data *schema.ResourceData, m interfac
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	contentHash, err := contentHashWith(data, fileHash, hashWith)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	data.Set("content_sha256", fileHash)
+	data.Set("content_hash", contentHash)
+	// a moved source keeps the hash it was copied with
+	if sourceMoved(data, cfg.resolvePath(data.Get("source").(string)), file) {
+		return nil
+	}
+	// the source may be gone, or unknown after an import, which leaves
+	// nothing to compare against
+	sourceHash, err := sourceSHA256(data, cfg)
+	if err != nil {
+		sourceHash = ""
+	}
+	data.Set("source_sha256", sourceHash)
 	return nil
 }
 
 func resourceFileUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) (diags diag.Diagnostics) {
-	diags = ensureCopyFile(data)
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination"); err != nil {
+		return diag.FromErr(err)
+	}
+	previous, _ := data.GetChange("content_sha256")
+	defer func() {
+		if !diags.HasError() {
+			setManaged(data, previous.(string))
+		}
+	}()
+	diags = timeoutDiagnostics(ctx, ensureCopyFile(ctx, data, configFromMeta(m)), data, schema.TimeoutUpdate)
 	if diags.HasError() {
 		return
 	}
-	return resourceFileRead(ctx, data, m)
+	if diags = append(diags, ensureOwnership(data, configFromMeta(m))...); diags.HasError() {
+		return
+	}
+	if diags = append(diags, ensureXattrs(data, configFromMeta(m))...); diags.HasError() {
+		return
+	}
+	return append(diags, resourceFileRead(ctx, data, m)...)
 }
 
 func resourceFileCreate(ctx context.Context, data *schema.ResourceData, m interface{}) (diags diag.Diagnostics) {
-	diags = ensureCopyFile(data)
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination"); err != nil {
+		return diag.FromErr(err)
+	}
+	diags = timeoutDiagnostics(ctx, ensureCopyFile(ctx, data, configFromMeta(m)), data, schema.TimeoutCreate)
 	if diags.HasError() {
 		return diags
 	}
-	id, err := fileToID(data.Get("destination").(string))
+	if diags = append(diags, ensureOwnership(data, configFromMeta(m))...); diags.HasError() {
+		return diags
+	}
+	if diags = append(diags, ensureXattrs(data, configFromMeta(m))...); diags.HasError() {
+		return diags
+	}
+	id, err := pathToID(data, "destination", configFromMeta(m))
 	if err != nil {
 		return diag.FromErr(err)
 	}
 	data.SetId(id)
-	return
+	data.Set("managed", true)
+	return append(diags, resourceFileRead(ctx, data, m)...)
+}
+
+// modeChanged reports whether the permissions of the destination differ from
+// file_mode, or from the source if file_mode is not set. Directory trees and
+// Windows, where only the read-only bit is kept, are not compared.
+func modeChanged(data resourceGetter, cfg *providerConfig) (bool, error) {
+	if runtime.GOOS == "windows" || data.Get("recursive").(bool) || data.Get("source_glob").(bool) {
+		return false, nil
+	}
+	destStat, err := os.Stat(cfg.resolvePath(data.Get("destination").(string)))
+	if err != nil {
+		return false, nil
+	}
+	var mode os.FileMode
+	if v, ok := data.GetOk("file_mode"); ok {
+		m, err := parseFileMode(v.(string))
+		if err != nil {
+			return false, fmt.Errorf("file_mode is not a valid octal number: %w", err)
+		}
+		mode = m
+	} else if source := cfg.resolvePath(data.Get("source").(string)); source == "" {
+		mode = cfg.fileMode()
+	} else {
+		srcStat, err := os.Stat(source)
+		if err != nil {
+			return false, err
+		}
+		mode = srcStat.Mode()
+	}
+	return mode.Perm() != destStat.Mode().Perm(), nil
 }
 
-func ensureFileMode(data *schema.ResourceData) (diags diag.Diagnostics) {
-	source := data.Get("source").(string)
-	dest := data.Get("destination").(string)
+func ensureFileMode(data *schema.ResourceData, cfg *providerConfig) (diags diag.Diagnostics) {
+	source := cfg.resolvePath(data.Get("source").(string))
+	dest := cfg.resolvePath(data.Get("destination").(string))
 	destStat, err := os.Stat(dest)
 	if err != nil {
 		return diag.FromErr(fmt.Errorf("could not stat destination %q: %w", dest, err))
 	}
 	var mode os.FileMode
 	if v, ok := data.GetOk("file_mode"); ok {
-		m, err := strconv.ParseUint(v.(string), 8, 32)
+		m, err := parseFileMode(v.(string))
 		if err != nil {
 			diags = append(diags, diag.Diagnostic{
 				Severity: diag.Error,
@@ -141,7 +612,9 @@ func ensureFileMode(data *schema.ResourceData) (diags diag.Diagnostics) {
 			})
 			return
 		}
-		mode = os.FileMode(m)
+		mode = m
+	} else if source == "" {
+		mode = cfg.fileMode()
 	} else {
 		srcStat, err := os.Stat(source)
 		if err != nil {
@@ -158,20 +631,96 @@ func ensureFileMode(data *schema.ResourceData) (diags diag.Diagnostics) {
 	return nil
 }
 
-func ensureCopyFile(data *schema.ResourceData) (diags diag.Diagnostics) {
-	source := data.Get("source").(string)
-	dest := data.Get("destination").(string)
+func ensureCopyFile(ctx context.Context, data *schema.ResourceData, cfg *providerConfig) (diags diag.Diagnostics) {
+	source := cfg.resolvePath(data.Get("source").(string))
+	dest := cfg.resolvePath(data.Get("destination").(string))
 	var mode os.FileMode
-	sourceHash, err := hashFile(source)
+	if sourceMoved(data, source, dest) {
+		logEvent("DEBUG", "source was moved to the destination", "source", source, "destination", dest)
+		if _, ok := data.GetOk("file_mode"); ok {
+			return ensureFileMode(data, cfg)
+		}
+		return nil
+	}
+	if diags := verifySourceSHA256(data, cfg); diags.HasError() {
+		return diags
+	}
+	if err := ensureParentDir(data, dest); err != nil {
+		return diag.FromErr(err)
+	}
+	flag, err := openFlags(data, dest)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	destHash, err := hashFile(dest)
-	if err == nil && destHash == sourceHash {
-		return ensureFileMode(data)
+	buf := cfg.newCopyBuffer()
+	logEvent("DEBUG", "copying file", "source", source, "destination", dest, "recursive", data.Get("recursive").(bool), "source_glob", data.Get("source_glob").(bool), "link", data.Get("link").(string))
+	if data.Get("recursive").(bool) {
+		return ensureCopyTree(ctx, data, cfg, flag, buf)
+	}
+	if data.Get("source_glob").(bool) {
+		return ensureCopyGlob(ctx, data, cfg, flag, buf)
+	}
+	content, inline, err := inlineContent(data)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if inline {
+		return ensureWriteContent(ctx, data, cfg, content, flag, buf)
+	}
+	if link := data.Get("link").(string); link != linkCopy {
+		return ensureLink(data, source, dest, link, flag)
+	}
+	srcStat, err := os.Stat(source)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not stat source file %q: %w", source, err))
+	}
+	preserve := data.Get("preserve_timestamps").(bool)
+	destStat, err := os.Stat(dest)
+	exists := err == nil
+	// only a destination of the same size can have the same content, so the
+	// files are only hashed up front in that case; otherwise the source is
+	// hashed while it is copied
+	le := lineEndingsFrom(data)
+	dc := decompressionFrom(data)
+	ignoreNewline := data.Get("ignore_trailing_newline").(bool)
+	if exists && (le.convert || dc != decompressNone || ignoreNewline || destStat.Size() == srcStat.Size()) && flag&os.O_EXCL == 0 {
+		sourceHash, err := dc.hashFile(source, le)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		destHash, err := hashFile(dest)
+		same := err == nil && destHash == sourceHash
+		if err == nil && !same {
+			if same, err = newlineOnlyChangeFile(data, source, dest); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+		if same {
+			logEvent("DEBUG", "destination is up to date", "source", source, "destination", dest)
+			if diags = ensureFileMode(data, cfg); diags.HasError() {
+				return
+			}
+			if preserve {
+				if err := copyTimes(source, dest); err != nil {
+					return append(diags, diag.FromErr(err)...)
+				}
+			}
+			if err := deleteSource(data, cfg, source, dest, destHash); err != nil {
+				return append(diags, diag.FromErr(err)...)
+			}
+			return
+		}
+	}
+	if exists && !data.Get("overwrite").(bool) {
+		return diag.FromErr(noOverwriteError(dest))
+	}
+	if exists {
+		if err := backupDestination(ctx, data, dest, buf); err != nil {
+			return diag.FromErr(err)
+		}
 	}
 	if v, ok := data.GetOk("file_mode"); ok {
-		m, err := strconv.ParseUint(v.(string), 8, 32)
+		m, err := parseFileMode(v.(string))
 		if err != nil {
 			diags = append(diags, diag.Diagnostic{
 				Severity: diag.Error,
@@ -180,79 +729,878 @@ func ensureCopyFile(data *schema.ResourceData) (diags diag.Diagnostics) {
 			})
 			return
 		}
-		mode = os.FileMode(m)
+		mode = m
 	}
-	if err := copyFile(source, dest, mode); err != nil {
+	sourceHash, err := copyFileConvert(ctx, source, dest, mode, flag, buf, dc, le)
+	if err != nil {
 		return diag.FromErr(err)
 	}
+	if stat, err := os.Stat(dest); err == nil {
+		logEvent("INFO", "copied file", "source", source, "destination", dest, "bytes", stat.Size(), "file_mode", stat.Mode())
+	}
+	if preserve {
+		if err := copyTimes(source, dest); err != nil {
+			return diag.FromErr(err)
+		}
+	}
 	data.Set("content_sha256", sourceHash)
+	if err := deleteSource(data, cfg, source, dest, sourceHash); err != nil {
+		return diag.FromErr(err)
+	}
 	return
 }
 
-func copyFile(source, destination string, mode os.FileMode) (err error) {
-	var src, dest *os.File
-	src, err = os.Open(source)
-	if err != nil {
-		return fmt.Errorf("could not open source file %q: %w", source, err)
+// backupDestination copies the existing dest to dest+backup_suffix if backup
+// is set, and records it in backup_path. Only a destination the resource has
+// not written yet is backed up, on create or the first update after an
+// import: after that it holds what the provider wrote itself, which
+// restore_backup_on_destroy must not bring back instead of removing it.
+func backupDestination(ctx context.Context, data *schema.ResourceData, dest string, buf []byte) error {
+	if !data.Get("backup").(bool) || (data.Id() != "" && data.Get("managed").(bool)) {
+		return nil
 	}
-	defer src.Close()
-	if mode == 0 {
-		stat, err := src.Stat()
-		if err != nil {
-			return fmt.Errorf("could not stat source file %q: %w", source, err)
-		}
-		mode = stat.Mode()
+	backup := dest + data.Get("backup_suffix").(string)
+	if err := copyFile(ctx, dest, backup, 0, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, buf); err != nil {
+		return fmt.Errorf("could not back up %q: %w", dest, err)
+	}
+	data.Set("backup_path", backup)
+	return nil
+}
+
+func noOverwriteError(dest string) error {
+	return fmt.Errorf("destination %q already exists with different content and overwrite is false", dest)
+}
+
+// defaultContentFileMode is the mode of a destination written from inline
+// content when neither file_mode nor the provider default_file_mode is set.
+const defaultContentFileMode os.FileMode = 0664
+
+// verifySourceSHA256 compares the source against expected_sha256, if it is
+// set, before anything is written. The source is hashed the same way as
+// content_sha256.
+func verifySourceSHA256(data *schema.ResourceData, cfg *providerConfig) diag.Diagnostics {
+	expected := data.Get("expected_sha256").(string)
+	if expected == "" {
+		return nil
 	}
-	dest, err = os.OpenFile(destination, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	actual, err := sourceSHA256(data, cfg)
 	if err != nil {
-		return fmt.Errorf("could not create destination file %q: %w", destination, err)
+		return diag.FromErr(err)
 	}
-	defer func() {
-		closeErr := dest.Close()
-		if err == nil {
-			err = closeErr
-		}
-	}()
-	if _, err = io.Copy(dest, src); err != nil {
-		// clean up dest
-		_ = dest.Close()
-		_ = os.Remove(destination)
-		return fmt.Errorf("error copying %q => %q: %w", source, destination, err)
+	if !strings.EqualFold(actual, expected) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "source does not match expected_sha256",
+			Detail:   fmt.Sprintf("expected sha256 %s, got %s. The destination was not changed.", expected, actual),
+		}}
 	}
 	return nil
 }
 
-func idToFile(id string) (string, error) {
-	u, err := url.Parse(id)
+// sourceSHA256 hashes the source, or the inline content, the same way as
+// content_sha256 but before line_endings are converted.
+func sourceSHA256(data resourceGetter, cfg *providerConfig) (string, error) {
+	content, inline, err := inlineContent(data)
 	if err != nil {
-		return "", fmt.Errorf("invalid id format %q: %w", id, err)
+		return "", err
 	}
-	if u.Scheme != "file" {
-		return "", fmt.Errorf("invalid id scheme %q, should be 'file'", u.Scheme)
+	if inline {
+		return hashBytes(content), nil
 	}
-	return filepath.Abs(filepath.FromSlash(u.Path))
+	source := cfg.resolvePath(data.Get("source").(string))
+	var hash string
+	if data.Get("source_glob").(bool) {
+		var sources []string
+		if sources, _, err = globFiles(source, cfg.resolvePath(data.Get("destination").(string))); err == nil {
+			hash, err = hashFileSet(sources)
+		}
+	} else {
+		hash, err = hashPath(source, data.Get("recursive").(bool))
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not hash source %q: %w", source, err)
+	}
+	return hash, nil
 }
 
-func fileToID(file string) (string, error) {
-	abs, err := filepath.Abs(file)
-	if err != nil {
-		return "", err
+// inlineContent returns the bytes of content or the decoded content_base64,
+// and false if neither is set.
+func inlineContent(data resourceGetter) ([]byte, bool, error) {
+	if v, ok := data.GetOk("content"); ok {
+		return []byte(v.(string)), true, nil
+	}
+	if v, ok := data.GetOk("content_base64"); ok {
+		b, err := base64.StdEncoding.DecodeString(v.(string))
+		if err != nil {
+			return nil, true, fmt.Errorf("content_base64 is not valid base64: %w", err)
+		}
+		return b, true, nil
 	}
-	return (&url.URL{
-		Scheme: "file",
-		Path:   filepath.ToSlash(abs),
-	}).String(), nil
+	return nil, false, nil
 }
 
-func hashFile(filename string) (string, error) {
-	h := sha256.New()
-	fd, err := os.Open(filename)
+func ensureWriteContent(ctx context.Context, data *schema.ResourceData, cfg *providerConfig, content []byte, flag int, buf []byte) (diags diag.Diagnostics) {
+	dest := cfg.resolvePath(data.Get("destination").(string))
+	content, err := lineEndingsFrom(data).convertBytes(content)
 	if err != nil {
-		return "", err
+		return diag.FromErr(err)
 	}
-	defer fd.Close()
-	if _, err := io.Copy(h, fd); err != nil {
-		return "", fmt.Errorf("could not hash file %q: %w", filename, err)
+	contentHash := hashBytes(content)
+	destHash, err := hashFile(dest)
+	if err == nil && destHash == contentHash && flag&os.O_EXCL == 0 {
+		return ensureFileMode(data, cfg)
 	}
-	return hex.EncodeToString(h.Sum(nil)), nil
+	if err == nil && destHash != contentHash && flag&os.O_EXCL == 0 {
+		same, err := newlineOnlyChange(data, bytes.NewReader(content), dest)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if same {
+			return ensureFileMode(data, cfg)
+		}
+	}
+	if err == nil && destHash != contentHash && !data.Get("overwrite").(bool) {
+		return diag.FromErr(noOverwriteError(dest))
+	}
+	if err == nil {
+		if err := backupDestination(ctx, data, dest, buf); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	mode := cfg.fileMode()
+	if v, ok := data.GetOk("file_mode"); ok {
+		m, err := parseFileMode(v.(string))
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "file_mode is not a valid octal number",
+				Detail:   err.Error(),
+			})
+			return
+		}
+		mode = m
+	}
+	if err := writeResponseBody(bytes.NewReader(content), dest, mode, flag, int64(len(content)), buf); err != nil {
+		return diag.FromErr(err)
+	}
+	data.Set("content_sha256", contentHash)
+	return
+}
+
+func ensureCopyTree(ctx context.Context, data *schema.ResourceData, cfg *providerConfig, flag int, buf []byte) (diags diag.Diagnostics) {
+	source := cfg.resolvePath(data.Get("source").(string))
+	dest := cfg.resolvePath(data.Get("destination").(string))
+	sourceHash, err := hashTree(source)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	destHash, err := hashTree(dest)
+	if err == nil && destHash == sourceHash && flag&os.O_EXCL == 0 {
+		return nil
+	}
+	if err == nil && destHash != sourceHash && !data.Get("overwrite").(bool) {
+		return diag.FromErr(noOverwriteError(dest))
+	}
+	var mode os.FileMode
+	if v, ok := data.GetOk("file_mode"); ok {
+		m, err := parseFileMode(v.(string))
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "file_mode is not a valid octal number",
+				Detail:   err.Error(),
+			})
+			return
+		}
+		mode = m
+	}
+	if err := copyTree(ctx, source, dest, mode, flag, data.Get("preserve_timestamps").(bool), buf); err != nil {
+		return diag.FromErr(err)
+	}
+	data.Set("content_sha256", sourceHash)
+	return
+}
+
+// copyTree mirrors the directory source into destination. Directories keep
+// the mode of their source; files use mode, or their source mode if it is 0.
+// Entries in destination that are not in source are removed. If preserve is
+// set, each file keeps the modification time of its source.
+func copyTree(ctx context.Context, source, destination string, mode os.FileMode, flag int, preserve bool, buf []byte) error {
+	entries, err := treeEntries(source)
+	if err != nil {
+		return err
+	}
+	rootStat, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("could not stat source %q: %w", source, err)
+	}
+	if err := os.MkdirAll(destination, rootStat.Mode().Perm()); err != nil {
+		return fmt.Errorf("could not create directory %q: %w", destination, err)
+	}
+	keep := make(map[string]bool, len(entries))
+	for _, rel := range entries {
+		keep[rel] = true
+		src := filepath.Join(source, rel)
+		dst := filepath.Join(destination, rel)
+		stat, err := os.Stat(src)
+		if err != nil {
+			return fmt.Errorf("could not stat source %q: %w", src, err)
+		}
+		if stat.IsDir() {
+			if err := os.MkdirAll(dst, stat.Mode().Perm()); err != nil {
+				return fmt.Errorf("could not create directory %q: %w", dst, err)
+			}
+			if err := os.Chmod(dst, stat.Mode().Perm()); err != nil {
+				return fmt.Errorf("failed to chmod %s %q: %w", stat.Mode().Perm(), dst, err)
+			}
+			continue
+		}
+		if err := copyFile(ctx, src, dst, mode, flag, buf); err != nil {
+			return err
+		}
+		if preserve {
+			if err := os.Chtimes(dst, stat.ModTime(), stat.ModTime()); err != nil {
+				return fmt.Errorf("could not set times of %q: %w", dst, err)
+			}
+		}
+	}
+	existing, err := treeEntries(destination)
+	if err != nil {
+		return err
+	}
+	// entries are sorted, so a removed directory is seen before its children
+	for _, rel := range existing {
+		if keep[rel] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(destination, rel)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove %q: %w", filepath.Join(destination, rel), err)
+		}
+	}
+	return nil
+}
+
+// treeEntries returns the sorted relative paths of every file and directory
+// below root, excluding root itself.
+func treeEntries(root string) ([]string, error) {
+	var entries []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			if !d.IsDir() {
+				return fmt.Errorf("%q is not a directory", root)
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(entries)
+	return entries, nil
+}
+
+// hashTree hashes the sorted relative paths below root together with the
+// hash of each file, so empty directories and renames change the result.
+func hashTree(root string) (string, error) {
+	return hashTreeWith(root, sha256.New)
+}
+
+// hashTreeWith is hashTree using a hash from newHash for the files and the
+// result.
+func hashTreeWith(root string, newHash func() hash.Hash) (string, error) {
+	entries, err := treeEntries(root)
+	if err != nil {
+		return "", err
+	}
+	h := newHash()
+	for _, rel := range entries {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		stat, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		if stat.IsDir() {
+			fmt.Fprintf(h, "%s/\n", rel)
+			continue
+		}
+		fileHash, err := hashFileWith(path, newHash)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %s\n", rel, fileHash)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// globFiles returns the regular files matching pattern and their paths in
+// destination, sorted by name. Matches must have distinct base names since
+// they are all copied into one directory.
+func globFiles(pattern, destination string) (sources, dests []string, err error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid source pattern %q: %w", pattern, err)
+	}
+	byName := make(map[string]string)
+	var names []string
+	for _, match := range matches {
+		stat, err := os.Stat(match)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not stat source %q: %w", match, err)
+		}
+		if stat.IsDir() {
+			continue
+		}
+		name := filepath.Base(match)
+		if other, ok := byName[name]; ok {
+			return nil, nil, fmt.Errorf("source pattern %q matches %q and %q which have the same name", pattern, other, match)
+		}
+		byName[name] = match
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("source pattern %q does not match any files", pattern)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sources = append(sources, byName[name])
+		dests = append(dests, filepath.Join(destination, name))
+	}
+	return sources, dests, nil
+}
+
+// hashFileSet hashes the base name and hash of each file in order, so the
+// result is the same for a set of sources and their copies.
+func hashFileSet(files []string) (string, error) {
+	return hashFileSetWith(files, sha256.New)
+}
+
+// hashFileSetWith is hashFileSet using a hash from newHash.
+func hashFileSetWith(files []string, newHash func() hash.Hash) (string, error) {
+	h := newHash()
+	for _, f := range files {
+		fileHash, err := hashFileWith(f, newHash)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %s\n", filepath.Base(f), fileHash)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// customizeSourceSHA256 plans source_sha256 from the current source, so a
+// change to the source shows up even if the destination already matches.
+func customizeSourceSHA256(diff *schema.ResourceDiff, cfg *providerConfig) error {
+	if !diff.NewValueKnown("source") || !diff.NewValueKnown("content") || !diff.NewValueKnown("content_base64") {
+		return diff.SetNewComputed("source_sha256")
+	}
+	hash, err := sourceSHA256(diff, cfg)
+	if err != nil {
+		// the source may be created by another resource during apply
+		return diff.SetNewComputed("source_sha256")
+	}
+	if hash != diff.Get("source_sha256").(string) {
+		return diff.SetNew("source_sha256", hash)
+	}
+	return nil
+}
+
+func customizeDiffGlob(diff *schema.ResourceDiff, cfg *providerConfig) error {
+	dest := cfg.resolvePath(diff.Get("destination").(string))
+	sources, dests, err := globFiles(cfg.resolvePath(diff.Get("source").(string)), dest)
+	if err != nil {
+		return err
+	}
+	if stat, err := os.Stat(dest); err == nil && !stat.IsDir() {
+		return fmt.Errorf("destination %q is not a directory", dest)
+	}
+	srcHash, err := hashFileSet(sources)
+	if err != nil {
+		return err
+	}
+	destHash, err := hashFileSet(dests)
+	if err != nil || destHash != srcHash {
+		return setContentComputed(diff)
+	}
+	return nil
+}
+
+func ensureCopyGlob(ctx context.Context, data *schema.ResourceData, cfg *providerConfig, flag int, buf []byte) (diags diag.Diagnostics) {
+	dest := cfg.resolvePath(data.Get("destination").(string))
+	sources, dests, err := globFiles(cfg.resolvePath(data.Get("source").(string)), dest)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if stat, err := os.Stat(dest); err == nil && !stat.IsDir() {
+		return diag.FromErr(fmt.Errorf("destination %q is not a directory", dest))
+	}
+	dirMode, err := strconv.ParseUint(data.Get("dir_mode").(string), 8, 32)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("dir_mode is not a valid octal number: %w", err))
+	}
+	if err := os.MkdirAll(dest, os.FileMode(dirMode)); err != nil {
+		return diag.FromErr(fmt.Errorf("could not create directory %q: %w", dest, err))
+	}
+	var mode os.FileMode
+	if v, ok := data.GetOk("file_mode"); ok {
+		m, err := parseFileMode(v.(string))
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "file_mode is not a valid octal number",
+				Detail:   err.Error(),
+			})
+			return
+		}
+		mode = m
+	}
+	overwrite := data.Get("overwrite").(bool)
+	preserve := data.Get("preserve_timestamps").(bool)
+	for i, src := range sources {
+		sourceHash, err := hashFile(src)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		destHash, err := hashFile(dests[i])
+		if err == nil && destHash == sourceHash && flag&os.O_EXCL == 0 {
+			continue
+		}
+		if err == nil && !overwrite {
+			return diag.FromErr(noOverwriteError(dests[i]))
+		}
+		if err := copyFile(ctx, src, dests[i], mode, flag, buf); err != nil {
+			return diag.FromErr(err)
+		}
+		if preserve {
+			if err := copyTimes(src, dests[i]); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+	}
+	sourceHash, err := hashFileSet(sources)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.Set("files", dests)
+	data.Set("content_sha256", sourceHash)
+	return
+}
+
+func stringList(v interface{}) []string {
+	var list []string
+	for _, s := range v.([]interface{}) {
+		list = append(list, s.(string))
+	}
+	return list
+}
+
+// hashPath hashes a single file, or a directory tree if recursive is set.
+func hashPath(path string, recursive bool) (string, error) {
+	return hashPathWith(path, recursive, sha256.New)
+}
+
+// hashPathWith is hashPath using a hash from newHash.
+func hashPathWith(path string, recursive bool, newHash func() hash.Hash) (string, error) {
+	if recursive {
+		return hashTreeWith(path, newHash)
+	}
+	return hashFileWith(path, newHash)
+}
+
+// ensureParentDir creates the parent directories of dest with dir_mode if
+// create_parents is set.
+func ensureParentDir(data resourceGetter, dest string) error {
+	if !data.Get("create_parents").(bool) {
+		return nil
+	}
+	m, err := strconv.ParseUint(data.Get("dir_mode").(string), 8, 32)
+	if err != nil {
+		return fmt.Errorf("dir_mode is not a valid octal number: %w", err)
+	}
+	dir := filepath.Dir(dest)
+	if err := os.MkdirAll(dir, os.FileMode(m)); err != nil {
+		return fmt.Errorf("could not create directory %q: %w", dir, err)
+	}
+	return nil
+}
+
+// openFlags returns the flags for opening dest for writing. O_EXCL is only
+// added while the resource is being created.
+func openFlags(data resourceGetter, dest string) (int, error) {
+	flag := os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	if data.Get("no_follow").(bool) {
+		if oNoFollow == 0 {
+			return 0, fmt.Errorf("no_follow is not supported on this platform")
+		}
+		if fi, err := os.Lstat(dest); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+			return 0, fmt.Errorf("destination %q is a symlink and no_follow is set", dest)
+		}
+		flag |= oNoFollow
+	}
+	if data.Get("exclusive_create").(bool) && data.Id() == "" {
+		flag |= os.O_EXCL
+	}
+	return flag, nil
+}
+
+// copyTimes sets the modification time of destination to that of source.
+// The access time is set to the same value since it is not portably
+// available from os.Stat.
+func copyTimes(source, destination string) error {
+	stat, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("could not stat source file %q: %w", source, err)
+	}
+	if err := os.Chtimes(destination, stat.ModTime(), stat.ModTime()); err != nil {
+		return fmt.Errorf("could not set times of %q: %w", destination, err)
+	}
+	return nil
+}
+
+func copyFile(ctx context.Context, source, destination string, mode os.FileMode, flag int, buf []byte) error {
+	_, err := copyFileSHA256(ctx, source, destination, mode, flag, buf)
+	return err
+}
+
+// copyFileSHA256 copies source to destination through buf and returns the
+// SHA256 hash of the copied content, computed while it is copied.
+func copyFileSHA256(ctx context.Context, source, destination string, mode os.FileMode, flag int, buf []byte) (string, error) {
+	return copyFileConvert(ctx, source, destination, mode, flag, buf, decompressNone, lineEndings{})
+}
+
+// copyFileConvert is copyFileSHA256 with the source decompressed by dc and
+// the line endings of text converted by le. The hash is of the converted
+// content.
+func copyFileConvert(ctx context.Context, source, destination string, mode os.FileMode, flag int, buf []byte, dc decompression, le lineEndings) (string, error) {
+	src, err := os.Open(source)
+	if err != nil {
+		return "", fmt.Errorf("could not open source file %q: %w", source, err)
+	}
+	defer src.Close()
+	if mode == 0 {
+		stat, err := src.Stat()
+		if err != nil {
+			return "", fmt.Errorf("could not stat source file %q: %w", source, err)
+		}
+		mode = stat.Mode()
+	}
+	r, err := dc.wrap(contextReader{ctx: ctx, r: src})
+	if err != nil {
+		return "", fmt.Errorf("could not read source file %q: %w", source, err)
+	}
+	if r, err = le.wrap(r); err != nil {
+		return "", fmt.Errorf("could not read source file %q: %w", source, err)
+	}
+	h := sha256.New()
+	err = writeFileAtomic(destination, mode, flag, func(w io.Writer) error {
+		if _, err := copyBuffer(w, io.TeeReader(r, h), buf); err != nil {
+			return fmt.Errorf("error copying %q => %q: %w", source, destination, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// copyBuffer copies src to dst through buf, or a default sized buffer if buf
+// is nil. dst is wrapped so io.CopyBuffer cannot bypass buf through
+// io.ReaderFrom, which for *os.File falls back to a small internal buffer.
+func copyBuffer(dst io.Writer, src io.Reader, buf []byte) (int64, error) {
+	if buf == nil {
+		return io.Copy(dst, src)
+	}
+	return io.CopyBuffer(struct{ io.Writer }{dst}, src, buf)
+}
+
+// noSpaceError is a write to path that failed because its filesystem is
+// full. The partially written file has already been removed.
+type noSpaceError struct {
+	path string
+	err  error
+}
+
+// newNoSpaceError wraps err, which may be wrapped in the context of a read
+// from the source rather than the write that failed.
+func newNoSpaceError(path string, err error) *noSpaceError {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr
+	}
+	return &noSpaceError{path: path, err: err}
+}
+
+func (e *noSpaceError) Error() string {
+	return fmt.Sprintf("destination filesystem is out of space: could not write %q: %s", e.path, e.err)
+}
+
+func (e *noSpaceError) Unwrap() error {
+	return e.err
+}
+
+// writeFileAtomic calls write with a temporary file in the directory of
+// destination and renames it over destination once it is synced, so readers
+// never see a partially written file. The temporary file is removed if
+// anything fails.
+//
+// flag is the set returned by openFlags: O_EXCL fails if destination exists,
+// and unless O_NOFOLLOW is set a symlink at destination is replaced through
+// to its target, as writing to it in place would. An exclusive write is put
+// in place with a hard link rather than a rename, since a link fails if the
+// destination was created in the meantime. A rename replaces the file, so
+// the temporary file takes the owner of an existing destination first; hard
+// links to the old file keep its old content.
+func writeFileAtomic(destination string, mode os.FileMode, flag int, write func(w io.Writer) error) (err error) {
+	target := destination
+	if flag&oNoFollow == 0 {
+		if resolved, err := filepath.EvalSymlinks(destination); err == nil {
+			target = resolved
+		}
+	}
+	if flag&os.O_EXCL != 0 {
+		// fail early rather than after writing the whole file; the link
+		// below is what makes it exclusive
+		if _, err := os.Lstat(target); err == nil {
+			return fmt.Errorf("could not create destination file %q: %w", destination, fs.ErrExist)
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not create destination file %q: %w", destination, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+			if isNoSpace(err) {
+				err = newNoSpaceError(destination, err)
+			}
+		}
+	}()
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if flag&os.O_EXCL == 0 {
+		keepOwner(tmp, target)
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return fmt.Errorf("failed to chmod %s %q: %w", mode, tmp.Name(), err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("could not sync %q: %w", tmp.Name(), err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("could not close %q: %w", tmp.Name(), err)
+	}
+	if flag&os.O_EXCL != 0 {
+		if err = os.Link(tmp.Name(), target); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return fmt.Errorf("could not create destination file %q: %w", destination, fs.ErrExist)
+			}
+			return fmt.Errorf("could not link %q to %q: %w", tmp.Name(), destination, err)
+		}
+		// the destination is in place, so failing to remove the temporary
+		// name is not an error
+		_ = os.Remove(tmp.Name())
+		return nil
+	}
+	if err = os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("could not rename %q to %q: %w", tmp.Name(), destination, err)
+	}
+	return nil
+}
+
+// keepOwner gives tmp the uid and gid of an existing target, which a rename
+// would otherwise replace with those of the provider. It is best effort: a
+// user who may not chown gets the file as their own, as before.
+func keepOwner(tmp *os.File, target string) {
+	if !chownSupported {
+		return
+	}
+	fi, err := os.Stat(target)
+	if err != nil {
+		return
+	}
+	uid, gid, ok := fileOwner(fi)
+	if !ok {
+		return
+	}
+	if err := tmp.Chown(uid, gid); err != nil {
+		logEvent("DEBUG", "could not keep the owner of the destination", "destination", target, "uid", uid, "gid", gid, "error", err)
+	}
+}
+
+// idToFile returns the absolute path of a file:// resource ID. fileToID
+// percent-encodes '#' and '?', so a fragment or query can only come from an
+// ID written by hand, such as an import ID; they are taken to be part of
+// the path.
+func idToFile(id string) (string, error) {
+	u, err := url.Parse(id)
+	if err != nil {
+		return "", fmt.Errorf("invalid id format %q: %w", id, err)
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("invalid id scheme %q, should be 'file'", u.Scheme)
+	}
+	path, err := fileURLToSlash(u, runtime.GOOS == "windows")
+	if err != nil {
+		return "", fmt.Errorf("invalid id %q: %w", id, err)
+	}
+	if u.ForceQuery || u.RawQuery != "" {
+		query, err := url.PathUnescape(u.RawQuery)
+		if err != nil {
+			return "", fmt.Errorf("invalid id format %q: %w", id, err)
+		}
+		path += "?" + query
+	}
+	if u.Fragment != "" || strings.HasSuffix(id, "#") {
+		path += "#" + u.Fragment
+	}
+	return filepath.Abs(filepath.FromSlash(path))
+}
+
+// fileURLToSlash returns the slash separated path of a file:// url. On
+// windows, the leading slash before a drive letter is dropped and a host
+// names the server of a UNC path. IDs from older versions put the drive
+// letter in the host, as in file://C:/dir/file, and are also accepted.
+func fileURLToSlash(u *url.URL, windows bool) (string, error) {
+	if u.Opaque != "" || u.Path == "" {
+		return "", fmt.Errorf("file url %q must have an absolute path", u)
+	}
+	path := u.Path
+	switch {
+	case u.Host == "" || u.Host == "localhost":
+		if windows && hasDriveLetter(strings.TrimPrefix(path, "/")) {
+			path = path[1:]
+		}
+	case windows && hasDriveLetter(u.Host):
+		path = u.Host + path
+	case windows:
+		path = "//" + u.Host + path
+	default:
+		return "", fmt.Errorf("file url %q must not have a host other than localhost", u)
+	}
+	return path, nil
+}
+
+// fileURLFromSlash is the inverse of fileURLToSlash for an absolute, slash
+// separated path.
+func fileURLFromSlash(path string) *url.URL {
+	u := &url.URL{Scheme: "file", Path: path}
+	if strings.HasPrefix(path, "//") {
+		// UNC path: //server/share/file
+		rest := path[2:]
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			u.Host, u.Path = rest[:i], rest[i:]
+		} else {
+			u.Host, u.Path = rest, "/"
+		}
+	} else if !strings.HasPrefix(path, "/") {
+		u.Path = "/" + path
+	}
+	return u
+}
+
+// hasDriveLetter reports whether path starts with a windows drive letter
+// such as "C:".
+func hasDriveLetter(path string) bool {
+	if len(path) < 2 || path[1] != ':' {
+		return false
+	}
+	c := path[0]
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// fileToID returns the file:// resource ID of file. Characters that are
+// not valid in a url path are percent-encoded.
+func fileToID(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	return fileURLFromSlash(filepath.ToSlash(abs)).String(), nil
+}
+
+// pathToID computes the resource ID for the path stored under key,
+// normalizing its case first if normalize_path_case is set.
+func pathToID(data *schema.ResourceData, key string, cfg *providerConfig) (string, error) {
+	file, err := filepath.Abs(cfg.resolvePath(data.Get(key).(string)))
+	if err != nil {
+		return "", err
+	}
+	if data.Get("normalize_path_case").(bool) {
+		file = canonicalPathCase(file)
+	}
+	return fileToID(file)
+}
+
+func suppressEquivalentPathCase(k, old, new string, d *schema.ResourceData) bool {
+	if old == "" || !d.Get("normalize_path_case").(bool) {
+		return false
+	}
+	oldAbs, err := filepath.Abs(old)
+	if err != nil {
+		return false
+	}
+	newAbs, err := filepath.Abs(new)
+	if err != nil {
+		return false
+	}
+	return canonicalPathCase(oldAbs) == canonicalPathCase(newAbs)
+}
+
+// canonicalPathCase rewrites each existing component of the absolute path
+// to the name stored on disk. Components that do not exist yet are kept as
+// given, and a drive letter is upper-cased.
+func canonicalPathCase(path string) string {
+	vol := filepath.VolumeName(path)
+	if len(vol) == 2 && vol[1] == ':' {
+		vol = strings.ToUpper(vol)
+	}
+	rest := strings.TrimPrefix(path[len(vol):], string(filepath.Separator))
+	if rest == "" {
+		return vol + string(filepath.Separator)
+	}
+	parts := strings.Split(rest, string(filepath.Separator))
+	dir := vol + string(filepath.Separator)
+	for i, part := range parts {
+		name, ok := lookupNameFold(dir, part)
+		if !ok {
+			return filepath.Join(append([]string{dir}, parts[i:]...)...)
+		}
+		dir = filepath.Join(dir, name)
+	}
+	return dir
+}
+
+// lookupNameFold finds the entry in dir matching name, preferring an exact
+// match over a case-insensitive one.
+func lookupNameFold(dir, name string) (string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	var folded string
+	for _, e := range entries {
+		if e.Name() == name {
+			return name, true
+		}
+		if folded == "" && strings.EqualFold(e.Name(), name) {
+			folded = e.Name()
+		}
+	}
+	return folded, folded != ""
+}
+
+func hashFile(filename string) (string, error) {
+	return hashFileWith(filename, sha256.New)
 }
@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+func resourceFilePermissions() *schema.Resource {
+	return &schema.Resource{
+		ReadContext:   resourceFilePermissionsRead,
+		CreateContext: resourceFilePermissionsCreate,
+		UpdateContext: resourceFilePermissionsUpdate,
+		DeleteContext: resourceFilePermissionsDelete,
+		CustomizeDiff: func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+			return configFromMeta(m).checkAllowedRoot(diff, "path")
+		},
+		Schema: resourceFilePermissionsSchema(),
+	}
+}
+
+func resourceFilePermissionsSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"path": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "Path of an existing file or directory. Its content is never changed or removed by this resource.",
+		},
+		"file_mode": {
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateDiagFunc: validateFileMode,
+			DiffSuppressFunc: suppressEquivalentFileMode,
+			AtLeastOneOf:     []string{"file_mode", "owner", "group"},
+			Description:      "File mode of path (Octal String). Left as it is if not set. Ignored on Windows.",
+		},
+		"owner": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Owner of path, as a user name or numeric uid. Left as it is if not set. Not supported on Windows.",
+		},
+		"group": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Group of path, as a group name or numeric gid. Left as it is if not set. Not supported on Windows.",
+		},
+		"restore_on_destroy": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "On destroy, put back the mode and ownership path had before this resource was created. By default they are left as they are.",
+		},
+		"previous_file_mode": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "File mode of path before this resource was created",
+		},
+		"previous_owner": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Numeric uid of path before this resource was created. Empty on Windows.",
+		},
+		"previous_group": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Numeric gid of path before this resource was created. Empty on Windows.",
+		},
+	}
+}
+
+// formatFileMode returns the octal string of the permission bits of mode,
+// including the setuid, setgid and sticky bits that parseFileMode accepts.
+func formatFileMode(mode os.FileMode) string {
+	m := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= 01000
+	}
+	return fmt.Sprintf("%04o", m)
+}
+
+// permissionBits is the part of a FileMode that file_mode manages.
+const permissionBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// ensurePermissions applies file_mode, owner and group to path. Attributes
+// that are not set are left alone.
+func ensurePermissions(data *schema.ResourceData, path string) diag.Diagnostics {
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return diag.Errorf("%q does not exist. synclocal_file_permissions only manages the permissions of an existing file.", path)
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not stat %q: %w", path, err))
+	}
+	if v, ok := data.GetOk("file_mode"); ok && runtime.GOOS != "windows" {
+		mode, err := parseFileMode(v.(string))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("file_mode is not a valid octal number: %w", err))
+		}
+		if stat.Mode()&permissionBits != mode {
+			if err := os.Chmod(path, mode); err != nil {
+				return diag.FromErr(fmt.Errorf("failed to chmod %s %q: %w", mode, path, err))
+			}
+			logEvent("INFO", "changed file mode", "path", path, "file_mode", mode)
+		}
+	}
+	uid, gid, err := resolveOwnership(data)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	if !chownSupported {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "owner and group are not supported on this platform",
+			Detail:   fmt.Sprintf("could not change ownership of %q. Remove owner and group from this resource.", path),
+		}}
+	}
+	if fileUID, fileGID, ok := fileOwner(stat); ok && (uid == -1 || uid == fileUID) && (gid == -1 || gid == fileGID) {
+		return nil
+	}
+	return chownDiagnostics(path, os.Chown(path, uid, gid))
+}
+
+func resourceFilePermissionsCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "path"); err != nil {
+		return diag.FromErr(err)
+	}
+	path := configFromMeta(m).resolvePath(data.Get("path").(string))
+	stat, err := os.Stat(path)
+	if err == nil {
+		data.Set("previous_file_mode", formatFileMode(stat.Mode()))
+		if uid, gid, ok := fileOwner(stat); ok {
+			data.Set("previous_owner", strconv.Itoa(uid))
+			data.Set("previous_group", strconv.Itoa(gid))
+		}
+	}
+	if diags := ensurePermissions(data, path); diags.HasError() {
+		return diags
+	}
+	id, err := fileToID(path)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	return resourceFilePermissionsRead(ctx, data, m)
+}
+
+func resourceFilePermissionsUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "path"); err != nil {
+		return diag.FromErr(err)
+	}
+	if diags := ensurePermissions(data, configFromMeta(m).resolvePath(data.Get("path").(string))); diags.HasError() {
+		return diags
+	}
+	return resourceFilePermissionsRead(ctx, data, m)
+}
+
+func resourceFilePermissionsRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	path, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		data.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not stat %q: %w", path, err))
+	}
+	// keep the configured spelling of each attribute unless it changed, so
+	// only drift shows up in the plan
+	if v, ok := data.GetOk("file_mode"); ok && runtime.GOOS != "windows" {
+		if mode, err := parseFileMode(v.(string)); err != nil || mode != stat.Mode()&permissionBits {
+			data.Set("file_mode", formatFileMode(stat.Mode()))
+		}
+	}
+	fileUID, fileGID, ok := fileOwner(stat)
+	if !ok {
+		return nil
+	}
+	uid, gid, err := resolveOwnership(data)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if uid != -1 && uid != fileUID {
+		data.Set("owner", strconv.Itoa(fileUID))
+	}
+	if gid != -1 && gid != fileGID {
+		data.Set("group", strconv.Itoa(fileGID))
+	}
+	return nil
+}
+
+// resourceFilePermissionsDelete never removes path. With restore_on_destroy
+// the recorded mode and ownership are put back.
+func resourceFilePermissionsDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if !data.Get("restore_on_destroy").(bool) {
+		return nil
+	}
+	path, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	// only the mode and ownership this resource manages are put back
+	if v, ok := data.GetOk("previous_file_mode"); ok && data.Get("file_mode").(string) != "" && runtime.GOOS != "windows" {
+		mode, err := parseFileMode(v.(string))
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("previous_file_mode is not a valid octal number: %w", err))
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to chmod %s %q: %w", mode, path, err))
+		}
+	}
+	uid, gid := -1, -1
+	if v, ok := data.GetOk("previous_owner"); ok && data.Get("owner").(string) != "" {
+		if uid, err = strconv.Atoi(v.(string)); err != nil {
+			return diag.FromErr(fmt.Errorf("previous_owner %q is not a numeric uid", v))
+		}
+	}
+	if v, ok := data.GetOk("previous_group"); ok && data.Get("group").(string) != "" {
+		if gid, err = strconv.Atoi(v.(string)); err != nil {
+			return diag.FromErr(fmt.Errorf("previous_group %q is not a numeric gid", v))
+		}
+	}
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	return chownDiagnostics(path, os.Chown(path, uid, gid))
+}
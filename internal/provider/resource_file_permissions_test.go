@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFormatFileMode(t *testing.T) {
+	for _, s := range []string{"0644", "0600", "0755", "4755", "2775", "1777"} {
+		mode, err := parseFileMode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := formatFileMode(mode); got != s {
+			t.Errorf("formatFileMode(%s) = %q", s, got)
+		}
+	}
+}
+
+func TestResourceFilePermissions_mode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceFilePermissionsSchema(), map[string]interface{}{
+		"path":      path,
+		"file_mode": "600",
+	})
+	if diags := resourceFilePermissionsCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	assertFileMode(t, path, 0600)
+	if got := data.Get("previous_file_mode").(string); got != "0644" {
+		t.Fatalf("unexpected previous_file_mode %q", got)
+	}
+	if got := data.Get("file_mode").(string); got != "600" {
+		t.Fatalf("expected the configured file_mode to be kept, got %q", got)
+	}
+	// a mode changed outside of terraform is drift
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceFilePermissionsRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if data.Id() == "" {
+		t.Fatal("the file was removed from state")
+	}
+	if got := data.Get("file_mode").(string); got != "0640" {
+		t.Fatalf("expected file_mode to show the drift, got %q", got)
+	}
+	data.Set("file_mode", "0600")
+	if diags := resourceFilePermissionsUpdate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	assertFileMode(t, path, 0600)
+	if b, _ := ioutil.ReadFile(path); string(b) != "hello" {
+		t.Fatalf("unexpected content %q", b)
+	}
+}
+
+func TestResourceFilePermissions_owner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+	path := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceFilePermissionsSchema(), map[string]interface{}{
+		"path":               path,
+		"owner":              "65534",
+		"group":              "65534",
+		"restore_on_destroy": true,
+	})
+	if diags := resourceFilePermissionsCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	assertFileOwner(t, path, 65534, 65534)
+	if data.Get("previous_owner").(string) != "0" || data.Get("previous_group").(string) != "0" {
+		t.Fatalf("unexpected previous ownership %s:%s", data.Get("previous_owner"), data.Get("previous_group"))
+	}
+	// an owner changed outside of terraform is drift
+	if err := os.Chown(path, 0, -1); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceFilePermissionsRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := data.Get("owner").(string); got != "0" {
+		t.Fatalf("expected owner to show the drift, got %q", got)
+	}
+	if got := data.Get("group").(string); got != "65534" {
+		t.Fatalf("expected group to be unchanged, got %q", got)
+	}
+	data.Set("owner", "65534")
+	if diags := resourceFilePermissionsUpdate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	assertFileOwner(t, path, 65534, 65534)
+	if diags := resourceFilePermissionsDelete(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	assertFileOwner(t, path, 0, 0)
+}
+
+func TestResourceFilePermissionsDelete(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on Windows")
+	}
+	for _, restore := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "file")
+		if err := ioutil.WriteFile(path, []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, 0644); err != nil {
+			t.Fatal(err)
+		}
+		data := schema.TestResourceDataRaw(t, resourceFilePermissionsSchema(), map[string]interface{}{
+			"path":               path,
+			"file_mode":          "0400",
+			"restore_on_destroy": restore,
+		})
+		if diags := resourceFilePermissionsCreate(context.Background(), data, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if diags := resourceFilePermissionsDelete(context.Background(), data, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if b, err := ioutil.ReadFile(path); err != nil || string(b) != "hello" {
+			t.Fatalf("restore_on_destroy %v: the file was modified: %q %v", restore, b, err)
+		}
+		want := os.FileMode(0400)
+		if restore {
+			want = 0644
+		}
+		assertFileMode(t, path, want)
+	}
+}
+
+func TestResourceFilePermissionsCreate_missing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+	data := schema.TestResourceDataRaw(t, resourceFilePermissionsSchema(), map[string]interface{}{
+		"path":      path,
+		"file_mode": "0644",
+	})
+	if diags := resourceFilePermissionsCreate(context.Background(), data, nil); !diags.HasError() {
+		t.Fatal("expected a missing path to fail")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("the path was created: %v", err)
+	}
+}
+
+func assertFileMode(t *testing.T, path string, want os.FileMode) {
+	t.Helper()
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stat.Mode().Perm(); got != want {
+		t.Fatalf("unexpected mode %s, want %s", got, want)
+	}
+}
+
+func assertFileOwner(t *testing.T, path string, uid, gid int) {
+	t.Helper()
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileUID, fileGID, _ := fileOwner(stat); fileUID != uid || fileGID != gid {
+		t.Fatalf("unexpected owner %d:%d, want %d:%d", fileUID, fileGID, uid, gid)
+	}
+}
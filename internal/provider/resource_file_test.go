@@ -1,10 +1,26 @@
 package provider
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAccResourceFile(t *testing.T) {
@@ -25,6 +41,7 @@ resource "synclocal_file" "copy" {
 `,
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttrSet("synclocal_file.copy", "content_sha256"),
+					resource.TestCheckResourceAttrPair("synclocal_file.copy", "source_sha256", "synclocal_file.copy", "content_sha256"),
 				),
 			},
 			{
@@ -39,6 +56,7 @@ resource "synclocal_file" "copy" {
 `,
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttrSet("synclocal_file.copy", "content_sha256"),
+					resource.TestCheckResourceAttrPair("synclocal_file.copy", "source_sha256", "synclocal_file.copy", "content_sha256"),
 				),
 			},
 		},
@@ -56,3 +74,1239 @@ func testAccDestroyFile(s *terraform.State) error {
 
 	return nil
 }
+
+func TestCanonicalPathCase(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Mixed-Case", "exact", "EXACT"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatalf("could not create %q: %v", name, err)
+		}
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{filepath.Join(dir, "Mixed-Case", "New-File"), filepath.Join(dir, "Mixed-Case", "New-File")},
+		{filepath.Join(dir, "mixed-case", "New-File"), filepath.Join(dir, "Mixed-Case", "New-File")},
+		{filepath.Join(dir, "MIXED-CASE", "New-File"), filepath.Join(dir, "Mixed-Case", "New-File")},
+		{filepath.Join(dir, "exact"), filepath.Join(dir, "exact")},
+		{filepath.Join(dir, "EXACT"), filepath.Join(dir, "EXACT")},
+		{filepath.Join(dir, "missing", "File"), filepath.Join(dir, "missing", "File")},
+	}
+	for _, tt := range tests {
+		got := canonicalPathCase(tt.input)
+		if got != tt.want {
+			t.Errorf("canonicalPathCase(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		id, err := fileToID(got)
+		if err != nil {
+			t.Fatalf("fileToID(%q): %v", got, err)
+		}
+		file, err := idToFile(id)
+		if err != nil {
+			t.Fatalf("idToFile(%q): %v", id, err)
+		}
+		if file != tt.want {
+			t.Errorf("round-trip of %q = %q, want %q", tt.input, file, tt.want)
+		}
+	}
+}
+
+func TestEnsureCopyFile_noFollow(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("O_NOFOLLOW is not supported on windows")
+	}
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := ioutil.WriteFile(target, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "./testdata/source-file01",
+		"destination": link,
+		"no_follow":   true,
+	})
+	if diags := ensureCopyFile(context.Background(), data, nil); !diags.HasError() {
+		t.Fatalf("expected copy through symlink %q to fail", link)
+	}
+	if b, _ := ioutil.ReadFile(target); string(b) != "original" {
+		t.Fatalf("symlink target was modified: %q", b)
+	}
+}
+
+func TestEnsureCopyFile_exclusiveCreate(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	raw := map[string]interface{}{
+		"source":           "./testdata/source-file01",
+		"destination":      dest,
+		"exclusive_create": true,
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), raw)
+	if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error creating %q: %v", dest, diags)
+	}
+	// the destination now exists, so a second create must fail
+	data = schema.TestResourceDataRaw(t, resourceFileSchema(), raw)
+	if diags := ensureCopyFile(context.Background(), data, nil); !diags.HasError() {
+		t.Fatalf("expected exclusive create of existing %q to fail", dest)
+	}
+	// updates are not exclusive
+	raw["source"] = "./testdata/source-file02"
+	data = schema.TestResourceDataRaw(t, resourceFileSchema(), raw)
+	data.SetId("file://" + filepath.ToSlash(dest))
+	if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error updating %q: %v", dest, diags)
+	}
+}
+
+func TestWriteFileAtomic_exclusiveRace(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dest-file")
+	err := writeFileAtomic(dest, 0644, os.O_CREATE|os.O_EXCL|os.O_WRONLY, func(w io.Writer) error {
+		// another process creates the destination while it is written
+		if err := ioutil.WriteFile(dest, []byte("theirs"), 0644); err != nil {
+			return err
+		}
+		_, err := w.Write([]byte("ours"))
+		return err
+	})
+	if !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected the destination created meanwhile to fail with ErrExist, got %v", err)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "theirs" {
+		t.Fatalf("the destination was overwritten with %q", b)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected the temporary file to be removed, got %d entries", len(entries))
+	}
+}
+
+func TestAccResourceFile_recursive(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccDestroyFile,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "synclocal" {
+}
+
+resource "synclocal_file" "tree" {
+	source      = "./testdata/source-dir"
+	destination = "./testdata/dest-dir"
+	recursive   = true
+}
+`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("synclocal_file.tree", "content_sha256"),
+				),
+			},
+			{
+				// drift on a nested file is repaired by the next apply
+				PreConfig: func() {
+					if err := ioutil.WriteFile("./testdata/dest-dir/sub/b.txt", []byte("changed"), 0644); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: `
+provider "synclocal" {
+}
+
+resource "synclocal_file" "tree" {
+	source      = "./testdata/source-dir"
+	destination = "./testdata/dest-dir"
+	recursive   = true
+}
+`,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestEnsureCopyFile_recursive(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "source")
+	for _, dir := range []string{"sub/nested", "empty"} {
+		if err := os.MkdirAll(filepath.Join(source, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]os.FileMode{
+		"a.txt":              0644,
+		"sub/b.txt":          0600,
+		"sub/nested/c.txt":   0640,
+		"sub/nested/run.cmd": 0755,
+	}
+	for name, mode := range files {
+		if err := ioutil.WriteFile(filepath.Join(source, name), []byte(name), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filepath.Join(source, name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dest := filepath.Join(t.TempDir(), "dest")
+	if err := os.MkdirAll(filepath.Join(dest, "stale"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dest, "stale", "old.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      source,
+		"destination": dest,
+		"recursive":   true,
+	})
+	if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	for name, mode := range files {
+		b, err := ioutil.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatalf("%s was not copied: %v", name, err)
+		}
+		if string(b) != name {
+			t.Errorf("unexpected content of %s: %q", name, b)
+		}
+		if runtime.GOOS == "windows" {
+			continue
+		}
+		if stat, _ := os.Stat(filepath.Join(dest, name)); stat.Mode().Perm() != mode {
+			t.Errorf("unexpected mode of %s: %s, want %s", name, stat.Mode().Perm(), mode)
+		}
+	}
+	if stat, err := os.Stat(filepath.Join(dest, "empty")); err != nil || !stat.IsDir() {
+		t.Errorf("empty directory was not copied: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "stale")); !os.IsNotExist(err) {
+		t.Errorf("expected stale directory to be removed")
+	}
+	sourceHash, _ := hashTree(source)
+	if got := data.Get("content_sha256").(string); got != sourceHash {
+		t.Fatalf("unexpected content_sha256 %q, want %q", got, sourceHash)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dest, "sub", "nested", "c.txt"), []byte("drift"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if destHash, _ := hashTree(dest); destHash == sourceHash {
+		t.Fatalf("expected drift in a nested file to change the tree hash")
+	}
+}
+
+func TestEnsureCopyFile_createParents(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "a", "b", "dest-file")
+	raw := map[string]interface{}{
+		"source":         "./testdata/source-file01",
+		"destination":    dest,
+		"create_parents": false,
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), raw)
+	if diags := ensureCopyFile(context.Background(), data, nil); !diags.HasError() {
+		t.Fatalf("expected copy without create_parents to fail")
+	}
+	raw["create_parents"] = true
+	raw["dir_mode"] = "0700"
+	data = schema.TestResourceDataRaw(t, resourceFileSchema(), raw)
+	if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("unexpected content %q", b)
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if stat, _ := os.Stat(filepath.Join(dir, "a")); stat.Mode().Perm() != 0700 {
+		t.Fatalf("unexpected directory mode %s", stat.Mode().Perm())
+	}
+}
+
+func TestAccResourceFile_content(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccDestroyFile,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "synclocal" {
+}
+
+resource "synclocal_file" "inline" {
+	content     = "hello"
+	destination = "./testdata/dest-file-content"
+}
+`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("synclocal_file.inline", "content_sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"),
+				),
+			},
+		},
+	})
+}
+
+func TestEnsureCopyFile_content(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  map[string]interface{}
+		want string
+	}{
+		{"content", map[string]interface{}{"content": "hello"}, "hello"},
+		{"content_base64", map[string]interface{}{"content_base64": "AAEC/w=="}, "\x00\x01\x02\xff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			tt.raw["destination"] = dest
+			data := schema.TestResourceDataRaw(t, resourceFileSchema(), tt.raw)
+			if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			b, err := ioutil.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Fatalf("unexpected content %q, want %q", b, tt.want)
+			}
+			if got := data.Get("content_sha256").(string); got != hashBytes([]byte(tt.want)) {
+				t.Fatalf("unexpected content_sha256 %q", got)
+			}
+		})
+	}
+}
+
+func TestInlineContent_invalidBase64(t *testing.T) {
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"destination":    "dest-file",
+		"content_base64": "not base64!",
+	})
+	if _, _, err := inlineContent(data); err == nil {
+		t.Fatalf("expected invalid base64 to fail")
+	}
+}
+
+func TestEnsureCopyFile_preserveTimestamps(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	if err := ioutil.WriteFile(source, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(source, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":              source,
+		"destination":         dest,
+		"preserve_timestamps": true,
+	})
+	// the second call takes the unchanged-content path
+	for i := 0; i < 2; i++ {
+		if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		stat, err := os.Stat(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stat.ModTime().Equal(mtime) {
+			t.Fatalf("copy %d: unexpected mtime %s, want %s", i, stat.ModTime(), mtime)
+		}
+		if err := os.Chtimes(dest, time.Now(), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dest-file")
+	if err := ioutil.WriteFile(dest, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	flag := os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	err := writeFileAtomic(dest, 0600, flag, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return fmt.Errorf("write failed")
+	})
+	if err == nil {
+		t.Fatalf("expected write error")
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "original" {
+		t.Fatalf("destination was modified by a failed write: %q", b)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("temporary file was not removed: %v", entries)
+	}
+	err = writeFileAtomic(dest, 0600, flag, func(w io.Writer) error {
+		_, err := w.Write([]byte("replaced"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "replaced" {
+		t.Fatalf("unexpected content %q", b)
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if stat, _ := os.Stat(dest); stat.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected mode %s", stat.Mode().Perm())
+	}
+	// without no_follow a symlink is written through to its target
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(dest, link); err != nil {
+		t.Fatal(err)
+	}
+	err = writeFileAtomic(link, 0600, flag, func(w io.Writer) error {
+		_, err := w.Write([]byte("through link"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fi, _ := os.Lstat(link); fi.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("symlink was replaced")
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "through link" {
+		t.Fatalf("unexpected target content %q", b)
+	}
+}
+
+func TestAccResourceFile_fileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+	config := func(mode string) string {
+		return fmt.Sprintf(`
+provider "synclocal" {
+}
+
+resource "synclocal_file" "copy" {
+	source      = "./testdata/source-file01"
+	destination = "./testdata/dest-file-mode"
+	file_mode   = "%s"
+}
+`, mode)
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccDestroyFile,
+		Steps: []resource.TestStep{
+			{
+				Config: config("0644"),
+				Check:  testAccCheckFileMode("./testdata/dest-file-mode", 0644),
+			},
+			{
+				Config: config("0600"),
+				Check:  testAccCheckFileMode("./testdata/dest-file-mode", 0600),
+			},
+			{
+				// a chmod outside of terraform is reverted
+				PreConfig: func() {
+					if err := os.Chmod("./testdata/dest-file-mode", 0666); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: config("0600"),
+				Check:  testAccCheckFileMode("./testdata/dest-file-mode", 0600),
+			},
+		},
+	})
+}
+
+func testAccCheckFileMode(filename string, mode os.FileMode) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		stat, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		if stat.Mode().Perm() != mode {
+			return fmt.Errorf("unexpected mode of %q: %s, want %s", filename, stat.Mode().Perm(), mode)
+		}
+		return nil
+	}
+}
+
+func TestModeChanged(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	if err := ioutil.WriteFile(dest, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dest, 0600); err != nil {
+		t.Fatal(err)
+	}
+	for mode, want := range map[string]bool{"0600": false, "0644": true, "600": false} {
+		data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+			"source":      "./testdata/source-file01",
+			"destination": dest,
+			"file_mode":   mode,
+		})
+		changed, err := modeChanged(data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != want {
+			t.Errorf("file_mode %s: modeChanged() = %v, want %v", mode, changed, want)
+		}
+	}
+}
+
+func TestResourceFileDelete_keepOnDestroy(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		dest := filepath.Join(t.TempDir(), "dest-file")
+		if err := ioutil.WriteFile(dest, []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+			"source":          "./testdata/source-file01",
+			"destination":     dest,
+			"keep_on_destroy": keep,
+		})
+		id, _ := fileToID(dest)
+		data.SetId(id)
+		data.Set("managed", true)
+		if diags := resourceFileDelete(context.Background(), data, nil); diags.HasError() {
+			t.Fatalf("keep_on_destroy=%v: unexpected error: %v", keep, diags)
+		}
+		if _, err := os.Stat(dest); os.IsNotExist(err) == keep {
+			t.Fatalf("keep_on_destroy=%v: unexpected existence of %q after delete: %v", keep, dest, err)
+		}
+	}
+}
+
+func TestEnsureCopyFile_noOverwrite(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	if err := ioutil.WriteFile(dest, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	raw := map[string]interface{}{
+		"source":      "./testdata/source-file01",
+		"destination": dest,
+		"overwrite":   false,
+	}
+	// matching content is a no-op
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), raw)
+	if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	raw["source"] = "./testdata/source-file02"
+	data = schema.TestResourceDataRaw(t, resourceFileSchema(), raw)
+	if diags := ensureCopyFile(context.Background(), data, nil); !diags.HasError() {
+		t.Fatalf("expected overwrite of %q to fail", dest)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("destination was overwritten: %q", b)
+	}
+}
+
+func TestAccResourceFile_noOverwrite(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccDestroyFile,
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					if err := ioutil.WriteFile("./testdata/dest-file-no-overwrite", []byte("existing"), 0644); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: `
+provider "synclocal" {
+}
+
+resource "synclocal_file" "copy" {
+	source      = "./testdata/source-file01"
+	destination = "./testdata/dest-file-no-overwrite"
+	overwrite   = false
+}
+`,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`overwrite is false`),
+			},
+		},
+	})
+}
+
+func TestEnsureCopyFile_backup(t *testing.T) {
+	tests := []struct {
+		name    string
+		restore bool
+		want    string
+	}{
+		{"leave backup", false, ""},
+		{"restore backup", true, "original"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			if err := ioutil.WriteFile(dest, []byte("original"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+				"source":                    "./testdata/source-file01",
+				"destination":               dest,
+				"backup":                    true,
+				"restore_backup_on_destroy": tt.restore,
+			})
+			if diags := resourceFileCreate(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			backup := data.Get("backup_path").(string)
+			if backup != dest+".bak" {
+				t.Fatalf("unexpected backup_path %q", backup)
+			}
+			if b, _ := ioutil.ReadFile(backup); string(b) != "original" {
+				t.Fatalf("unexpected backup content %q", b)
+			}
+			if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+				t.Fatalf("unexpected destination content %q", b)
+			}
+			if diags := resourceFileDelete(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			b, _ := ioutil.ReadFile(dest)
+			if string(b) != tt.want {
+				t.Fatalf("unexpected destination content after destroy %q, want %q", b, tt.want)
+			}
+			if _, err := os.Stat(backup); os.IsNotExist(err) != tt.restore {
+				t.Fatalf("unexpected backup existence after destroy: %v", err)
+			}
+		})
+	}
+}
+
+func TestEnsureCopyFile_backupKeptAcrossUpdates(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source-file")
+	dest := filepath.Join(dir, "dest-file")
+	if err := ioutil.WriteFile(dest, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":                    source,
+		"destination":               dest,
+		"backup":                    true,
+		"restore_backup_on_destroy": true,
+	})
+	for i, content := range []string{"first", "second", "third"} {
+		if err := ioutil.WriteFile(source, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		apply := resourceFileUpdate
+		if i == 0 {
+			apply = resourceFileCreate
+		}
+		if diags := apply(context.Background(), data, nil); diags.HasError() {
+			t.Fatalf("%s: unexpected error: %v", content, diags)
+		}
+		if b, _ := ioutil.ReadFile(dest); string(b) != content {
+			t.Fatalf("%s: unexpected destination content %q", content, b)
+		}
+		if b, _ := ioutil.ReadFile(dest + ".bak"); string(b) != "original" {
+			t.Fatalf("%s: the backup was replaced with %q", content, b)
+		}
+	}
+	if diags := resourceFileDelete(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "original" {
+		t.Fatalf("destroy restored %q instead of the original file", b)
+	}
+}
+
+func TestEnsureCopyFile_backupNotOfOwnWrite(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source-file")
+	dest := filepath.Join(dir, "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":                    source,
+		"destination":               dest,
+		"backup":                    true,
+		"restore_backup_on_destroy": true,
+	})
+	// nothing is there to back up on create, and on update the destination
+	// is what the resource wrote
+	for i, content := range []string{"first", "second"} {
+		if err := ioutil.WriteFile(source, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		apply := resourceFileUpdate
+		if i == 0 {
+			apply = resourceFileCreate
+		}
+		if diags := apply(context.Background(), data, nil); diags.HasError() {
+			t.Fatalf("%s: unexpected error: %v", content, diags)
+		}
+	}
+	if backup := data.Get("backup_path").(string); backup != "" {
+		t.Fatalf("unexpected backup_path %q", backup)
+	}
+	if _, err := os.Stat(dest + ".bak"); !os.IsNotExist(err) {
+		t.Fatalf("the content written by the resource was backed up: %v", err)
+	}
+	if diags := resourceFileDelete(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("the destination was not removed on destroy: %v", err)
+	}
+}
+
+func TestEnsureCopyFile_sourceGlob(t *testing.T) {
+	source := t.TempDir()
+	for name, content := range map[string]string{"a.pem": "alpha", "b.pem": "bravo", "c.txt": "charlie"} {
+		if err := ioutil.WriteFile(filepath.Join(source, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dest := filepath.Join(t.TempDir(), "certs")
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      filepath.Join(source, "*.pem"),
+		"destination": dest,
+		"source_glob": true,
+	})
+	if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	want := []string{filepath.Join(dest, "a.pem"), filepath.Join(dest, "b.pem")}
+	if got := stringList(data.Get("files")); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected files %v, want %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "c.txt")); !os.IsNotExist(err) {
+		t.Fatalf("unmatched file was copied")
+	}
+	destHash, err := hashFileSet(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := data.Get("content_sha256").(string); got != destHash {
+		t.Fatalf("unexpected content_sha256 %q, want %q", got, destHash)
+	}
+}
+
+func TestGlobFiles_errors(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"x", "y"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, sub, "same.pem"), []byte(sub), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		pattern string
+		wantErr *regexp.Regexp
+	}{
+		{filepath.Join(dir, "*.none"), regexp.MustCompile(`does not match any files`)},
+		{filepath.Join(dir, "*", "same.pem"), regexp.MustCompile(`have the same name`)},
+		{filepath.Join(dir, "["), regexp.MustCompile(`invalid source pattern`)},
+	}
+	for _, tt := range tests {
+		if _, _, err := globFiles(tt.pattern, "dest"); err == nil || !tt.wantErr.MatchString(err.Error()) {
+			t.Errorf("globFiles(%q) error = %v, want %s", tt.pattern, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEnsureCopyFile_sourceGlobNotDirectory(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	if err := ioutil.WriteFile(dest, []byte("file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "./testdata/source-file0*",
+		"destination": dest,
+		"source_glob": true,
+	})
+	if diags := ensureCopyFile(context.Background(), data, nil); !diags.HasError() {
+		t.Fatalf("expected copy into non-directory %q to fail", dest)
+	}
+}
+
+func TestCopyFileSHA256(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	hash, err := copyFileSHA256(context.Background(), "./testdata/source-file02", dest, 0, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := hashFile("./testdata/source-file02"); hash != want {
+		t.Fatalf("unexpected hash %q, want %q", hash, want)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "goodbye" {
+		t.Fatalf("unexpected content %q", b)
+	}
+}
+
+func TestEnsureCopyFile_sameSizeDifferentContent(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	// same length as source-file01 so the hashes must be compared
+	if err := ioutil.WriteFile(dest, []byte("jello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "./testdata/source-file01",
+		"destination": dest,
+	})
+	if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("unexpected content %q", b)
+	}
+	if got := data.Get("content_sha256").(string); got != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Fatalf("unexpected content_sha256 %q", got)
+	}
+}
+
+func TestResourceFileCreate_deadline(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "./testdata/source-file01",
+		"destination": dest,
+	})
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	diags := resourceFileCreate(ctx, data, nil)
+	if !diags.HasError() {
+		t.Fatalf("expected a deadline error")
+	}
+	if !strings.HasPrefix(diags[0].Summary, "create timed out after") {
+		t.Fatalf("unexpected summary %q", diags[0].Summary)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("expected no file to be written")
+	}
+}
+
+// BenchmarkCopyFile copies a 256MB file with different buffer sizes:
+//
+//	go test ./internal/provider -run '^$' -bench CopyFile
+func BenchmarkCopyFile(b *testing.B) {
+	dir := b.TempDir()
+	source := filepath.Join(dir, "source")
+	chunk := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	f, err := os.Create(source)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 256; i++ {
+		if _, err := f.Write(chunk); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		b.Fatal(err)
+	}
+	dest := filepath.Join(dir, "dest")
+	flag := os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	for _, size := range []int{32 << 10, 256 << 10, defaultCopyBufferSize, 4 << 20} {
+		b.Run(fmt.Sprintf("buffer=%dKB", size>>10), func(b *testing.B) {
+			b.SetBytes(int64(len(chunk)) * 256)
+			for i := 0; i < b.N; i++ {
+				buf := (&providerConfig{copyBufferSize: size}).newCopyBuffer()
+				if _, err := copyFileSHA256(context.Background(), source, dest, 0, flag, buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestAccResourceFile_import(t *testing.T) {
+	config := `
+provider "synclocal" {
+}
+
+resource "synclocal_file" "copy" {
+	source      = "./testdata/source-file01"
+	destination = "./testdata/dest-file-import"
+}
+`
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccDestroyFile,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+			},
+			{
+				Config:            config,
+				ResourceName:      "synclocal_file.copy",
+				ImportState:       true,
+				ImportStateVerify: true,
+				// source is not known from the destination alone, and the
+				// imported destination is absolute
+				ImportStateVerifyIgnore: []string{"source", "destination"},
+			},
+		},
+	})
+}
+
+func TestResourceFileImport(t *testing.T) {
+	dest, err := filepath.Abs("./testdata/source-file02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := fileToID(dest)
+	for _, importID := range []string{"./testdata/source-file02", id} {
+		data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{})
+		data.SetId(importID)
+		result, err := resourceFileImport(context.Background(), data, nil)
+		if err != nil {
+			t.Fatalf("import %q: unexpected error: %v", importID, err)
+		}
+		if len(result) != 1 || result[0].Id() != id {
+			t.Fatalf("import %q: unexpected id %q", importID, result[0].Id())
+		}
+		if got := data.Get("content_sha256").(string); got != "82e35a63ceba37e9646434c5dd412ea577147f1e4a41ccde1614253187e3dbf9" {
+			t.Fatalf("import %q: unexpected content_sha256 %q", importID, got)
+		}
+		if got := data.Get("destination").(string); got != dest {
+			t.Fatalf("import %q: unexpected destination %q", importID, got)
+		}
+		if !data.Get("overwrite").(bool) || data.Get("dir_mode").(string) != "0755" {
+			t.Fatalf("import %q: defaults were not set", importID)
+		}
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{})
+	data.SetId("./testdata/does-not-exist")
+	if _, err := resourceFileImport(context.Background(), data, nil); err == nil {
+		t.Fatalf("expected import of a missing file to fail")
+	}
+}
+
+func TestEnsureCopyFile_recursiveNewDestination(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest")
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "./testdata/source-dir",
+		"destination": dest,
+		"recursive":   true,
+	})
+	if diags := ensureCopyFile(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dest, "a.txt"))
+	if err != nil || string(b) != "alpha\n" {
+		t.Fatalf("unexpected content %q: %v", b, err)
+	}
+}
+
+func TestResourceFileCreate_hashAlgorithm(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{"md5", "5d41402abc4b2a76b9719d911017c592"},
+		{"sha1", "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{"sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{"sha512", "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+				"source":         "./testdata/source-file01",
+				"destination":    filepath.Join(t.TempDir(), "dest-file"),
+				"hash_algorithm": tt.algorithm,
+			})
+			if diags := resourceFileCreate(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if got := data.Get("content_hash").(string); got != tt.want {
+				t.Fatalf("unexpected content_hash %q, want %q", got, tt.want)
+			}
+			// content_sha256 is kept for change detection
+			if got := data.Get("content_sha256").(string); got != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+				t.Fatalf("unexpected content_sha256 %q", got)
+			}
+		})
+	}
+}
+
+func TestResourceFileCreate_hashAlgorithmRecursive(t *testing.T) {
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":         "./testdata/source-dir",
+		"destination":    filepath.Join(t.TempDir(), "dest"),
+		"recursive":      true,
+		"hash_algorithm": "md5",
+	})
+	if diags := resourceFileCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	want, err := hashTreeWith("./testdata/source-dir", md5.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := data.Get("content_hash").(string); got != want || len(got) != 32 {
+		t.Fatalf("unexpected content_hash %q, want %q", got, want)
+	}
+}
+
+func TestResourceFileCreate_workingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "source"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &providerConfig{workingDir: dir}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      "source",
+		"destination": "dest",
+	})
+	if diags := resourceFileCreate(context.Background(), data, cfg); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	dest := filepath.Join(dir, "dest")
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("unexpected content %q", b)
+	}
+	if want, _ := fileToID(dest); data.Id() != want {
+		t.Fatalf("unexpected id %q, want %q", data.Id(), want)
+	}
+	if got := data.Get("source_sha256").(string); got != hashBytes([]byte("hello")) {
+		t.Fatalf("unexpected source_sha256 %q", got)
+	}
+	// the plan resolves the paths the same way
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"source":      "source",
+		"destination": "dest",
+	})
+	diff, err := resourceFile().Diff(context.Background(), data.State(), config, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr := diff.Attributes["content_sha256"]; attr != nil {
+		t.Fatalf("expected the content to be unchanged, got %#v", attr)
+	}
+}
+
+func TestResourceFileRead_sourceSHA256(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	if err := ioutil.WriteFile(source, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      source,
+		"destination": filepath.Join(dir, "dest"),
+	})
+	if diags := resourceFileCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := data.Get("source_sha256").(string); got != data.Get("content_sha256").(string) {
+		t.Fatalf("expected source_sha256 %q to match content_sha256 %q", got, data.Get("content_sha256"))
+	}
+	// the source changes, but the destination keeps the copied content
+	if err := ioutil.WriteFile(source, []byte("goodbye"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceFileRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := data.Get("source_sha256").(string); got != "82e35a63ceba37e9646434c5dd412ea577147f1e4a41ccde1614253187e3dbf9" {
+		t.Fatalf("unexpected source_sha256 %q", got)
+	}
+	if got := data.Get("content_sha256").(string); got != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Fatalf("unexpected content_sha256 %q", got)
+	}
+	// a missing source leaves it empty rather than failing the read
+	if err := os.Remove(source); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceFileRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if got := data.Get("source_sha256").(string); got != "" {
+		t.Fatalf("expected an empty source_sha256, got %q", got)
+	}
+}
+
+func TestResourceFileDiff_sourceSHA256(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dest")
+	if err := ioutil.WriteFile(dest, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"content":     "hello",
+		"destination": dest,
+	})
+	diff, err := resourceFile().Diff(context.Background(), nil, config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attr := diff.Attributes["source_sha256"]
+	if attr == nil || attr.NewComputed || attr.New != hashBytes([]byte("hello")) {
+		t.Fatalf("expected source_sha256 to be planned, got %#v", attr)
+	}
+}
+
+func TestEnsureCopyFile_expectedSHA256(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      map[string]interface{}
+		expected string
+		wantErr  bool
+	}{
+		{"match", map[string]interface{}{"source": "./testdata/source-file01"}, "2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824", false},
+		{"mismatch", map[string]interface{}{"source": "./testdata/source-file01"}, strings.Repeat("0", 64), true},
+		{"content mismatch", map[string]interface{}{"content": "tampered"}, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", true},
+		{"recursive mismatch", map[string]interface{}{"source": "./testdata/source-dir", "recursive": true}, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "parent", "dest")
+			tt.raw["destination"] = dest
+			tt.raw["expected_sha256"] = tt.expected
+			data := schema.TestResourceDataRaw(t, resourceFileSchema(), tt.raw)
+			diags := ensureCopyFile(context.Background(), data, nil)
+			if !tt.wantErr {
+				if diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				return
+			}
+			if !diags.HasError() || !strings.Contains(diags[0].Detail, tt.expected) {
+				t.Fatalf("expected a mismatch error showing the expected hash, got %v", diags)
+			}
+			// nothing is written, not even the parent directory
+			if _, err := os.Stat(filepath.Dir(dest)); !os.IsNotExist(err) {
+				t.Fatalf("expected the destination to be untouched, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFileToID_roundTrip(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"plain.txt",
+		"my dir/file#1.txt",
+		"what?.txt",
+		"100% done.txt",
+		"a+b=c&d;e.txt",
+		"%20literal.txt",
+		"ünïcødé ☃.txt",
+		"trailing#",
+		"trailing?",
+	}
+	for _, name := range names {
+		want := filepath.Join(dir, filepath.FromSlash(name))
+		id, err := fileToID(want)
+		if err != nil {
+			t.Fatalf("fileToID(%q): %v", want, err)
+		}
+		got, err := idToFile(id)
+		if err != nil {
+			t.Fatalf("idToFile(%q): %v", id, err)
+		}
+		if got != want {
+			t.Errorf("round-trip of %q through %q = %q", want, id, got)
+		}
+	}
+}
+
+func TestIDToFile_unescaped(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses unix paths")
+	}
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"file:///tmp/my dir/file#1.txt", "/tmp/my dir/file#1.txt"},
+		{"file:///tmp/what?.txt", "/tmp/what?.txt"},
+		{"file:///tmp/a?b#c", "/tmp/a?b#c"},
+	}
+	for _, tt := range tests {
+		got, err := idToFile(tt.id)
+		if err != nil {
+			t.Fatalf("idToFile(%q): %v", tt.id, err)
+		}
+		if got != tt.want {
+			t.Errorf("idToFile(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestFileURLSlash_windows(t *testing.T) {
+	tests := []struct {
+		path string
+		id   string
+	}{
+		{"C:/foo/bar", "file:///C:/foo/bar"},
+		{"c:/my dir/file#1.txt", "file:///c:/my%20dir/file%231.txt"},
+		{"D:/", "file:///D:/"},
+		{"//server/share/dir/file.txt", "file://server/share/dir/file.txt"},
+		{"//server/share", "file://server/share"},
+	}
+	for _, tt := range tests {
+		id := fileURLFromSlash(tt.path).String()
+		if id != tt.id {
+			t.Errorf("fileURLFromSlash(%q) = %q, want %q", tt.path, id, tt.id)
+		}
+		u, err := url.Parse(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := fileURLToSlash(u, true)
+		if err != nil {
+			t.Fatalf("fileURLToSlash(%q): %v", id, err)
+		}
+		if got != tt.path {
+			t.Errorf("fileURLToSlash(%q) = %q, want %q", id, got, tt.path)
+		}
+	}
+}
+
+func TestFileURLToSlash(t *testing.T) {
+	tests := []struct {
+		id      string
+		windows bool
+		want    string
+		wantErr bool
+	}{
+		{"file:///tmp/file", false, "/tmp/file", false},
+		{"file://localhost/tmp/file", false, "/tmp/file", false},
+		{"file:///C:/foo", false, "/C:/foo", false},
+		{"file://server/share/file", false, "", true},
+		{"file://localhost/C:/foo", true, "C:/foo", false},
+		// IDs written before the drive letter was moved into the path
+		{"file://C:/foo/bar", true, "C:/foo/bar", false},
+		{"file:relative", true, "", true},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := fileURLToSlash(u, tt.windows)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("fileURLToSlash(%q, %v) error = %v, wantErr %v", tt.id, tt.windows, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("fileURLToSlash(%q, %v) = %q, want %q", tt.id, tt.windows, got, tt.want)
+		}
+	}
+}
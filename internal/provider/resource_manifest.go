@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io"
+	"os"
+)
+
+func resourceManifest() *schema.Resource {
+	return &schema.Resource{
+		ReadContext:   resourceManifestRead,
+		CreateContext: resourceManifestCreate,
+		UpdateContext: resourceManifestUpdate,
+		DeleteContext: resourceManifestDelete,
+		CustomizeDiff: func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+			cfg := configFromMeta(m)
+			if err := cfg.checkAllowedRoot(diff, "destination"); err != nil {
+				return err
+			}
+			destHash, err := hashFile(cfg.resolvePath(diff.Get("destination").(string)))
+			if os.IsNotExist(err) {
+				return diff.SetNewComputed("content_sha256")
+			}
+			files, ok := manifestFiles(diff)
+			if !ok {
+				// paths are not known until apply
+				return diff.SetNewComputed("content_sha256")
+			}
+			doc, err := buildManifest(files, cfg)
+			if err != nil {
+				// the referenced files may be created during apply
+				return diff.SetNewComputed("content_sha256")
+			}
+			if destHash != hashBytes(doc) {
+				return diff.SetNewComputed("content_sha256")
+			}
+			return nil
+		},
+		Schema: resourceManifestSchema(),
+	}
+}
+
+func resourceManifestSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"destination": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "Destination file path of the JSON manifest",
+			ForceNew:    true,
+		},
+		"file": {
+			Type:        schema.TypeList,
+			Required:    true,
+			Description: "Files to record in the manifest, in order",
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"name": {
+						Type:        schema.TypeString,
+						Required:    true,
+						Description: "Name of the entry in the manifest",
+					},
+					"path": {
+						Type:        schema.TypeString,
+						Required:    true,
+						Description: "Path of the file to record",
+					},
+				},
+			},
+		},
+		"content_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash of the manifest contents",
+		},
+	}
+}
+
+type manifestFile struct {
+	Name string
+	Path string
+}
+
+type manifestEntry struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	Mode   string `json:"mode"`
+}
+
+type manifestDocument struct {
+	Files []manifestEntry `json:"files"`
+}
+
+// manifestFiles reads the file blocks. It returns false if any path is not
+// known yet.
+func manifestFiles(data resourceGetter) ([]manifestFile, bool) {
+	var files []manifestFile
+	for _, v := range data.Get("file").([]interface{}) {
+		m, _ := v.(map[string]interface{})
+		name, _ := m["name"].(string)
+		path, _ := m["path"].(string)
+		if path == "" {
+			return nil, false
+		}
+		files = append(files, manifestFile{Name: name, Path: path})
+	}
+	return files, true
+}
+
+// buildManifest hashes each file and renders the manifest document. Paths
+// are recorded as configured, even when they are resolved against
+// working_directory.
+func buildManifest(files []manifestFile, cfg *providerConfig) ([]byte, error) {
+	doc := manifestDocument{Files: []manifestEntry{}}
+	for _, f := range files {
+		path := cfg.resolvePath(f.Path)
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not stat %q for manifest entry %q: %w", f.Path, f.Name, err)
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not hash %q for manifest entry %q: %w", f.Path, f.Name, err)
+		}
+		doc.Files = append(doc.Files, manifestEntry{
+			Name:   f.Name,
+			Path:   f.Path,
+			SHA256: hash,
+			Size:   stat.Size(),
+			Mode:   fmt.Sprintf("%04o", stat.Mode().Perm()),
+		})
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func hashBytes(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func ensureManifest(data *schema.ResourceData, cfg *providerConfig) diag.Diagnostics {
+	files, _ := manifestFiles(data)
+	doc, err := buildManifest(files, cfg)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	dest := cfg.resolvePath(data.Get("destination").(string))
+	err = writeFileAtomic(dest, 0644, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, func(w io.Writer) error {
+		_, err := w.Write(doc)
+		return err
+	})
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not write manifest %q: %w", dest, err))
+	}
+	data.Set("content_sha256", hashBytes(doc))
+	return nil
+}
+
+func resourceManifestCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination"); err != nil {
+		return diag.FromErr(err)
+	}
+	if diags := ensureManifest(data, configFromMeta(m)); diags.HasError() {
+		return diags
+	}
+	id, err := fileToID(configFromMeta(m).resolvePath(data.Get("destination").(string)))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	return nil
+}
+
+func resourceManifestUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination"); err != nil {
+		return diag.FromErr(err)
+	}
+	if diags := ensureManifest(data, configFromMeta(m)); diags.HasError() {
+		return diags
+	}
+	return resourceManifestRead(ctx, data, m)
+}
+
+func resourceManifestRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	file, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	fileHash, err := hashFile(file)
+	if os.IsNotExist(err) {
+		data.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.Set("content_sha256", fileHash)
+	return nil
+}
+
+func resourceManifestDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	name, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("could not remove file %q: %w", name, err))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestAccResourceManifest(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccDestroyFile,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "synclocal" {
+}
+
+resource "synclocal_manifest" "bom" {
+	destination = "./testdata/dest-manifest.json"
+	file {
+		name = "first"
+		path = "./testdata/source-file01"
+	}
+	file {
+		name = "second"
+		path = "./testdata/source-file02"
+	}
+}
+`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("synclocal_manifest.bom", "content_sha256"),
+					testAccCheckManifest("./testdata/dest-manifest.json", []string{"first", "second"}),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckManifest(filename string, names []string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		var doc manifestDocument
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return fmt.Errorf("manifest %q is not valid JSON: %w", filename, err)
+		}
+		var got []string
+		for _, e := range doc.Files {
+			got = append(got, e.Name)
+		}
+		if !reflect.DeepEqual(got, names) {
+			return fmt.Errorf("manifest entries %v, want %v", got, names)
+		}
+		return nil
+	}
+}
+
+func TestBuildManifest(t *testing.T) {
+	doc, err := buildManifest([]manifestFile{
+		{Name: "first", Path: "./testdata/source-file01"},
+		{Name: "second", Path: "./testdata/source-file02"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got manifestDocument
+	if err := json.Unmarshal(doc, &got); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v", err)
+	}
+	want := []manifestEntry{
+		{Name: "first", Path: "./testdata/source-file01", SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", Size: 5},
+		{Name: "second", Path: "./testdata/source-file02", SHA256: "82e35a63ceba37e9646434c5dd412ea577147f1e4a41ccde1614253187e3dbf9", Size: 7},
+	}
+	for i := range got.Files {
+		// modes depend on the checkout
+		got.Files[i].Mode = ""
+	}
+	if !reflect.DeepEqual(got.Files, want) {
+		t.Fatalf("unexpected manifest entries:\n%+v\nwant:\n%+v", got.Files, want)
+	}
+	if _, err := buildManifest([]manifestFile{{Name: "missing", Path: "./testdata/does-not-exist"}}, nil); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
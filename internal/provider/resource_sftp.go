@@ -0,0 +1,415 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func resourceSFTP() *schema.Resource {
+	return &schema.Resource{
+		ReadContext:   resourceSFTPRead,
+		CreateContext: resourceSFTPCreate,
+		UpdateContext: resourceSFTPUpdate,
+		DeleteContext: resourceSFTPDelete,
+		CustomizeDiff: func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+			return configFromMeta(m).checkAllowedRoot(diff, "destination")
+		},
+		Timeouts: copyTimeouts(),
+		Schema:   resourceSFTPSchema(),
+	}
+}
+
+func resourceSFTPSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"host": {
+			Type:         schema.TypeString,
+			Required:     true,
+			ForceNew:     true,
+			ValidateFunc: validation.StringIsNotEmpty,
+			Description:  "Host name or address of the SFTP server",
+		},
+		"port": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			ForceNew:     true,
+			Default:      22,
+			ValidateFunc: validation.IsPortNumber,
+			Description:  "Port of the SFTP server. Defaults to 22.",
+		},
+		"path": {
+			Type:         schema.TypeString,
+			Required:     true,
+			ForceNew:     true,
+			ValidateFunc: validation.StringIsNotEmpty,
+			Description:  "Path of the file on the server. A relative path is resolved by the server, usually against the home directory of username.",
+		},
+		"username": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "User to authenticate as",
+		},
+		"password": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Sensitive:    true,
+			AtLeastOneOf: []string{"password", "private_key"},
+			Description:  "Password to authenticate with",
+		},
+		"private_key": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			Description: "PEM encoded private key to authenticate with. It is tried before password when both are set.",
+		},
+		"private_key_passphrase": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Sensitive:    true,
+			RequiredWith: []string{"private_key"},
+			Description:  "Passphrase of an encrypted private_key",
+		},
+		"known_hosts": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"insecure_ignore_host_key"},
+			Description:   "Path of the known_hosts file the server host key is verified against. Defaults to .ssh/known_hosts in the home directory.",
+		},
+		"insecure_ignore_host_key": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Accept any host key from the server. This allows the connection to be intercepted and should only be used for testing.",
+		},
+		"destination": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "Path the remote file is downloaded to",
+		},
+		"file_mode": {
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateDiagFunc: validateFileMode,
+			DiffSuppressFunc: suppressEquivalentFileMode,
+			Description:      "File mode for the destination (Octal String). Defaults to the provider default_file_mode, or 0664.",
+		},
+		"content_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash of the downloaded file",
+		},
+		"size": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "Size in bytes of the remote file when it was downloaded",
+		},
+		"last_modified": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Modification time of the remote file when it was downloaded, in RFC 3339 format. The file is downloaded again when its size or modification time changes.",
+		},
+		"create_parents":  createParentsSchema(),
+		"dir_mode":        dirModeSchema(),
+		"keep_on_destroy": keepOnDestroySchema(),
+	}
+}
+
+// sftpConn is an ssh connection with the sftp subsystem started.
+type sftpConn struct {
+	ssh  *ssh.Client
+	sftp *sftpClient
+}
+
+func (c *sftpConn) Close() error {
+	c.sftp.Close()
+	return c.ssh.Close()
+}
+
+// dialSFTP connects to the server and starts an sftp session. Connection,
+// host key and authentication failures are reported as separate
+// diagnostics, since each is fixed in a different place.
+func dialSFTP(ctx context.Context, data resourceGetter, cfg *providerConfig) (*sftpConn, diag.Diagnostics) {
+	auth, diags := sftpAuthMethods(data)
+	if diags.HasError() {
+		return nil, diags
+	}
+	hostKeyCallback, knownHosts, diags := sftpHostKeyCallback(data, cfg)
+	if diags.HasError() {
+		return nil, diags
+	}
+	// the ssh handshake error does not say why the host key was rejected,
+	// so the error of the callback is kept
+	var hostKeyErr error
+	config := &ssh.ClientConfig{
+		User: data.Get("username").(string),
+		Auth: auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKeyErr = hostKeyCallback(hostname, remote, key)
+			return hostKeyErr
+		},
+	}
+	addr := net.JoinHostPort(data.Get("host").(string), strconv.Itoa(data.Get("port").(int)))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "could not connect to the SFTP server",
+			Detail:        err.Error(),
+			AttributePath: cty.GetAttrPath("host"),
+		}}
+	}
+	// the ssh package does not take a context, so the deadline of ctx is
+	// put on the connection instead
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, sshHandshakeDiagnostics(addr, knownHosts, err, hostKeyErr)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	s, err := newSFTPClient(client)
+	if err != nil {
+		client.Close()
+		return nil, diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "could not start an SFTP session",
+			Detail:   err.Error(),
+		}}
+	}
+	return &sftpConn{ssh: client, sftp: s}, nil
+}
+
+// sftpAuthMethods returns the auth methods for private_key and password.
+func sftpAuthMethods(data resourceGetter) ([]ssh.AuthMethod, diag.Diagnostics) {
+	var methods []ssh.AuthMethod
+	if v, ok := data.GetOk("private_key"); ok {
+		var signer ssh.Signer
+		var err error
+		if passphrase, ok := data.GetOk("private_key_passphrase"); ok {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(v.(string)), []byte(passphrase.(string)))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(v.(string)))
+		}
+		if err != nil {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       "private_key could not be parsed",
+				Detail:        err.Error(),
+				AttributePath: cty.GetAttrPath("private_key"),
+			}}
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if v, ok := data.GetOk("password"); ok {
+		methods = append(methods, ssh.Password(v.(string)))
+	}
+	return methods, nil
+}
+
+// sftpHostKeyCallback returns the callback verifying the server host key
+// and the known_hosts file it uses.
+func sftpHostKeyCallback(data resourceGetter, cfg *providerConfig) (ssh.HostKeyCallback, string, diag.Diagnostics) {
+	if data.Get("insecure_ignore_host_key").(bool) {
+		return ssh.InsecureIgnoreHostKey(), "", nil
+	}
+	path := cfg.resolvePath(data.Get("known_hosts").(string))
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, "", diag.Errorf("could not find the known_hosts file: %s", err)
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		return nil, "", diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "could not load known_hosts",
+			Detail:        err.Error(),
+			AttributePath: cty.GetAttrPath("known_hosts"),
+		}}
+	}
+	return callback, path, nil
+}
+
+// sshHandshakeDiagnostics describes why the ssh handshake with addr failed.
+func sshHandshakeDiagnostics(addr, knownHosts string, err, hostKeyErr error) diag.Diagnostics {
+	var keyErr *knownhosts.KeyError
+	switch {
+	case errors.As(hostKeyErr, &keyErr) && len(keyErr.Want) == 0:
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "the SFTP server host key is unknown",
+			Detail:        fmt.Sprintf("%s is not listed in %q. Add its host key to the file, for example with ssh-keyscan.", addr, knownHosts),
+			AttributePath: cty.GetAttrPath("known_hosts"),
+		}}
+	case keyErr != nil:
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "the SFTP server host key does not match known_hosts",
+			Detail:        fmt.Sprintf("The host key of %s does not match the key listed in %q at line %d. The server may have been reinstalled, or the connection may have been intercepted.", addr, knownHosts, keyErr.Want[0].Line),
+			AttributePath: cty.GetAttrPath("known_hosts"),
+		}}
+	case hostKeyErr != nil:
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "the SFTP server host key was rejected",
+			Detail:        hostKeyErr.Error(),
+			AttributePath: cty.GetAttrPath("known_hosts"),
+		}}
+	case strings.Contains(err.Error(), "unable to authenticate"):
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "SFTP authentication failed",
+			Detail:        fmt.Sprintf("%s did not accept the credentials of the user: %s", addr, err),
+			AttributePath: cty.GetAttrPath("username"),
+		}}
+	}
+	return diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  "the SSH handshake with the SFTP server failed",
+		Detail:   err.Error(),
+	}}
+}
+
+// remoteFileDiagnostics describes an error reading the remote file.
+func remoteFileDiagnostics(path string, err error) diag.Diagnostics {
+	summary := "could not read the remote file"
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		summary = "the remote file does not exist"
+	case errors.Is(err, os.ErrPermission):
+		summary = "permission denied reading the remote file"
+	}
+	return diag.Diagnostics{{
+		Severity:      diag.Error,
+		Summary:       summary,
+		Detail:        fmt.Sprintf("%q: %s", path, err),
+		AttributePath: cty.GetAttrPath("path"),
+	}}
+}
+
+// ensureSFTPFile downloads the remote file to destination. Unless force is
+// set, the download is skipped while the size and modification time of the
+// remote file match the state.
+func ensureSFTPFile(ctx context.Context, data *schema.ResourceData, cfg *providerConfig, force bool) diag.Diagnostics {
+	conn, diags := dialSFTP(ctx, data, cfg)
+	if diags.HasError() {
+		return diags
+	}
+	defer conn.Close()
+	remote := data.Get("path").(string)
+	fi, err := conn.sftp.Stat(remote)
+	if err != nil {
+		return remoteFileDiagnostics(remote, err)
+	}
+	lastModified := fi.ModTime.Format(time.RFC3339)
+	if !force && int64(data.Get("size").(int)) == fi.Size && data.Get("last_modified").(string) == lastModified {
+		return nil
+	}
+	f, err := conn.sftp.Open(remote)
+	if err != nil {
+		return remoteFileDiagnostics(remote, err)
+	}
+	defer f.Close()
+	dest := cfg.resolvePath(data.Get("destination").(string))
+	if err := ensureParentDir(data, dest); err != nil {
+		return diag.FromErr(err)
+	}
+	mode, err := getFileMode(data, cfg)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	h := sha256.New()
+	err = writeFileAtomic(dest, mode, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, func(w io.Writer) error {
+		if _, err := copyBuffer(io.MultiWriter(w, h), contextReader{ctx: ctx, r: f}, cfg.newCopyBuffer()); err != nil {
+			return fmt.Errorf("could not download %q into %q: %w", remote, dest, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.Set("content_sha256", hex.EncodeToString(h.Sum(nil)))
+	data.Set("size", int(fi.Size))
+	data.Set("last_modified", lastModified)
+	return nil
+}
+
+func resourceSFTPCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination"); err != nil {
+		return diag.FromErr(err)
+	}
+	cfg := configFromMeta(m)
+	if diags := ensureSFTPFile(ctx, data, cfg, true); diags.HasError() {
+		return timeoutDiagnostics(ctx, diags, data, schema.TimeoutCreate)
+	}
+	id, err := fileToID(cfg.resolvePath(data.Get("destination").(string)))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	return nil
+}
+
+func resourceSFTPUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination"); err != nil {
+		return diag.FromErr(err)
+	}
+	return timeoutDiagnostics(ctx, ensureSFTPFile(ctx, data, configFromMeta(m), true), data, schema.TimeoutUpdate)
+}
+
+// resourceSFTPRead recreates the file if it was changed locally, and
+// downloads it again if the remote file changed.
+func resourceSFTPRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	dest, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	fileHash, err := hashFile(dest)
+	if os.IsNotExist(err) {
+		data.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if fileHash != data.Get("content_sha256").(string) {
+		data.SetId("")
+		return nil
+	}
+	return timeoutDiagnostics(ctx, ensureSFTPFile(ctx, data, configFromMeta(m), false), data, schema.TimeoutRead)
+}
+
+func resourceSFTPDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if data.Get("keep_on_destroy").(bool) {
+		return nil
+	}
+	dest, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("could not remove %q: %w", dest, err))
+	}
+	return nil
+}
@@ -0,0 +1,419 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testSFTPServer is an ssh server with a minimal sftp subsystem serving the
+// local file system. It accepts the user synclocal with the password secret
+// or the key userKey.
+type testSFTPServer struct {
+	host       string
+	port       int
+	hostKey    ssh.Signer
+	userKey    string
+	knownHosts string
+}
+
+func newTestSFTPServer(t *testing.T) *testSFTPServer {
+	t.Helper()
+	hostKey, _ := newTestSSHKey(t)
+	userKey, userKeyPEM := newTestSSHKey(t)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if c.User() == "synclocal" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("invalid password")
+		},
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if c.User() == "synclocal" && bytes.Equal(key.Marshal(), userKey.PublicKey().Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	config.AddHostKey(hostKey)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSSH(c, config)
+		}
+	}()
+	addr := l.Addr().(*net.TCPAddr)
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr.String())}, hostKey.PublicKey())
+	if err := ioutil.WriteFile(knownHosts, []byte(line+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &testSFTPServer{
+		host:       addr.IP.String(),
+		port:       addr.Port,
+		hostKey:    hostKey,
+		userKey:    string(userKeyPEM),
+		knownHosts: knownHosts,
+	}
+}
+
+// newTestSSHKey returns a new key as a signer and in PEM format.
+func newTestSSHKey(t *testing.T) (ssh.Signer, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func serveTestSSH(c net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(c, config)
+	if err != nil {
+		c.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, requests, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range requests {
+				name, _, _ := consumeString(req.Payload)
+				ok := req.Type == "subsystem" && name == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					go func() {
+						serveTestSFTP(ch)
+						ch.Close()
+					}()
+				}
+			}
+		}()
+	}
+}
+
+// serveTestSFTP answers the requests made by sftpClient until rw is closed.
+func serveTestSFTP(rw io.ReadWriter) {
+	files := make(map[string]*os.File)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for {
+		typ, payload, err := readSFTPPacket(rw)
+		if err != nil {
+			return
+		}
+		if typ == sftpInit {
+			writeSFTPPacket(rw, sftpVersion, appendUint32(nil, 3))
+			continue
+		}
+		id, payload, _ := consumeUint32(payload)
+		reply := func(typ byte, b []byte) {
+			writeSFTPPacket(rw, typ, append(appendUint32(nil, id), b...))
+		}
+		status := func(err error) {
+			var code uint32
+			switch {
+			case err == nil:
+				code = sftpOK
+			case err == io.EOF:
+				code = sftpEOF
+			case os.IsNotExist(err):
+				code = sftpNoSuchFile
+			case os.IsPermission(err):
+				code = sftpPermissionDenied
+			default:
+				code = 4 // SSH_FX_FAILURE
+			}
+			msg := ""
+			if err != nil {
+				msg = err.Error()
+			}
+			reply(sftpStatus, appendString(appendString(appendUint32(nil, code), msg), ""))
+		}
+		switch typ {
+		case sftpStat:
+			path, _, _ := consumeString(payload)
+			fi, err := os.Stat(path)
+			if err != nil {
+				status(err)
+				continue
+			}
+			b := appendUint32(nil, sftpAttrSize|sftpAttrPermissions|sftpAttrACModTime)
+			b = appendUint64(b, uint64(fi.Size()))
+			b = appendUint32(b, uint32(fi.Mode().Perm()))
+			b = appendUint32(b, uint32(fi.ModTime().Unix()))
+			b = appendUint32(b, uint32(fi.ModTime().Unix()))
+			reply(sftpAttrs, b)
+		case sftpOpen:
+			path, _, _ := consumeString(payload)
+			f, err := os.Open(path)
+			if err != nil {
+				status(err)
+				continue
+			}
+			handle := strconv.Itoa(len(files))
+			files[handle] = f
+			reply(sftpHandle, appendString(nil, handle))
+		case sftpRead:
+			handle, b, _ := consumeString(payload)
+			offset, b, _ := consumeUint64(b)
+			length, _, _ := consumeUint32(b)
+			buf := make([]byte, length)
+			n, err := files[handle].ReadAt(buf, int64(offset))
+			if n == 0 {
+				status(err)
+				continue
+			}
+			reply(sftpData, appendString(nil, string(buf[:n])))
+		case sftpClose:
+			handle, _, _ := consumeString(payload)
+			status(files[handle].Close())
+			delete(files, handle)
+		default:
+			status(errors.New("unsupported operation"))
+		}
+	}
+}
+
+func (s *testSFTPServer) raw(path, dest string) map[string]interface{} {
+	return map[string]interface{}{
+		"host":        s.host,
+		"port":        s.port,
+		"path":        path,
+		"username":    "synclocal",
+		"password":    "secret",
+		"known_hosts": s.knownHosts,
+		"destination": dest,
+	}
+}
+
+func TestResourceSFTPCreate(t *testing.T) {
+	server := newTestSFTPServer(t)
+	tests := []struct {
+		name string
+		auth map[string]interface{}
+	}{
+		{
+			name: "password",
+			auth: map[string]interface{}{"password": "secret"},
+		},
+		{
+			name: "private key",
+			auth: map[string]interface{}{"password": "", "private_key": server.userKey},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "dest")
+			raw := server.raw("./testdata/source-file01", dest)
+			for k, v := range tt.auth {
+				raw[k] = v
+			}
+			data := schema.TestResourceDataRaw(t, resourceSFTPSchema(), raw)
+			if diags := resourceSFTPCreate(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("create: %v", diags)
+			}
+			b, err := ioutil.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != "hello" {
+				t.Errorf("destination = %q, want %q", b, "hello")
+			}
+			if got := data.Get("content_sha256").(string); got != hashHello {
+				t.Errorf("content_sha256 = %q, want %q", got, hashHello)
+			}
+			if got := data.Get("size").(int); got != 5 {
+				t.Errorf("size = %d, want 5", got)
+			}
+		})
+	}
+}
+
+func TestResourceSFTPCreate_errors(t *testing.T) {
+	server := newTestSFTPServer(t)
+	other := newTestSFTPServer(t)
+	emptyKnownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	if err := ioutil.WriteFile(emptyKnownHosts, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	source := "./testdata/source-file01"
+	tests := []struct {
+		name        string
+		raw         map[string]interface{}
+		wantSummary string
+	}{
+		{
+			name:        "connection refused",
+			raw:         map[string]interface{}{"port": closedPort},
+			wantSummary: "could not connect to the SFTP server",
+		},
+		{
+			name:        "wrong password",
+			raw:         map[string]interface{}{"password": "wrong"},
+			wantSummary: "SFTP authentication failed",
+		},
+		{
+			name:        "unknown host",
+			raw:         map[string]interface{}{"known_hosts": emptyKnownHosts},
+			wantSummary: "the SFTP server host key is unknown",
+		},
+		{
+			name:        "host key mismatch",
+			raw:         map[string]interface{}{"known_hosts": rewriteKnownHostsAddr(t, other, server)},
+			wantSummary: "the SFTP server host key does not match known_hosts",
+		},
+		{
+			name:        "missing remote file",
+			raw:         map[string]interface{}{"path": filepath.Join(t.TempDir(), "missing")},
+			wantSummary: "the remote file does not exist",
+		},
+		{
+			name:        "invalid private key",
+			raw:         map[string]interface{}{"private_key": "not a key"},
+			wantSummary: "private_key could not be parsed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := server.raw(source, filepath.Join(t.TempDir(), "dest"))
+			for k, v := range tt.raw {
+				raw[k] = v
+			}
+			data := schema.TestResourceDataRaw(t, resourceSFTPSchema(), raw)
+			diags := resourceSFTPCreate(context.Background(), data, nil)
+			if !diags.HasError() {
+				t.Fatal("expected an error")
+			}
+			if diags[0].Summary != tt.wantSummary {
+				t.Errorf("summary = %q (%s), want %q", diags[0].Summary, diags[0].Detail, tt.wantSummary)
+			}
+			if data.Id() != "" {
+				t.Errorf("id = %q, want it unset", data.Id())
+			}
+		})
+	}
+}
+
+// rewriteKnownHostsAddr returns a known_hosts file listing the host key of
+// key under the address of addr.
+func rewriteKnownHostsAddr(t *testing.T, key, addr *testSFTPServer) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	hostport := net.JoinHostPort(addr.host, strconv.Itoa(addr.port))
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostport)}, key.hostKey.PublicKey())
+	if err := ioutil.WriteFile(path, []byte(line+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResourceSFTPRead_remoteChanged(t *testing.T) {
+	server := newTestSFTPServer(t)
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	dest := filepath.Join(dir, "dest")
+	if err := ioutil.WriteFile(source, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceSFTPSchema(), server.raw(source, dest))
+	if diags := resourceSFTPCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("create: %v", diags)
+	}
+	if diags := resourceSFTPRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("read: %v", diags)
+	}
+	if got := data.Get("content_sha256").(string); got != hashHello {
+		t.Fatalf("content_sha256 = %q after an unchanged read, want %q", got, hashHello)
+	}
+	if err := ioutil.WriteFile(source, []byte("goodbye"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(source, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceSFTPRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("read: %v", diags)
+	}
+	b, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "goodbye" {
+		t.Errorf("destination = %q, want %q", b, "goodbye")
+	}
+	if got := data.Get("content_sha256").(string); got != hashGoodbye {
+		t.Errorf("content_sha256 = %q, want %q", got, hashGoodbye)
+	}
+	if data.Id() == "" {
+		t.Error("the resource was removed from state")
+	}
+}
+
+func TestResourceSFTPRead_localDrift(t *testing.T) {
+	server := newTestSFTPServer(t)
+	dest := filepath.Join(t.TempDir(), "dest")
+	data := schema.TestResourceDataRaw(t, resourceSFTPSchema(), server.raw("./testdata/source-file01", dest))
+	if diags := resourceSFTPCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("create: %v", diags)
+	}
+	if err := ioutil.WriteFile(dest, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceSFTPRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("read: %v", diags)
+	}
+	if data.Id() != "" {
+		t.Errorf("id = %q, want it unset so the file is recreated", data.Id())
+	}
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"os"
+	"path/filepath"
+)
+
+func resourceSymlink() *schema.Resource {
+	return &schema.Resource{
+		ReadContext:   resourceSymlinkRead,
+		CreateContext: resourceSymlinkCreate,
+		UpdateContext: resourceSymlinkUpdate,
+		DeleteContext: resourceSymlinkDelete,
+		CustomizeDiff: func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+			return configFromMeta(m).checkAllowedRootLink(diff, "link_path")
+		},
+		Schema: resourceSymlinkSchema(),
+	}
+}
+
+func resourceSymlinkSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"target": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "Path the link points to. It is stored as given, so a relative target is relative to the directory of link_path. The target does not need to exist.",
+		},
+		"link_path": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "Path of the symlink to create",
+		},
+		"force": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Replace an existing file or link at link_path",
+		},
+		"resolved_target": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Absolute path the link points to",
+		},
+	}
+}
+
+// resolveLinkTarget returns the absolute path of target, which is relative
+// to the directory of link when it is not absolute.
+func resolveLinkTarget(link, target string) (string, error) {
+	if filepath.IsAbs(target) {
+		return filepath.Clean(target), nil
+	}
+	return filepath.Abs(filepath.Join(filepath.Dir(link), target))
+}
+
+func resourceSymlinkCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootLinkApply(data, "link_path"); err != nil {
+		return diag.FromErr(err)
+	}
+	link := configFromMeta(m).resolvePath(data.Get("link_path").(string))
+	target := data.Get("target").(string)
+	if data.Get("force").(bool) {
+		stat, err := os.Lstat(link)
+		if err == nil && stat.IsDir() {
+			return diag.Errorf("could not replace %q: it is a directory", link)
+		}
+		if err == nil {
+			if err := os.Remove(link); err != nil {
+				return diag.FromErr(fmt.Errorf("could not remove %q: %w", link, err))
+			}
+		}
+	}
+	if err := os.Symlink(target, link); err != nil {
+		return diag.FromErr(fmt.Errorf("could not create symlink %q: %w", link, err))
+	}
+	id, err := fileToID(link)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	return resourceSymlinkRead(ctx, data, m)
+}
+
+func resourceSymlinkUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootLinkApply(data, "link_path"); err != nil {
+		return diag.FromErr(err)
+	}
+	// only force can change in place, and it only applies on create
+	return resourceSymlinkRead(ctx, data, m)
+}
+
+func resourceSymlinkRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	link, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	stat, err := os.Lstat(link)
+	if os.IsNotExist(err) || (err == nil && stat.Mode()&os.ModeSymlink == 0) {
+		// removed, or replaced by something that is not a link
+		data.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not stat %q: %w", link, err))
+	}
+	target, err := os.Readlink(link)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not read symlink %q: %w", link, err))
+	}
+	resolved, err := resolveLinkTarget(link, target)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.Set("target", target)
+	data.Set("resolved_target", resolved)
+	return nil
+}
+
+func resourceSymlinkDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	link, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	stat, err := os.Lstat(link)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not stat %q: %w", link, err))
+	}
+	if stat.Mode()&os.ModeSymlink == 0 {
+		// not the link this resource created; leave it alone
+		return nil
+	}
+	if err := os.Remove(link); err != nil {
+		return diag.FromErr(fmt.Errorf("could not remove symlink %q: %w", link, err))
+	}
+	return nil
+}
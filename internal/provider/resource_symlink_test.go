@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestAccResourceSymlink(t *testing.T) {
+	target, err := filepath.Abs("./testdata/source-file01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccDestroySymlink,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "synclocal" {
+}
+
+resource "synclocal_symlink" "link" {
+	target    = "source-file01"
+	link_path = "./testdata/dest-symlink"
+}
+`,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("synclocal_symlink.link", "resolved_target", target),
+				),
+			},
+		},
+	})
+}
+
+func testAccDestroySymlink(s *terraform.State) error {
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "synclocal_symlink" {
+			continue
+		}
+		link, err := idToFile(rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Lstat(link); !os.IsNotExist(err) {
+			return fmt.Errorf("symlink %q was not removed", link)
+		}
+	}
+	return nil
+}
+
+func TestResourceSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks requires extra privileges on windows")
+	}
+	dir := t.TempDir()
+	link := filepath.Join(dir, "link")
+	if err := ioutil.WriteFile(link, []byte("existing"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	newData := func(force bool) *schema.ResourceData {
+		return schema.TestResourceDataRaw(t, resourceSymlinkSchema(), map[string]interface{}{
+			"target":    "missing-target",
+			"link_path": link,
+			"force":     force,
+		})
+	}
+	if diags := resourceSymlinkCreate(context.Background(), newData(false), nil); !diags.HasError() {
+		t.Fatalf("expected an error creating over an existing file without force")
+	}
+	data := newData(true)
+	if diags := resourceSymlinkCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	// a dangling link is allowed
+	if got, want := data.Get("resolved_target").(string), filepath.Join(dir, "missing-target"); got != want {
+		t.Fatalf("unexpected resolved_target %q, want %q", got, want)
+	}
+
+	// a link retargeted outside of terraform is reported through target
+	if err := os.Remove(link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("other-target", link); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceSymlinkRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected read error: %v", diags)
+	}
+	if got := data.Get("target").(string); got != "other-target" {
+		t.Fatalf("unexpected target %q", got)
+	}
+
+	if diags := resourceSymlinkDelete(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected delete error: %v", diags)
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Fatalf("expected link to be removed, got %v", err)
+	}
+	if diags := resourceSymlinkRead(context.Background(), data, nil); diags.HasError() || data.Id() != "" {
+		t.Fatalf("expected a missing link to clear the id")
+	}
+}
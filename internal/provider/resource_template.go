@@ -0,0 +1,265 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/template"
+)
+
+func resourceTemplate() *schema.Resource {
+	return &schema.Resource{
+		ReadContext:   resourceTemplateRead,
+		CreateContext: resourceTemplateCreate,
+		UpdateContext: resourceTemplateUpdate,
+		DeleteContext: resourceTemplateDelete,
+		CustomizeDiff: func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+			cfg := configFromMeta(m)
+			if err := cfg.checkAllowedRoot(diff, "destination"); err != nil {
+				return err
+			}
+			if diff.HasChange("hash_algorithm") {
+				if err := diff.SetNewComputed("content_hash"); err != nil {
+					return err
+				}
+			}
+			for _, k := range []string{"source", "template", "vars"} {
+				if !diff.NewValueKnown(k) {
+					return setContentComputed(diff)
+				}
+			}
+			// rendering at plan time reports template errors early and
+			// catches a destination that no longer matches the output
+			rendered, diags := renderTemplate(diff, cfg)
+			if diags.HasError() {
+				return diagsError(diags)
+			}
+			destHash, err := hashFile(cfg.resolvePath(diff.Get("destination").(string)))
+			if err != nil || destHash != hashBytes(rendered) {
+				return setContentComputed(diff)
+			}
+			return nil
+		},
+		Schema: resourceTemplateSchema(),
+	}
+}
+
+func resourceTemplateSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"source": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ExactlyOneOf: []string{"source", "template"},
+			Description:  "Path of the template file",
+		},
+		"template": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Inline template to render instead of source",
+		},
+		"vars": {
+			Type:        schema.TypeMap,
+			Optional:    true,
+			Description: "Variables available to the template, such as {{ .name }}",
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"missing_key": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      "error",
+			ValidateFunc: validation.StringInSlice([]string{"error", "zero", "default"}, false),
+			Description:  "What a reference to a key that is not in vars renders as: error fails, zero renders an empty string and default renders \"<no value>\"",
+		},
+		"destination": {
+			Type:        schema.TypeString,
+			Required:    true,
+			ForceNew:    true,
+			Description: "Path the rendered template is written to",
+		},
+		"file_mode": {
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateDiagFunc: validateFileMode,
+			DiffSuppressFunc: suppressEquivalentFileMode,
+			Description:      "File mode for the destination (Octal String). Defaults to the provider default_file_mode, or 0664.",
+		},
+		"content_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash of the rendered output",
+		},
+		"hash_algorithm":  hashAlgorithmSchema(false),
+		"content_hash":    contentHashSchema(),
+		"create_parents":  createParentsSchema(),
+		"dir_mode":        dirModeSchema(),
+		"keep_on_destroy": keepOnDestroySchema(),
+	}
+}
+
+// missingKeyPattern matches the text/template error for a key that is not
+// in the map, which is reported against that key of vars.
+var missingKeyPattern = regexp.MustCompile(`map has no entry for key "([^"]*)"`)
+
+// renderTemplate renders source or template with vars.
+func renderTemplate(data resourceGetter, cfg *providerConfig) ([]byte, diag.Diagnostics) {
+	text, name, attr := data.Get("template").(string), "template", "template"
+	if source := cfg.resolvePath(data.Get("source").(string)); source != "" {
+		b, err := ioutil.ReadFile(source)
+		if err != nil {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       "could not read the template",
+				Detail:        err.Error(),
+				AttributePath: cty.GetAttrPath("source"),
+			}}
+		}
+		text, name, attr = string(b), filepath.Base(source), "source"
+	}
+	tmpl, err := template.New(name).Option("missingkey=" + data.Get("missing_key").(string)).Parse(text)
+	if err != nil {
+		return nil, diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "could not parse the template",
+			Detail:        err.Error(),
+			AttributePath: cty.GetAttrPath(attr),
+		}}
+	}
+	// a map of strings, rather than interface{}, makes missingkey=zero render
+	// an empty string instead of "<no value>"
+	vars := make(map[string]string)
+	for k, v := range data.Get("vars").(map[string]interface{}) {
+		vars[k] = v.(string)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		path := cty.GetAttrPath(attr)
+		if m := missingKeyPattern.FindStringSubmatch(err.Error()); m != nil {
+			path = cty.GetAttrPath("vars").IndexString(m[1])
+		}
+		return nil, diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "could not render the template",
+			Detail:        err.Error(),
+			AttributePath: path,
+		}}
+	}
+	return buf.Bytes(), nil
+}
+
+// diagsError returns the first error in diags for functions that can only
+// return an error, such as CustomizeDiff.
+func diagsError(diags diag.Diagnostics) error {
+	for _, d := range diags {
+		if d.Severity == diag.Error {
+			return fmt.Errorf("%s: %s", d.Summary, d.Detail)
+		}
+	}
+	return nil
+}
+
+func ensureTemplateFile(data *schema.ResourceData, cfg *providerConfig) diag.Diagnostics {
+	rendered, diags := renderTemplate(data, cfg)
+	if diags.HasError() {
+		return diags
+	}
+	dest := cfg.resolvePath(data.Get("destination").(string))
+	if err := ensureParentDir(data, dest); err != nil {
+		return diag.FromErr(err)
+	}
+	mode := cfg.fileMode()
+	if v, ok := data.GetOk("file_mode"); ok {
+		m, err := parseFileMode(v.(string))
+		if err != nil {
+			return diag.Diagnostics{{
+				Severity: diag.Error,
+				Summary:  "file_mode is not a valid octal number",
+				Detail:   err.Error(),
+			}}
+		}
+		mode = m
+	}
+	err := writeFileAtomic(dest, mode, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, func(w io.Writer) error {
+		_, err := w.Write(rendered)
+		return err
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.Set("content_sha256", hashBytes(rendered))
+	return nil
+}
+
+func resourceTemplateCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination"); err != nil {
+		return diag.FromErr(err)
+	}
+	if diags := ensureTemplateFile(data, configFromMeta(m)); diags.HasError() {
+		return diags
+	}
+	id, err := fileToID(configFromMeta(m).resolvePath(data.Get("destination").(string)))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(id)
+	return resourceTemplateRead(ctx, data, m)
+}
+
+func resourceTemplateUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "destination"); err != nil {
+		return diag.FromErr(err)
+	}
+	if diags := ensureTemplateFile(data, configFromMeta(m)); diags.HasError() {
+		return diags
+	}
+	return resourceTemplateRead(ctx, data, m)
+}
+
+func resourceTemplateRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	dest, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	fileHash, err := hashFile(dest)
+	if os.IsNotExist(err) {
+		data.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	contentHash, err := contentHashWith(data, fileHash, func(newHash func() hash.Hash) (string, error) {
+		return hashFileWith(dest, newHash)
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.Set("content_sha256", fileHash)
+	data.Set("content_hash", contentHash)
+	return nil
+}
+
+func resourceTemplateDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if data.Get("keep_on_destroy").(bool) {
+		return nil
+	}
+	dest, err := idToFile(data.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return diag.FromErr(fmt.Errorf("could not remove %q: %w", dest, err))
+	}
+	return nil
+}
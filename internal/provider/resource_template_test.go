@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAccResourceTemplate(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "greeting")
+	config := func(name string) string {
+		return fmt.Sprintf(`
+provider "synclocal" {
+}
+
+resource "synclocal_template" "greeting" {
+	template    = "hello {{ .name }}\n"
+	destination = %q
+	vars = {
+		name = %q
+	}
+}
+`, dest, name)
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy: func(s *terraform.State) error {
+			if _, err := os.Stat(dest); !os.IsNotExist(err) {
+				return fmt.Errorf("destination %q was not removed", dest)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config("world"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckFileContent(dest, "hello world\n"),
+					resource.TestCheckResourceAttr("synclocal_template.greeting", "content_sha256", hashBytes([]byte("hello world\n"))),
+				),
+			},
+			{
+				Config: config("there"),
+				Check:  testAccCheckFileContent(dest, "hello there\n"),
+			},
+		},
+	})
+}
+
+func TestRenderTemplate(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "config.tmpl")
+	if err := ioutil.WriteFile(source, []byte("port={{ .port }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		raw      map[string]interface{}
+		want     string
+		wantPath cty.Path
+	}{
+		{
+			name: "template",
+			raw:  map[string]interface{}{"template": "{{ .a }}-{{ .b }}", "vars": map[string]interface{}{"a": "x", "b": "y"}},
+			want: "x-y",
+		},
+		{
+			name: "source",
+			raw:  map[string]interface{}{"source": source, "vars": map[string]interface{}{"port": "8080"}},
+			want: "port=8080",
+		},
+		{
+			name:     "missing key",
+			raw:      map[string]interface{}{"template": "{{ .a }}-{{ .b }}", "vars": map[string]interface{}{"a": "x"}},
+			wantPath: cty.GetAttrPath("vars").IndexString("b"),
+		},
+		{
+			name:     "missing key in source",
+			raw:      map[string]interface{}{"source": source},
+			wantPath: cty.GetAttrPath("vars").IndexString("port"),
+		},
+		{
+			name: "missing key zero",
+			raw:  map[string]interface{}{"template": "{{ .a }}-{{ .b }}", "vars": map[string]interface{}{"a": "x"}, "missing_key": "zero"},
+			want: "x-",
+		},
+		{
+			name:     "parse error",
+			raw:      map[string]interface{}{"template": "{{ .a "},
+			wantPath: cty.GetAttrPath("template"),
+		},
+		{
+			name:     "missing source",
+			raw:      map[string]interface{}{"source": source + ".missing"},
+			wantPath: cty.GetAttrPath("source"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.raw["destination"] = "unused"
+			data := schema.TestResourceDataRaw(t, resourceTemplateSchema(), tt.raw)
+			got, diags := renderTemplate(data, nil)
+			if tt.wantPath != nil {
+				if !diags.HasError() {
+					t.Fatalf("expected an error, rendered %q", got)
+				}
+				if !diags[0].AttributePath.Equals(tt.wantPath) {
+					t.Fatalf("unexpected attribute path %#v", diags[0].AttributePath)
+				}
+				return
+			}
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("unexpected output %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceTemplateCreate(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "sub", "out")
+	data := schema.TestResourceDataRaw(t, resourceTemplateSchema(), map[string]interface{}{
+		"template":    "{{ .a }}",
+		"vars":        map[string]interface{}{"a": "b"},
+		"destination": dest,
+	})
+	if diags := resourceTemplateCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "b" {
+		t.Fatalf("unexpected content %q", b)
+	}
+	if got := data.Get("content_sha256").(string); got != hashBytes([]byte("b")) {
+		t.Fatalf("unexpected content_sha256 %q", got)
+	}
+	if !strings.HasPrefix(data.Id(), "file://") {
+		t.Fatalf("unexpected id %q", data.Id())
+	}
+	if diags := resourceTemplateDelete(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("destination was not removed: %v", err)
+	}
+}
@@ -1,40 +1,60 @@
 package provider
 
 import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"hash"
 	"io"
 	"io/ioutil"
 	"mime"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
-	"strconv"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"time"
 )
 
 func resourceURL() *schema.Resource {
 	return &schema.Resource{
 		ReadContext:   resourceURLRead,
 		CreateContext: resourceURLCreate,
+		UpdateContext: resourceURLUpdate,
 		DeleteContext: resourceURLDelete,
-		CustomizeDiff: func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
-			return nil
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceURLImport,
 		},
-		Schema: resourceURLSchema(),
+		Timeouts:       copyTimeouts(),
+		CustomizeDiff:  resourceURLCustomizeDiff,
+		Schema:         resourceURLSchema(),
+		SchemaVersion:  1,
+		StateUpgraders: managedStateUpgraders(resourceURLSchema()),
 	}
 }
 
 func resourceURLSchema() map[string]*schema.Schema {
 	return map[string]*schema.Schema{
 		"url": {
-			Type:        schema.TypeString,
-			Required:    true,
-			ForceNew:    true,
-			Description: "source url",
+			Type:             schema.TypeString,
+			Required:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validateURL,
+			Description:      "source url. http, https and file urls are supported. A relative url is resolved against the provider base_url.",
 		},
 		"headers": {
 			Type:        schema.TypeMap,
@@ -45,17 +65,113 @@ func resourceURLSchema() map[string]*schema.Schema {
 				Type: schema.TypeString,
 			},
 		},
-		"filename": {
+		"cookies": {
+			Type:        schema.TypeMap,
+			Optional:    true,
+			ForceNew:    true,
+			Sensitive:   true,
+			Description: "cookies to send with the request, by name. They are added to any Cookie header set in headers.",
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"method": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ForceNew:     true,
+			Default:      http.MethodGet,
+			ValidateFunc: validation.StringInSlice([]string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch}, false),
+			Description:  "HTTP method used to download the url. A HEAD writes an empty file and only records the response metadata.",
+		},
+		"request_body": {
 			Type:        schema.TypeString,
-			Required:    true,
-			Description: "Destination file path",
+			Optional:    true,
 			ForceNew:    true,
+			Description: "body to send with the request",
 		},
-		"file_mode": {
+		"allow_get_body": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "allow request_body to be sent with a GET or HEAD request",
+		},
+		"user_agent": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "User-Agent sent with the request, overriding the provider user_agent",
+		},
+		"filename": {
+			Type:             schema.TypeString,
+			Optional:         true,
+			ExactlyOneOf:     []string{"filename", "extract_to"},
+			Description:      "Destination file path",
+			ForceNew:         true,
+			DiffSuppressFunc: suppressEquivalentPathCase,
+		},
+		"mirror_urls": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Description: "Mirrors to try in order when the url cannot be downloaded, returns an unexpected status, or does not match expected_sha256",
+			Elem: &schema.Schema{
+				Type:             schema.TypeString,
+				ValidateDiagFunc: validateURL,
+			},
+		},
+		"source_url": {
 			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "the url or mirror that the file was last downloaded from",
+		},
+		"extract": {
+			Type:        schema.TypeBool,
 			Optional:    true,
+			Default:     false,
 			ForceNew:    true,
-			Description: "File mode for the destination (Octal String). Mirrors the source file if not provided.",
+			Description: "Extract the downloaded tar, tar.gz or zip archive into extract_to instead of writing it to filename",
+		},
+		"extract_to": {
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			Description:      "Directory to extract the archive into when extract is set. Its contents are replaced on every download.",
+			DiffSuppressFunc: suppressEquivalentPathCase,
+		},
+		"expected_sha256": {
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"checksum_url"},
+			Description:   "Fail the download if the SHA256 hash of the response body does not match. When extracting, the archive is checked before it replaces extract_to.",
+		},
+		"accept": acceptSchema(),
+		"expected_content_type": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validateMediaType,
+			Description:  "Fail the download if the Content-Type of the response is a different media type, such as an HTML error page instead of an archive. Parameters are ignored and a structured suffix matches its base type, so application/vnd.api+json matches application/json. file urls are not checked.",
+		},
+		"checksum_url": {
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateDiagFunc: validateURL,
+			Description:      "URL of a checksum file, such as a .sha256 or SHA256SUMS file in sha256sum format, that lists the SHA256 hash of the url. It is fetched with the same headers and auth before each download, and the download fails if it does not match, like expected_sha256.",
+		},
+		"checksum_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash read from checksum_url for the last download",
+		},
+		"extracted_sha256": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "SHA256 hash over the relative path and hash of every extracted entry",
+		},
+		"file_mode": {
+			Type:             schema.TypeString,
+			Optional:         true,
+			ForceNew:         true,
+			ValidateDiagFunc: validateFileMode,
+			DiffSuppressFunc: suppressEquivalentFileMode,
+			Description:      "File mode for the destination (Octal String). Defaults to the provider default_file_mode, or 0664.",
 		},
 		"last_modified": {
 			Type:        schema.TypeString,
@@ -72,10 +188,383 @@ func resourceURLSchema() map[string]*schema.Schema {
 			Computed:    true,
 			Description: "SHA256 hash of the file contents",
 		},
+		"hash_algorithm": hashAlgorithmSchema(true),
+		"content_hash":   contentHashSchema(),
+		"content_type": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "the Content-Type of the last successful response",
+		},
+		"content_length": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "the Content-Length of the last successful response, or -1 if the server did not send one",
+		},
+		"status_code": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "the HTTP status code of the last successful download",
+		},
+		"managed": managedSchema(),
+		"bytes_written": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "the number of bytes of the response body written by the last download. A 304 Not Modified response keeps the previous value.",
+		},
+		"download_duration_ms": {
+			Type:        schema.TypeInt,
+			Computed:    true,
+			Description: "the time in milliseconds the last download took to write the response body",
+		},
+		"response_headers": {
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Description: "the headers of the last successful response. Repeated headers are joined with a comma.",
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"aws_sigv4": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Description: "Sign requests with AWS Signature Version 4, for example to download private S3 objects",
+			Elem: &schema.Resource{
+				Schema: awsSigV4Schema(),
+			},
+		},
+		"oauth2": {
+			Type:          schema.TypeList,
+			Optional:      true,
+			MaxItems:      1,
+			ConflictsWith: []string{"aws_sigv4"},
+			Description:   "Send a bearer token fetched from an OAuth2 token endpoint with the client credentials grant. Tokens are reused by resources with the same client until they expire.",
+			Elem: &schema.Resource{
+				Schema: oauth2Schema(),
+			},
+		},
+		"insecure_skip_verify": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Skip TLS certificate verification. Only use this for endpoints with self-signed certificates.",
+		},
+		"unix_socket": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Description: "Path of a unix domain socket to send requests to, such as /var/run/docker.sock. The host of the url is only sent as the Host header, so a url like http://unix/path can be used. proxy_url is not used for these requests.",
+		},
+		"resolve": {
+			Type:          schema.TypeMap,
+			Optional:      true,
+			ValidateFunc:  validateResolve,
+			ConflictsWith: []string{"unix_socket"},
+			Description:   "Connect to a host:port at the given IP address instead of resolving it with DNS, like the --resolve option of curl, such as { \"example.com:443\" = \"10.0.0.5\" }. An IPv6 host in a key is written in brackets, such as [::1]:443, and an IPv6 address value without them. The url host is still sent and used to verify TLS certificates. Other hosts are resolved as usual.",
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"skip_matching_destination": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     true,
+			Description: "Do not download the url when the destination already has the hash of expected_sha256, or of checksum_url, for example when the state was lost. Not used with extract, force_download or exclusive_create.",
+		},
+		"force_download": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Download the url on every refresh instead of sending If-None-Match and If-Modified-Since, for servers whose validators cannot be trusted",
+		},
+		"preserve_timestamps": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Set the modification time of filename to the Last-Modified date sent by the server",
+		},
+		"validate":    validateSchema(),
+		"range_start": rangeStartSchema(),
+		"range_end":   rangeEndSchema(),
+		"etag_is_sha256": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "The server sends the hex SHA256 of the content as its ETag. Before downloading, a HEAD request is made, and the download is skipped if its ETag is content_sha256 and the destination still has that hash. Not used with extract, force_download or a method other than GET.",
+		},
+		"normalize_path_case": normalizePathCaseSchema(),
+		"create_parents":      createParentsSchema(),
+		"dir_mode":            dirModeSchema(),
+		"keep_on_destroy":     keepOnDestroySchema(),
+		"no_follow":           noFollowSchema(),
+		"exclusive_create":    exclusiveCreateSchema(),
+		"max_redirects": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      10,
+			ValidateFunc: validation.IntAtLeast(0),
+			Description:  "Maximum number of redirects to follow. Set to 0 to disallow redirects.",
+		},
+		"max_bytes": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      0,
+			ValidateFunc: validation.IntAtLeast(0),
+			Description:  "Fail the download if the response body is larger than this many bytes. 0 means no limit.",
+		},
+		"parallel_chunks": {
+			Type:         schema.TypeInt,
+			Optional:     true,
+			Default:      1,
+			ValidateFunc: validation.IntBetween(1, 64),
+			Description:  "Download the url with this many parallel ranged requests, for large files over high latency links. Ranges are only used for a GET when the server sends Accept-Ranges: bytes and a Content-Length, and not with extract or a Content-Encoding; otherwise the url is downloaded as a single stream. Each range counts against the provider max_concurrent_requests.",
+		},
+		"timeout":     timeoutSchema(),
+		"max_retries": maxRetriesSchema(),
+		"retry_wait":  retryWaitSchema(),
+		"allowed_status_codes": {
+			Type:        schema.TypeList,
+			Optional:    true,
+			Description: "Status codes other than 200 to treat as success, writing the response body to the destination. A listed code is not retried, even a 429 or 5xx. A 304 is still handled as not modified.",
+			Elem: &schema.Schema{
+				Type:         schema.TypeInt,
+				ValidateFunc: validation.IntBetween(100, 599),
+			},
+		},
+		"decompress": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     true,
+			Description: "Decode the response body according to its Content-Encoding header before writing it, so the file and content_sha256 reflect the decoded content.",
+		},
+		"plan_time_check": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Check the url for changes during plan with a conditional HEAD request, so that an update shows up in the plan instead of being downloaded during refresh.",
+		},
+		"read_strategy": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validation.StringInSlice(readStrategies, false),
+			Description:  "How a refresh checks the file, instead of downloading the url again: stat only checks that it exists, hash also checks that its content_sha256 has not changed, and remote also sends a conditional HEAD request so the next apply downloads an updated url. Defaults to the provider read_strategy. Without either, a refresh downloads the url again with conditional headers, except for a POST, PUT or PATCH, which is only checked like hash.",
+		},
+		"remote_changed": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Set by a remote read_strategy when the url changed since it was downloaded. The next apply downloads it again.",
+		},
+		"precheck": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+			Description: "Send a HEAD request to the url and mirror_urls during plan, and warn for each that is unreachable or does not return a 2xx status. The plan does not fail. For an existing resource the warnings are shown by the refresh of the plan. For a new resource, or a changed url or mirror_urls, they are only written to the Terraform log, shown with TF_LOG=WARN.",
+		},
 	}
 }
 
+// resourceGetter is implemented by both *schema.ResourceData and
+// *schema.ResourceDiff so requests can be built during apply or plan.
+type resourceGetter interface {
+	Id() string
+	Get(key string) interface{}
+	GetOk(key string) (interface{}, bool)
+}
+
+func resourceURLCustomizeDiff(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if err := configFromMeta(m).checkAllowedRoot(diff, "filename", "extract_to"); err != nil {
+		return err
+	}
+	if err := validateRequestBody(diff); err != nil {
+		return err
+	}
+	if err := validateExtract(diff); err != nil {
+		return err
+	}
+	if err := validateByteRange(diff); err != nil {
+		return err
+	}
+	if diff.NewValueKnown("url") {
+		if err := configFromMeta(m).checkRelativeURL(diff.Get("url").(string)); err != nil {
+			return err
+		}
+		// an existing url is checked by Read, which can return warnings
+		if diff.Get("precheck").(bool) && diff.NewValueKnown("mirror_urls") && (diff.Id() == "" || diff.HasChange("url") || diff.HasChange("mirror_urls")) {
+			logPrecheck(precheckURLs(ctx, diff, configFromMeta(m)))
+		}
+	}
+	if diff.Id() != "" && diff.Get("remote_changed").(bool) {
+		// found by a remote read_strategy refresh
+		return setDownloadComputed(diff)
+	}
+	if diff.Id() == "" || !diff.Get("plan_time_check").(bool) || !isConditionalMethod(diff.Get("method").(string)) {
+		return nil
+	}
+	changed, err := remoteChanged(diff, configFromMeta(m))
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	return setDownloadComputed(diff)
+}
+
+// setDownloadComputed plans an update that downloads the url again.
+func setDownloadComputed(diff *schema.ResourceDiff) error {
+	if err := diff.SetNew("remote_changed", false); err != nil {
+		return err
+	}
+	for _, key := range []string{"etag", "last_modified", "content_sha256", "content_hash", "content_type", "content_length", "status_code", "bytes_written", "download_duration_ms", "response_headers", "source_url"} {
+		if err := diff.SetNewComputed(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRequestBody rejects a request_body on GET and HEAD requests unless
+// allow_get_body is set.
+func validateRequestBody(data resourceGetter) error {
+	method := data.Get("method").(string)
+	if _, ok := data.GetOk("request_body"); ok && isConditionalMethod(method) && !data.Get("allow_get_body").(bool) {
+		return fmt.Errorf("request_body cannot be sent with a %s request unless allow_get_body is set", method)
+	}
+	return nil
+}
+
+// validateExtract checks that extract is set exactly when extract_to is.
+func validateExtract(data resourceGetter) error {
+	_, hasExtractTo := data.GetOk("extract_to")
+	extract := data.Get("extract").(bool)
+	if extract && !hasExtractTo {
+		return fmt.Errorf("extract requires extract_to to be set instead of filename")
+	}
+	if !extract && hasExtractTo {
+		return fmt.Errorf("extract_to can only be used with extract = true")
+	}
+	return nil
+}
+
+// validateURL rejects urls that cannot be downloaded. Relative urls are
+// allowed here and checked against base_url in CustomizeDiff.
+func validateURL(v interface{}, path cty.Path) diag.Diagnostics {
+	raw, ok := v.(string)
+	if !ok {
+		return diag.Diagnostics{{Severity: diag.Error, Summary: "expected a string", AttributePath: path}}
+	}
+	invalid := func(detail string) diag.Diagnostics {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "invalid url",
+			Detail:        detail,
+			AttributePath: path,
+		}}
+	}
+	if raw == "" {
+		return invalid("url must not be empty")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return invalid(fmt.Sprintf("%q is not a valid url: %s", raw, err))
+	}
+	if !u.IsAbs() {
+		return nil
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+	case "file":
+		if _, err := fileURLPath(u); err != nil {
+			return invalid(err.Error())
+		}
+		return nil
+	default:
+		return invalid(fmt.Sprintf("%q has unsupported scheme %q. Only http, https and file are supported.", raw, u.Scheme))
+	}
+	if u.Host == "" {
+		return invalid(fmt.Sprintf("%q has no host", raw))
+	}
+	return nil
+}
+
+// isConditionalMethod reports whether conditional request headers apply to
+// method.
+func isConditionalMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// remoteChanged issues a conditional HEAD request using the stored etag and
+// last_modified values and reports whether the remote content has changed.
+// A file:// url is compared against content_sha256 instead.
+func remoteChanged(data resourceGetter, cfg *providerConfig) (bool, error) {
+	if source, ok, err := localSource(data.Get("url").(string), cfg); err != nil {
+		return false, err
+	} else if ok {
+		sourceHash, err := hashFile(source)
+		if err != nil {
+			return false, fmt.Errorf("could not read %q: %w", source, err)
+		}
+		return sourceHash != data.Get("content_sha256").(string), nil
+	}
+	req, err := makeRequest(http.MethodHead, data, cfg)
+	if err != nil {
+		return false, err
+	}
+	c, diags := newHTTPClient(data, cfg)
+	if diags.HasError() {
+		return false, fmt.Errorf("%s", diags[0].Summary)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("error making request to %q: %w", req.URL, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return false, nil
+	case http.StatusOK:
+		// If-None-Match uses the weak comparison function, so a server that
+		// ignored it but still reports an equivalent validator is unchanged
+		if stored, ok := data.GetOk("etag"); ok {
+			if etag := resp.Header.Get("ETag"); etag != "" && etagWeakMatch(stored.(string), etag) {
+				return false, nil
+			}
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("plan time check of %q returned an unexpected response code: %s", req.URL, resp.Status)
+	}
+}
+
+// normalizeETag splits an entity tag into its opaque tag (including quotes)
+// and whether it carries the W/ weak indicator.
+func normalizeETag(etag string) (tag string, weak bool) {
+	etag = strings.TrimSpace(etag)
+	if strings.HasPrefix(etag, "W/") {
+		return etag[2:], true
+	}
+	return etag, false
+}
+
+// etagStrongMatch compares two entity tags using the strong comparison
+// function of RFC 7232 section 2.3.2: both must be strong and identical.
+func etagStrongMatch(a, b string) bool {
+	at, aw := normalizeETag(a)
+	bt, bw := normalizeETag(b)
+	return !aw && !bw && at != "" && at == bt
+}
+
+// etagWeakMatch compares two entity tags using the weak comparison function
+// of RFC 7232 section 2.3.2: the opaque tags must match, either may be weak.
+func etagWeakMatch(a, b string) bool {
+	at, _ := normalizeETag(a)
+	bt, _ := normalizeETag(b)
+	return at != "" && at == bt
+}
+
 func resourceURLDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	if data.Get("keep_on_destroy").(bool) {
+		return nil
+	}
 	id := data.Id()
 	name, err := idToFile(id)
 	if err != nil {
@@ -88,18 +577,70 @@ func resourceURLDelete(ctx context.Context, data *schema.ResourceData, m interfa
 	if err != nil {
 		return diag.FromErr(fmt.Errorf("could not stat file %q: %w", name, err))
 	}
+	// content_sha256 of an extracted archive is the hash of the archive
+	if diags := keepOnDelete(data, name, !data.Get("extract").(bool)); diags != nil {
+		return diags
+	}
+	if data.Get("extract").(bool) {
+		if err := os.RemoveAll(name); err != nil {
+			return diag.FromErr(fmt.Errorf("could not remove directory %q: %w", name, err))
+		}
+		return nil
+	}
 	if err := os.Remove(name); err != nil {
 		return diag.FromErr(fmt.Errorf("could not remove file %q: %w", name, err))
 	}
 	return nil
 }
 
+// read_strategy values
+const (
+	readStrategyStat   = "stat"
+	readStrategyHash   = "hash"
+	readStrategyRemote = "remote"
+)
+
+var readStrategies = []string{readStrategyStat, readStrategyHash, readStrategyRemote}
+
+// readStrategy returns the read_strategy of data, or the provider default.
+// It is empty if neither is set.
+func (c *providerConfig) readStrategy(data resourceGetter) string {
+	if v, ok := data.GetOk("read_strategy"); ok {
+		return v.(string)
+	}
+	if c == nil {
+		return ""
+	}
+	return c.defaultReadStrategy
+}
+
 func resourceURLRead(ctx context.Context, data *schema.ResourceData, m interface{}) (diags diag.Diagnostics) {
 	file, err := idToFile(data.Id())
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	_, err = os.Stat(file)
+	defer func() {
+		if !diags.HasError() && data.Id() != "" {
+			diags = append(diags, readPrecheck(ctx, data, configFromMeta(m))...)
+		}
+	}()
+	strategy := configFromMeta(m).readStrategy(data)
+	if strategy == readStrategyStat {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			data.SetId("")
+			return nil
+		} else if err != nil {
+			return diag.FromErr(err)
+		}
+		return nil
+	}
+	// an extracted archive is compared by its tree, since the archive itself
+	// is not kept
+	hashKey := "content_sha256"
+	if data.Get("extract").(bool) {
+		hashKey = "extracted_sha256"
+	}
+	fileHash, err := hashPath(file, data.Get("extract").(bool))
 	if os.IsNotExist(err) {
 		data.SetId("")
 		return nil
@@ -107,32 +648,130 @@ func resourceURLRead(ctx context.Context, data *schema.ResourceData, m interface
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	mode, err := getFileMode(data)
+	if stored, ok := data.GetOk(hashKey); ok && stored.(string) != fileHash {
+		// the file was modified outside of terraform; recreate it
+		data.SetId("")
+		return nil
+	}
+	switch strategy {
+	case readStrategyHash:
+		return nil
+	case readStrategyRemote:
+		return readRemoteChanged(data, configFromMeta(m))
+	}
+	if data.Get("plan_time_check").(bool) {
+		// changes are detected by CustomizeDiff and downloaded on update
+		return nil
+	}
+	if data.Get("url").(string) == "" {
+		// imported; the url is not known until the next apply
+		return nil
+	}
+	if !isConditionalMethod(data.Get("method").(string)) {
+		// a POST, PUT or PATCH cannot be made conditional, so refresh does
+		// not send it again; the local file is checked above
+		return nil
+	}
+	mode, err := getFileMode(data, configFromMeta(m))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return timeoutDiagnostics(ctx, ensureDownloadFile(ctx, data, mode, configFromMeta(m)), data, schema.TimeoutRead)
+}
+
+// readRemoteChanged sets remote_changed if the url changed since it was
+// downloaded, so the next plan downloads it again.
+func readRemoteChanged(data *schema.ResourceData, cfg *providerConfig) diag.Diagnostics {
+	if data.Get("url").(string) == "" || !isConditionalMethod(data.Get("method").(string)) {
+		// imported, or a request that cannot be checked with HEAD
+		return nil
+	}
+	changed, err := remoteChanged(data, cfg)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	return ensureDownloadFile(data, mode)
+	if changed {
+		logEvent("INFO", "url changed since it was downloaded", "url", data.Get("url").(string))
+	}
+	data.Set("remote_changed", changed)
+	return nil
+}
+
+// resourceURLImport adopts an existing file given by its path or file:// ID.
+// The url is not known at import, so the next apply replaces the file.
+func resourceURLImport(ctx context.Context, data *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	path, err := importPath(data.Id(), configFromMeta(m))
+	if err != nil {
+		return nil, err
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not import %q: %w", path, err)
+	}
+	id, err := fileToID(path)
+	if err != nil {
+		return nil, err
+	}
+	setImportDefaults(data, resourceURLSchema())
+	data.SetId(id)
+	data.Set("filename", path)
+	data.Set("content_sha256", hash)
+	data.Set("content_hash", hash)
+	data.Set("managed", false)
+	return []*schema.ResourceData{data}, nil
 }
 
 func resourceURLCreate(ctx context.Context, data *schema.ResourceData, m interface{}) (diags diag.Diagnostics) {
-	mode, err := getFileMode(data)
+	if err := configFromMeta(m).checkAllowedRootApply(data, "filename", "extract_to"); err != nil {
+		return diag.FromErr(err)
+	}
+	mode, err := getFileMode(data, configFromMeta(m))
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	diags = ensureDownloadFile(data, mode)
+	diags = timeoutDiagnostics(ctx, ensureDownloadFile(ctx, data, mode, configFromMeta(m)), data, schema.TimeoutCreate)
 	if diags.HasError() {
 		return diags
 	}
-	id, err := fileToID(data.Get("filename").(string))
+	id, err := pathToID(data, destinationKey(data), configFromMeta(m))
 	if err != nil {
 		return diag.FromErr(err)
 	}
 	data.SetId(id)
+	data.Set("managed", true)
 	return
 }
 
-func makeRequest(method string, data *schema.ResourceData) (*http.Request, error) {
-	source := data.Get("url").(string)
+func resourceURLUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) (diags diag.Diagnostics) {
+	if err := configFromMeta(m).checkAllowedRootApply(data, "filename", "extract_to"); err != nil {
+		return diag.FromErr(err)
+	}
+	mode, err := getFileMode(data, configFromMeta(m))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	previous, _ := data.GetChange("content_sha256")
+	diags = timeoutDiagnostics(ctx, ensureDownloadFile(ctx, data, mode, configFromMeta(m)), data, schema.TimeoutUpdate)
+	if !diags.HasError() {
+		data.Set("remote_changed", false)
+		setManaged(data, previous.(string))
+	}
+	return diags
+}
+
+// makeRequest builds the request for the url. Provider default_headers are
+// sent unless the resource sets a header of the same name.
+func makeRequest(method string, data resourceGetter, cfg *providerConfig) (*http.Request, error) {
+	return makeRequestURL(method, data.Get("url").(string), data, cfg)
+}
+
+// makeRequestURL builds the request like makeRequest, but for rawURL instead
+// of the url attribute.
+func makeRequestURL(method, rawURL string, data resourceGetter, cfg *providerConfig) (*http.Request, error) {
+	source, err := cfg.resolveURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
 	var etag, modified string
 	if v, ok := data.GetOk("etag"); ok {
 		etag = v.(string)
@@ -140,70 +779,439 @@ func makeRequest(method string, data *schema.ResourceData) (*http.Request, error
 	if v, ok := data.GetOk("last_modified"); ok {
 		modified = v.(string)
 	}
-	req, err := http.NewRequest(method, source, nil)
+	var body io.Reader
+	if v, ok := data.GetOk("request_body"); ok {
+		body = strings.NewReader(v.(string))
+	}
+	req, err := http.NewRequest(method, source, body)
 	if err != nil {
 		return nil, err
 	}
+	if cfg != nil {
+		if cfg.userAgent != "" {
+			req.Header.Set("User-Agent", cfg.userAgent)
+		}
+		for k, v := range cfg.defaultHeaders {
+			value, err := cfg.expandHeader(k, v)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set(k, value)
+		}
+	}
 	if v, ok := data.GetOk("headers"); ok {
 		m := v.(map[string]interface{})
 		for k, v := range m {
-			req.Header.Set(k, v.(string))
+			value, err := cfg.expandHeader(k, v.(string))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set(k, value)
 		}
 	}
-	if etag != "" {
-		req.Header.Set("If-None-Match", etag)
-	} else {
+	setAccept(req, data)
+	setRange(req, data)
+	if v, ok := data.GetOk("cookies"); ok {
+		m := v.(map[string]interface{})
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		// a stable order keeps the header the same between runs
+		sort.Strings(names)
+		for _, name := range names {
+			req.AddCookie(&http.Cookie{Name: name, Value: m[name].(string)})
+		}
+	}
+	if v, ok := data.GetOk("user_agent"); ok {
+		req.Header.Set("User-Agent", v.(string))
+	}
+	force := false
+	if v, ok := data.GetOk("force_download"); ok {
+		force = v.(bool)
+	}
+	if isConditionalMethod(method) && !force {
+		// send both validators when known; servers that ignore ETags may
+		// still honor the date (RFC 7232 section 6)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
 		if modified != "" {
 			req.Header.Set("If-Modified-Since", modified)
 		}
 	}
+	// signing and oauth2 set the Authorization header, so netrc is not used
+	// with them
+	if err := signRequest(req, data); err != nil {
+		return nil, err
+	}
+	if err := setOAuth2Token(req, data, cfg); err != nil {
+		return nil, err
+	}
+	cfg.setNetrcAuth(req)
 	return req, nil
 }
 
-func getFileMode(data *schema.ResourceData) (os.FileMode, error) {
+// httpDateFormats are the date formats accepted in a Last-Modified header.
+// Besides the formats of http.ParseTime, some servers send a numeric zone.
+var httpDateFormats = []string{
+	http.TimeFormat,
+	time.RFC1123,
+	time.RFC1123Z,
+	time.RFC850,
+	time.ANSIC,
+}
+
+// parseHTTPDate parses an HTTP date in any of httpDateFormats.
+func parseHTTPDate(v string) (t time.Time, err error) {
+	for _, layout := range httpDateFormats {
+		t, err = time.Parse(layout, strings.TrimSpace(v))
+		if err == nil {
+			return t, nil
+		}
+	}
+	return t, err
+}
+
+// normalizeHTTPDate rewrites an HTTP date in the preferred http.TimeFormat,
+// so a server that varies its date format does not cause a diff. Dates
+// that cannot be parsed are returned as they are.
+func normalizeHTTPDate(v string) string {
+	t, err := parseHTTPDate(v)
+	if err != nil {
+		return v
+	}
+	return t.UTC().Format(http.TimeFormat)
+}
+
+// setLastModified sets the modification time of filename to the date in a
+// Last-Modified header. It does nothing if the header is missing or invalid.
+func setLastModified(filename, lastModified string) error {
+	if lastModified == "" {
+		return nil
+	}
+	t, err := parseHTTPDate(lastModified)
+	if err != nil {
+		return nil
+	}
+	if err := os.Chtimes(filename, t, t); err != nil {
+		return fmt.Errorf("could not set times of %q: %w", filename, err)
+	}
+	return nil
+}
+
+// getFileMode returns the file_mode of data, or the provider default.
+func getFileMode(data *schema.ResourceData, cfg *providerConfig) (os.FileMode, error) {
 	if v, ok := data.GetOk("file_mode"); ok {
-		m, err := strconv.ParseUint(v.(string), 8, 32)
+		m, err := parseFileMode(v.(string))
 		if err != nil {
 			return 0, fmt.Errorf("file_mode is not a valid octal number")
 		}
-		return os.FileMode(m), nil
+		return m, nil
+	}
+	return cfg.fileMode(), nil
+}
+
+func newHTTPClient(data resourceGetter, cfg *providerConfig) (*http.Client, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	settings, err := cfg.httpSettings(data)
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+	transport := cfg.httpTransport()
+	v, hasSocket := data.GetOk("unix_socket")
+	overrides := resolveOverrides(data)
+	insecure := data.Get("insecure_skip_verify").(bool)
+	if hasSocket || len(overrides) > 0 || insecure {
+		// connections that are dialed or verified differently are not
+		// shared with other resources
+		transport = transport.Clone()
+	}
+	if hasSocket {
+		socket := cfg.resolvePath(v.(string))
+		var dialer net.Dialer
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		// a proxy would be dialed through the socket instead of the server
+		transport.Proxy = nil
+	}
+	if len(overrides) > 0 {
+		transport.DialContext = resolveDialer(transport.DialContext, overrides)
+	}
+	if insecure {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "TLS certificate verification is disabled",
+			Detail:   fmt.Sprintf("insecure_skip_verify is set for %q. The server certificate will not be verified.", data.Get("url").(string)),
+		})
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+	maxRedirects := data.Get("max_redirects").(int)
+	var base http.RoundTripper = transport
+	if cfg != nil && cfg.requests != nil {
+		// below the retries, so a slot is not held while waiting to retry
+		base = &limitTransport{base: transport, limiter: cfg.requests}
+	}
+	return &http.Client{
+		Timeout: settings.timeout,
+		Transport: &retryTransport{
+			base:       base,
+			maxRetries: settings.maxRetries,
+			wait:       settings.retryWait,
+			allowed:    allowedStatusCodes(data),
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if maxRedirects == 0 {
+				// hand the redirect response back so the location can be reported
+				return http.ErrUseLastResponse
+			}
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	}, diags
+}
+
+// allowedStatusCodes returns the set of allowed_status_codes, which is empty
+// for resources without it.
+func allowedStatusCodes(data resourceGetter) map[int]bool {
+	v, ok := data.GetOk("allowed_status_codes")
+	if !ok {
+		return nil
+	}
+	codes := make(map[int]bool)
+	for _, code := range v.([]interface{}) {
+		codes[code.(int)] = true
+	}
+	return codes
+}
+
+// destinationMatches reports whether the destination already has the
+// expected hash, so the download can be skipped. The content hashes are set
+// as if it was downloaded. A destination that is missing or cannot be read
+// is downloaded as usual.
+func destinationMatches(data *schema.ResourceData, mode os.FileMode, cfg *providerConfig) (bool, error) {
+	expected := data.Get("expected_sha256").(string)
+	if expected == "" {
+		expected = data.Get("checksum_sha256").(string)
+	}
+	if expected == "" || !data.Get("skip_matching_destination").(bool) || data.Get("extract").(bool) || data.Get("force_download").(bool) {
+		return false, nil
+	}
+	dest := cfg.resolvePath(data.Get(destinationKey(data)).(string))
+	// the download reports a destination that cannot be written
+	if flag, err := openFlags(data, dest); err != nil || flag&os.O_EXCL != 0 {
+		return false, nil
+	}
+	fileHash, err := hashFile(dest)
+	if err != nil || !strings.EqualFold(fileHash, expected) {
+		return false, nil
+	}
+	contentHash, err := contentHashWith(data, fileHash, func(newHash func() hash.Hash) (string, error) {
+		return hashFileWith(dest, newHash)
+	})
+	if err != nil {
+		return false, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(dest, mode); err != nil {
+			return false, fmt.Errorf("failed to chmod %s %q: %w", mode, dest, err)
+		}
+	}
+	logEvent("INFO", "destination already matches, skipping download", "url", data.Get("url").(string), "destination", dest)
+	data.Set("content_sha256", fileHash)
+	data.Set("content_hash", contentHash)
+	data.Set("source_url", data.Get("url").(string))
+	return true, nil
+}
+
+func ensureDownloadFile(ctx context.Context, data *schema.ResourceData, mode os.FileMode, cfg *providerConfig) (diags diag.Diagnostics) {
+	checksum := ""
+	if _, ok := data.GetOk("checksum_url"); ok {
+		if checksum, diags = fetchChecksum(ctx, data, cfg); diags.HasError() {
+			return diags
+		}
+	}
+	data.Set("checksum_sha256", checksum)
+	if skip, err := destinationMatches(data, mode, cfg); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	} else if skip {
+		return diags
+	}
+	if skip, err := etagSHA256Matches(ctx, data, data.Get("url").(string), mode, cfg); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	} else if skip {
+		data.Set("source_url", data.Get("url").(string))
+		return diags
+	}
+	urls := []string{data.Get("url").(string)}
+	for _, v := range data.Get("mirror_urls").([]interface{}) {
+		urls = append(urls, v.(string))
+	}
+	if len(urls) == 1 {
+		diags = append(diags, downloadURL(ctx, data, urls[0], mode, cfg)...)
+		if !diags.HasError() {
+			data.Set("source_url", urls[0])
+		}
+		return diags
+	}
+	// try each mirror in turn; the errors are only reported if all of them
+	// fail
+	var failures diag.Diagnostics
+	for _, rawURL := range urls {
+		attempt := downloadURL(ctx, data, rawURL, mode, cfg)
+		if !attempt.HasError() {
+			data.Set("source_url", rawURL)
+			return append(diags, attempt...)
+		}
+		logEvent("DEBUG", "download failed, trying the next url", "url", rawURL)
+		for _, d := range attempt {
+			if d.Severity == diag.Error {
+				d.Summary = fmt.Sprintf("%s: %s", rawURL, d.Summary)
+				failures = append(failures, d)
+			} else {
+				diags = append(diags, d)
+			}
+		}
 	}
-	return os.FileMode(0664), nil
+	return append(diags, failures...)
 }
 
-func ensureDownloadFile(data *schema.ResourceData, mode os.FileMode) (diags diag.Diagnostics) {
-	req, err := makeRequest(http.MethodGet, data)
+// downloadURL downloads rawURL into the destination of data.
+func downloadURL(ctx context.Context, data *schema.ResourceData, rawURL string, mode os.FileMode, cfg *providerConfig) (diags diag.Diagnostics) {
+	if source, ok, err := localSource(rawURL, cfg); err != nil {
+		return diag.FromErr(err)
+	} else if ok {
+		if err := ensureLocalFile(ctx, data, source, mode, cfg); err != nil {
+			return diag.FromErr(err)
+		}
+		if diags := checkDownloadFormat(data, cfg.resolvePath(data.Get(destinationKey(data)).(string)), ""); diags.HasError() {
+			return diags
+		}
+		logEvent("INFO", "copied local url", "url", rawURL, "source", source, "file_mode", mode)
+		return nil
+	}
+	req, err := makeRequestURL(data.Get("method").(string), rawURL, data, cfg)
+	var tokenErr *oauth2TokenError
+	if errors.As(err, &tokenErr) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  "OAuth2 token request failed",
+			Detail:   tokenErr.Error(),
+		}}
+	}
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	c := &http.Client{}
+	req = req.WithContext(ctx)
+	c, diags := newHTTPClient(data, cfg)
+	if diags.HasError() {
+		return diags
+	}
+	diags = append(diags, acceptWarning(data)...)
+	logEvent("DEBUG", "sending request", "method", req.Method, "url", req.URL.Redacted(), "request_headers", headerNames(req.Header))
 	resp, err := c.Do(req)
 	if err != nil {
-		diag.FromErr(fmt.Errorf("error making request to %q: %w", req.URL, err))
+		logEvent("DEBUG", "request failed", "url", req.URL.Redacted(), "error", err.Error())
+		return append(diags, diag.FromErr(fmt.Errorf("error making request to %q: %w", req.URL, err))...)
 	}
+	logEvent("DEBUG", "received response", "url", req.URL.Redacted(), "status", resp.StatusCode, "response_headers", headerNames(resp.Header))
 
-	dest := data.Get("filename").(string)
+	dest := cfg.resolvePath(data.Get(destinationKey(data)).(string))
 
 	defer resp.Body.Close()
-	switch resp.StatusCode {
+	rangeDiags := checkRangeResponse(req, resp, data)
+	diags = append(diags, rangeDiags...)
+	if rangeDiags.HasError() {
+		return diags
+	}
+	status := resp.StatusCode
+	if status == http.StatusPartialContent && req.Header.Get("Range") != "" {
+		status = http.StatusOK
+	}
+	if status != http.StatusNotModified && allowedStatusCodes(data)[status] {
+		status = http.StatusOK
+	}
+	switch status {
 	case http.StatusNotModified:
+		logEvent("INFO", "url not modified", "url", req.URL.Redacted(), "destination", dest)
 		return diags
 	case http.StatusOK:
-		data.Set("etag", resp.Header.Get("ETag"))
-		data.Set("last_modified", resp.Header.Get("Last-Modified"))
-		h := sha256.New()
-		tr := io.TeeReader(resp.Body, h)
-		if err := writeResponseBody(tr, dest, mode); err != nil {
-			return diag.FromErr(err)
+		if v, ok := data.GetOk("expected_content_type"); ok && !mediaTypeMatches(resp.Header.Get("Content-Type"), v.(string)) {
+			// the body of a misrouted request, such as an HTML error page,
+			// usually explains it
+			d := diagResponseError(resp, "the server returned content type %q, expected %q", resp.Header.Get("Content-Type"), v)
+			d[len(d)-1].AttributePath = cty.GetAttrPath("expected_content_type")
+			return append(diags, d...)
+		}
+		start := time.Now()
+		var written int64
+		if chunks := downloadChunks(data, req, resp); chunks > 1 {
+			if err := writeChunkedDownload(ctx, c, req, resp, chunks, data, dest, mode, cfg); err != nil {
+				return append(diags, diag.FromErr(err)...)
+			}
+			written = resp.ContentLength
+			logEvent("INFO", "downloaded url", "url", req.URL.Redacted(), "destination", dest, "bytes", written, "file_mode", mode, "chunks", chunks)
+		} else {
+			var body io.Reader = resp.Body
+			// a decoded body no longer has the advertised length
+			size := resp.ContentLength
+			if req.Method == http.MethodHead {
+				// the Content-Length of a HEAD is that of the GET, while the
+				// body is empty
+				size = -1
+			}
+			if data.Get("decompress").(bool) {
+				body, err = decodeContentEncoding(resp.Body, resp.Header.Values("Content-Encoding"))
+				if err != nil {
+					return append(diags, diag.Diagnostic{
+						Severity: diag.Error,
+						Summary:  "could not decode response body",
+						Detail:   err.Error(),
+					})
+				}
+				if body != resp.Body {
+					size = -1
+				}
+			}
+			counter := &countingReader{r: body}
+			if err := writeDownload(data, counter, req.URL.Path, dest, mode, size, cfg); err != nil {
+				return append(diags, diag.FromErr(err)...)
+			}
+			written = int64(counter.n)
+			logEvent("INFO", "downloaded url", "url", req.URL.Redacted(), "destination", dest, "bytes", written, "file_mode", mode)
+		}
+		data.Set("bytes_written", int(written))
+		data.Set("download_duration_ms", int(time.Since(start).Milliseconds()))
+		// keep the stored validators if the server omits them
+		if v := resp.Header.Get("ETag"); v != "" {
+			data.Set("etag", v)
+		}
+		if v := resp.Header.Get("Last-Modified"); v != "" {
+			data.Set("last_modified", normalizeHTTPDate(v))
 		}
-		shaStr := hex.EncodeToString(h.Sum(nil))
-		data.Set("content_sha256", shaStr)
+		data.Set("content_type", resp.Header.Get("Content-Type"))
+		data.Set("content_length", int(resp.ContentLength))
+		data.Set("status_code", resp.StatusCode)
+		data.Set("response_headers", flattenHeader(resp.Header))
+		if d := checkDownloadFormat(data, dest, resp.Header.Get("Content-Type")); d.HasError() {
+			return append(diags, d...)
+		}
+		if data.Get("preserve_timestamps").(bool) && !data.Get("extract").(bool) {
+			if err := setLastModified(dest, resp.Header.Get("Last-Modified")); err != nil {
+				return append(diags, diag.FromErr(err)...)
+			}
+		}
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return append(diags, diagResponseError(resp, "the server redirected to %q but redirects are disabled by max_redirects", resp.Header.Get("Location"))...)
 	case http.StatusUnauthorized:
-		return diagResponseError(resp, "this url requires authorization. You may need to add Authorization header to this resource")
+		return append(diags, diagResponseError(resp, "this url requires authorization. You may need to add Authorization header to this resource")...)
 	case http.StatusForbidden:
-		return diagResponseError(resp, "the server rejected your auth credentials. They may be expired or you may not be allowed to download this anymore.")
+		return append(diags, diagResponseError(resp, "the server rejected your auth credentials. They may be expired or you may not be allowed to download this anymore.")...)
 	default:
-		return diagResponseError(resp, "the server returned an unexpected response code: %s", resp.Status)
+		return append(diags, diagResponseError(resp, "the server returned an unexpected response code: %s", resp.Status)...)
 	}
 	return
 }
@@ -225,6 +1233,24 @@ func isTextual(contentType string) bool {
 	}
 }
 
+// mediaTypeMatches reports whether contentType has the normalized media
+// type of expected.
+func mediaTypeMatches(contentType, expected string) bool {
+	mt := getNormalizedMediaType(contentType)
+	return mt != "" && mt == getNormalizedMediaType(expected)
+}
+
+func validateMediaType(v interface{}, k string) (warnings []string, errors []error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+	if getNormalizedMediaType(s) == "" {
+		errors = append(errors, fmt.Errorf("%s %q is not a valid media type, such as application/gzip", k, s))
+	}
+	return
+}
+
 func getNormalizedMediaType(contentType string) string {
 	// trim of the content-type parameters
 	mt, _, err := mime.ParseMediaType(contentType)
@@ -250,20 +1276,70 @@ func getNormalizedMediaType(contentType string) string {
 	return mt
 }
 
-func diagResponseError(resp *http.Response, format string, v ...interface{}) (diags diag.Diagnostics) {
-	var detail string
-	if isTextual(resp.Header.Get("Content-Type")) {
-		text, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			diags = append(diags, diag.Diagnostic{
-				Severity: diag.Warning,
-				Summary:  "could not read response body",
-				Detail:   err.Error(),
-			})
-		} else {
-			detail = string(text)
+// decodeContentEncoding wraps body in decoders for each coding listed in the
+// Content-Encoding header values. Codings are listed in the order they were
+// applied, so they are removed in reverse.
+func decodeContentEncoding(body io.Reader, values []string) (io.Reader, error) {
+	var codings []string
+	for _, v := range values {
+		for _, coding := range strings.Split(v, ",") {
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding == "" || coding == "identity" {
+				continue
+			}
+			codings = append(codings, coding)
+		}
+	}
+	for i := len(codings) - 1; i >= 0; i-- {
+		switch codings[i] {
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(body)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gzip stream for Content-Encoding %q: %w", strings.Join(codings, ", "), err)
+			}
+			body = zr
+		case "deflate":
+			br := bufio.NewReader(body)
+			// deflate should be zlib-wrapped, but some servers send a raw stream
+			if hdr, err := br.Peek(2); err == nil && hdr[0]&0x0f == 8 && (uint16(hdr[0])<<8|uint16(hdr[1]))%31 == 0 {
+				zr, err := zlib.NewReader(br)
+				if err != nil {
+					return nil, fmt.Errorf("invalid deflate stream for Content-Encoding %q: %w", strings.Join(codings, ", "), err)
+				}
+				body = zr
+			} else {
+				body = flate.NewReader(br)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported Content-Encoding %q; set decompress = false to store the encoded response", strings.Join(codings, ", "))
 		}
 	}
+	return body, nil
+}
+
+// flattenHeader converts h into a map for a string map attribute, joining
+// the values of a repeated header with a comma.
+func flattenHeader(h http.Header) map[string]interface{} {
+	m := make(map[string]interface{}, len(h))
+	for k, v := range h {
+		m[k] = strings.Join(v, ", ")
+	}
+	return m
+}
+
+// maxErrorBodySize limits how much of an error response body is read into
+// the diagnostic detail.
+const maxErrorBodySize = 64 * 1024
+
+func diagResponseError(resp *http.Response, format string, v ...interface{}) (diags diag.Diagnostics) {
+	detail, err := describeErrorBody(resp)
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "could not read response body",
+			Detail:   err.Error(),
+		})
+	}
 	diags = append(diags, diag.Diagnostic{
 		Severity: diag.Error,
 		Summary:  fmt.Sprintf(format, v...),
@@ -272,25 +1348,287 @@ func diagResponseError(resp *http.Response, format string, v ...interface{}) (di
 	return
 }
 
-func writeResponseBody(body io.Reader, filename string, mode os.FileMode) (err error) {
-	if mode == 0 {
-		mode = os.FileMode(0644)
+// describeErrorBody returns the diagnostic detail for the body of an error
+// response. A textual body is included, truncated to maxErrorBodySize, and
+// any other body is summarized by its content type and length. At most one
+// byte past maxErrorBodySize is read either way.
+func describeErrorBody(resp *http.Response) (string, error) {
+	// read one byte past the limit to tell if the body was truncated
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(body) == 0 {
+		return "", nil
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	truncated := len(body) > maxErrorBodySize
+	if isTextual(contentType) {
+		if truncated {
+			// drop a rune that was cut in half by the limit
+			return strings.ToValidUTF8(string(body[:maxErrorBodySize]), "") + "\n... (response body truncated)", nil
+		}
+		return string(body), nil
+	}
+	size := fmt.Sprintf("%d bytes", len(body))
+	if resp.ContentLength >= 0 {
+		size = fmt.Sprintf("%d bytes", resp.ContentLength)
+	} else if truncated {
+		size = fmt.Sprintf("more than %d bytes", maxErrorBodySize)
+	}
+	return fmt.Sprintf("The response body is not shown since it is not text (%s, %s).", contentType, size), nil
+}
+
+// writeDownload writes body to dest, or extracts it there, and records its
+// hashes. name is used to detect the archive format.
+func writeDownload(data *schema.ResourceData, body io.Reader, name, dest string, mode os.FileMode, size int64, cfg *providerConfig) error {
+	if limit := int64(data.Get("max_bytes").(int)); limit > 0 {
+		if size > limit {
+			return fmt.Errorf("the response body is %d bytes, which exceeds max_bytes (%d)", size, limit)
+		}
+		body = newMaxBytesReader(body, limit)
 	}
-	dest, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	tr, err := newSHA256Reader(body, data)
 	if err != nil {
-		return fmt.Errorf("could not create destination file %q: %w", filename, err)
+		return err
+	}
+	if err := ensureParentDir(data, dest); err != nil {
+		return err
+	}
+	if data.Get("extract").(bool) {
+		if err := extractResponseBody(tr, name, dest, cfg.newCopyBuffer()); err != nil {
+			return err
+		}
+		treeHash, err := hashTree(dest)
+		if err != nil {
+			return err
+		}
+		data.Set("extracted_sha256", treeHash)
+	} else {
+		flag, err := openFlags(data, dest)
+		if err != nil {
+			return err
+		}
+		if err := writeResponseBody(tr, dest, mode, flag, size, cfg.newCopyBuffer()); err != nil {
+			return err
+		}
+	}
+	data.Set("content_sha256", tr.Sum())
+	data.Set("content_hash", tr.ContentHash())
+	return nil
+}
+
+// maxBytesReader fails a read once more than limit bytes have been read, so
+// a copy from it fails before its result is put in place.
+type maxBytesReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func newMaxBytesReader(r io.Reader, limit int64) *maxBytesReader {
+	// reading a single byte past the limit is enough to detect it
+	return &maxBytesReader{r: io.LimitReader(r, limit+1), limit: limit}
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if r.n > r.limit {
+		return n, fmt.Errorf("response body exceeds max_bytes (%d)", r.limit)
+	}
+	return n, err
+}
+
+// localSource returns the local path of rawURL if it is a file:// url.
+func localSource(rawURL string, cfg *providerConfig) (string, bool, error) {
+	source, err := cfg.resolveURL(rawURL)
+	if err != nil {
+		return "", false, err
+	}
+	u, err := url.Parse(source)
+	if err != nil || !strings.EqualFold(u.Scheme, "file") {
+		return "", false, nil
+	}
+	path, err := fileURLPath(u)
+	return path, err == nil, err
+}
+
+// fileURLPath converts a file:// url into a local path.
+func fileURLPath(u *url.URL) (string, error) {
+	path, err := fileURLToSlash(u, runtime.GOOS == "windows")
+	if err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(path), nil
+}
+
+// ensureLocalFile copies the file behind a file:// url. The copy is skipped
+// when the source still matches content_sha256, in place of the
+// conditional request made for http urls.
+func ensureLocalFile(ctx context.Context, data *schema.ResourceData, source string, mode os.FileMode, cfg *providerConfig) error {
+	dest := cfg.resolvePath(data.Get(destinationKey(data)).(string))
+	sourceHash, err := hashFile(source)
+	if err != nil {
+		return fmt.Errorf("could not read %q: %w", source, err)
+	}
+	if data.Id() != "" && !data.Get("force_download").(bool) && sourceHash == data.Get("content_sha256").(string) {
+		return nil
+	}
+	f, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("could not open %q: %w", source, err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("could not stat %q: %w", source, err)
+	}
+	if err := writeDownload(data, contextReader{ctx: ctx, r: f}, source, dest, mode, stat.Size(), cfg); err != nil {
+		return err
+	}
+	data.Set("content_type", "")
+	data.Set("content_length", int(stat.Size()))
+	data.Set("status_code", 0)
+	data.Set("response_headers", map[string]interface{}{})
+	if data.Get("preserve_timestamps").(bool) && !data.Get("extract").(bool) {
+		if err := os.Chtimes(dest, stat.ModTime(), stat.ModTime()); err != nil {
+			return fmt.Errorf("could not set times of %q: %w", dest, err)
+		}
+	}
+	return nil
+}
+
+// destinationKey returns the attribute holding the download destination.
+func destinationKey(data resourceGetter) string {
+	if data.Get("extract").(bool) {
+		return "extract_to"
+	}
+	return "filename"
+}
+
+// sha256Reader hashes everything read through it. If expected is set, the
+// read that reaches EOF fails instead when the hash does not match, so a
+// copy from it fails before its result is put in place.
+type sha256Reader struct {
+	r        io.Reader
+	h        hash.Hash
+	expected string
+	// content is the hash_algorithm hash, if it is not sha256
+	content hash.Hash
+}
+
+// newSHA256Reader returns a sha256Reader for body that checks
+// expected_sha256, or the hash from checksum_url, and also hashes with
+// hash_algorithm.
+func newSHA256Reader(body io.Reader, data resourceGetter) (*sha256Reader, error) {
+	r := &sha256Reader{r: body, h: sha256.New(), expected: data.Get("expected_sha256").(string)}
+	if v, ok := data.GetOk("checksum_sha256"); ok && r.expected == "" {
+		r.expected = v.(string)
+	}
+	if algorithm := data.Get("hash_algorithm").(string); algorithm != "sha256" {
+		newHash, err := newHashFunc(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		r.content = newHash()
+	}
+	return r, nil
+}
+
+func (r *sha256Reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.h.Write(p[:n])
+	if r.content != nil {
+		r.content.Write(p[:n])
+	}
+	if err == io.EOF && r.expected != "" && !strings.EqualFold(r.Sum(), r.expected) {
+		return n, fmt.Errorf("checksum mismatch: got sha256 %s, expected %s", r.Sum(), r.expected)
+	}
+	return n, err
+}
+
+// Sum returns the hex encoded hash of the bytes read so far.
+func (r *sha256Reader) Sum() string {
+	return hex.EncodeToString(r.h.Sum(nil))
+}
+
+// ContentHash returns the hex encoded hash_algorithm hash of the bytes read
+// so far.
+func (r *sha256Reader) ContentHash() string {
+	if r.content == nil {
+		return r.Sum()
+	}
+	return hex.EncodeToString(r.content.Sum(nil))
+}
+
+// extractResponseBody extracts the archive in body into destination. The
+// archive is extracted into a temporary directory beside destination and
+// body is read to the end before that directory replaces destination, so
+// a failed checksum leaves destination untouched. A zip archive is held in
+// memory since it cannot be read as a stream.
+func extractResponseBody(body io.Reader, name, destination string, buf []byte) (err error) {
+	br := bufio.NewReaderSize(body, 512)
+	head, _ := br.Peek(512)
+	format := detectArchiveFormat(name, head)
+	if format == archiveUnknown {
+		return fmt.Errorf("could not detect the archive format of %q", name)
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(destination), "."+filepath.Base(destination)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not create temporary directory for %q: %w", destination, err)
 	}
 	defer func() {
-		closeErr := dest.Close()
-		if err == nil {
-			err = closeErr
+		if err != nil {
+			os.RemoveAll(staging)
 		}
 	}()
-	if _, err = io.Copy(dest, body); err != nil {
-		// clean up dest
-		_ = dest.Close()
-		_ = os.Remove(filename)
-		return fmt.Errorf("error reading request body into %q: %w", filename, err)
+	if err := os.Chmod(staging, 0755); err != nil {
+		return err
+	}
+	if format == archiveZip {
+		b, err := ioutil.ReadAll(br)
+		if err != nil {
+			return fmt.Errorf("error reading archive from response: %w", err)
+		}
+		if err := extractZip(bytes.NewReader(b), int64(len(b)), staging, buf); err != nil {
+			return err
+		}
+	} else {
+		if err := extractTarStream(br, format, staging, buf); err != nil {
+			return err
+		}
+		// drain any trailing padding so the checksum covers the whole body
+		if _, err := io.Copy(ioutil.Discard, br); err != nil {
+			return fmt.Errorf("error reading archive from response: %w", err)
+		}
+	}
+	if err := os.RemoveAll(destination); err != nil {
+		return fmt.Errorf("could not clear destination %q: %w", destination, err)
+	}
+	if err := os.Rename(staging, destination); err != nil {
+		return fmt.Errorf("could not move extracted archive into %q: %w", destination, err)
 	}
 	return nil
 }
+
+// writeResponseBody writes body to filename. If size is not negative, the
+// number of bytes written must match it or the file is removed.
+func writeResponseBody(body io.Reader, filename string, mode os.FileMode, flag int, size int64, buf []byte) error {
+	if mode == 0 {
+		mode = os.FileMode(0644)
+	}
+	return writeFileAtomic(filename, mode, flag, func(w io.Writer) error {
+		n, err := copyBuffer(w, body, buf)
+		if err != nil {
+			return fmt.Errorf("error reading request body into %q: %w", filename, err)
+		}
+		if size >= 0 && n != size {
+			return fmt.Errorf("truncated download into %q: got %d bytes, expected Content-Length %d", filename, n, size)
+		}
+		return nil
+	})
+}
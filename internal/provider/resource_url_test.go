@@ -1,19 +1,35 @@
 package provider
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
 	"crypto/sha256"
+	"crypto/tls"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strconv"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
@@ -50,6 +66,13 @@ resource "synclocal_url" "copy" {
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttrSet("synclocal_url.copy", "etag"),
 					resource.TestCheckResourceAttr("synclocal_url.copy", "content_sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"),
+					resource.TestCheckResourceAttr("synclocal_url.copy", "content_type", "text/plain; charset=utf-8"),
+					resource.TestCheckResourceAttr("synclocal_url.copy", "content_length", "5"),
+					resource.TestCheckResourceAttr("synclocal_url.copy", "status_code", "200"),
+					resource.TestCheckResourceAttr("synclocal_url.copy", "bytes_written", "5"),
+					resource.TestCheckResourceAttrSet("synclocal_url.copy", "download_duration_ms"),
+					resource.TestCheckResourceAttr("synclocal_url.copy", "response_headers.Content-Type", "text/plain; charset=utf-8"),
+					resource.TestCheckResourceAttrSet("synclocal_url.copy", "response_headers.Etag"),
 				),
 			},
 			{
@@ -68,6 +91,9 @@ resource "synclocal_url" "copy" {
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttrSet("synclocal_url.copy", "etag"),
 					resource.TestCheckResourceAttr("synclocal_url.copy", "content_sha256", "82e35a63ceba37e9646434c5dd412ea577147f1e4a41ccde1614253187e3dbf9"),
+					resource.TestCheckResourceAttr("synclocal_url.copy", "content_length", "7"),
+					resource.TestCheckResourceAttr("synclocal_url.copy", "status_code", "200"),
+					resource.TestCheckResourceAttr("synclocal_url.copy", "bytes_written", "7"),
 				),
 			},
 			{
@@ -101,6 +127,1182 @@ resource "synclocal_url" "copy" {
 	})
 }
 
+func TestAccResourceURL_insecureSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccDestroyURL,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "synclocal" {
+}
+
+resource "synclocal_url" "copy" {
+	url         = "%s"
+	headers     = {
+		Authorization = "Bearer secret"
+	}
+	filename = "./testdata/dest-file-url-tls"
+}
+`, srv.URL),
+				ExpectError: regexp.MustCompile(`.*certificate.*`),
+			},
+			{
+				Config: fmt.Sprintf(`
+provider "synclocal" {
+}
+
+resource "synclocal_url" "copy" {
+	url         = "%s"
+	headers     = {
+		Authorization = "Bearer secret"
+	}
+	filename = "./testdata/dest-file-url-tls"
+	insecure_skip_verify = true
+}
+`, srv.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("synclocal_url.copy", "insecure_skip_verify", "true"),
+					resource.TestCheckResourceAttr("synclocal_url.copy", "content_sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceURL_defaultHeaders(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccDestroyURL,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "synclocal" {
+	default_headers = {
+		Authorization = "Bearer secret"
+	}
+}
+
+resource "synclocal_url" "copy" {
+	url      = "%s"
+	filename = "./testdata/dest-file-url-default-headers"
+}
+`, srv.URL),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("synclocal_url.copy", "content_sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"),
+				),
+			},
+		},
+	})
+}
+
+func TestNewHTTPClient_insecureSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	for _, skip := range []bool{false, true} {
+		data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+			"url":                  srv.URL,
+			"filename":             "./testdata/dest-file-url-tls",
+			"insecure_skip_verify": skip,
+		})
+		c, diags := newHTTPClient(data, nil)
+		if skip != (len(diags) == 1 && diags[0].Severity == diag.Warning) {
+			t.Errorf("insecure_skip_verify=%v: unexpected diagnostics %v", skip, diags)
+		}
+		resp, err := c.Get(srv.URL)
+		if skip && err != nil {
+			t.Errorf("insecure_skip_verify=%v: unexpected error: %v", skip, err)
+		}
+		if !skip && err == nil {
+			t.Errorf("insecure_skip_verify=%v: expected certificate error", skip)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+func TestNewHTTPClient_minTLSVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		serverMax uint16
+		min       uint16
+		wantErr   bool
+	}{
+		{"tls 1.1 server", tls.VersionTLS11, 0, true},
+		{"tls 1.2 server", tls.VersionTLS12, 0, false},
+		{"tls 1.2 server with minimum 1.3", tls.VersionTLS12, tls.VersionTLS13, true},
+		{"tls 1.3 server with minimum 1.3", tls.VersionTLS13, tls.VersionTLS13, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			srv.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tt.serverMax}
+			srv.StartTLS()
+			defer srv.Close()
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":                  srv.URL,
+				"filename":             "./testdata/dest-file-url-tls",
+				"insecure_skip_verify": true,
+			})
+			c, _ := newHTTPClient(data, &providerConfig{minTLSVersion: tt.min})
+			resp, err := c.Get(srv.URL)
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestAccResourceURL_planTimeCheck(t *testing.T) {
+	file1 := testURLHandler(t, "./testdata/source-file01")
+	file2 := testURLHandler(t, "./testdata/source-file02")
+	current := file1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	config := fmt.Sprintf(`
+provider "synclocal" {
+}
+
+resource "synclocal_url" "copy" {
+	url         = "%s"
+	headers     = {
+		Authorization = "Bearer secret"
+	}
+	filename = "./testdata/dest-file-url-plan"
+	plan_time_check = true
+}
+`, srv.URL)
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccDestroyURL,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("synclocal_url.copy", "content_sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"),
+				),
+			},
+			{
+				// remote unchanged
+				Config:   config,
+				PlanOnly: true,
+			},
+			{
+				// remote changed
+				PreConfig:          func() { current = file2 },
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("synclocal_url.copy", "content_sha256", "82e35a63ceba37e9646434c5dd412ea577147f1e4a41ccde1614253187e3dbf9"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceURLRead_readStrategy(t *testing.T) {
+	var gets int
+	file1 := testURLHandler(t, "./testdata/source-file01")
+	file2 := testURLHandler(t, "./testdata/source-file02")
+	current := file1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets++
+		}
+		current.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name     string
+		strategy string
+		provider string
+		// after the url changes
+		wantGets    int
+		wantContent string
+		wantUpdate  bool
+		// after the file is modified locally
+		wantRecreate bool
+	}{
+		{"download", "", "", 1, "goodbye", false, true},
+		{"stat", "stat", "", 0, "hello", false, false},
+		{"hash", "hash", "", 0, "hello", false, true},
+		{"remote", "remote", "", 0, "hello", true, true},
+		{"provider default", "", "remote", 0, "hello", true, true},
+		{"resource overrides provider", "stat", "remote", 0, "hello", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, gets = file1, 0
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			raw := map[string]interface{}{
+				"url":      srv.URL,
+				"filename": dest,
+				"headers":  map[string]interface{}{"Authorization": "Bearer secret"},
+			}
+			if tt.strategy != "" {
+				raw["read_strategy"] = tt.strategy
+			}
+			meta := &providerConfig{defaultReadStrategy: tt.provider}
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), raw)
+			if diags := resourceURLCreate(context.Background(), data, meta); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			current, gets = file2, 0
+			data = resourceURL().Data(data.State())
+			if diags := resourceURLRead(context.Background(), data, meta); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if gets != tt.wantGets {
+				t.Fatalf("expected %d downloads during refresh, got %d", tt.wantGets, gets)
+			}
+			if b, _ := ioutil.ReadFile(dest); string(b) != tt.wantContent {
+				t.Fatalf("unexpected content %q", b)
+			}
+			diff, err := resourceURL().Diff(context.Background(), data.State(), terraform.NewResourceConfigRaw(raw), meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			update := diff != nil && diff.Attributes["content_sha256"] != nil && diff.Attributes["content_sha256"].NewComputed
+			if update != tt.wantUpdate {
+				t.Fatalf("expected a planned download to be %v, got %v", tt.wantUpdate, diff)
+			}
+			if err := ioutil.WriteFile(dest, []byte("tampered"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			current = file1
+			data = resourceURL().Data(data.State())
+			if diags := resourceURLRead(context.Background(), data, meta); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if recreate := data.Id() == ""; recreate != tt.wantRecreate {
+				t.Fatalf("expected the modified file to be recreated to be %v", tt.wantRecreate)
+			}
+		})
+	}
+}
+
+func TestAccResourceURL_localDrift(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	config := fmt.Sprintf(`
+provider "synclocal" {
+}
+
+resource "synclocal_url" "copy" {
+	url         = "%s"
+	headers     = {
+		Authorization = "Bearer secret"
+	}
+	filename = "./testdata/dest-file-url-drift"
+}
+`, srv.URL)
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		CheckDestroy:      testAccDestroyURL,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+			},
+			{
+				PreConfig: func() {
+					if err := ioutil.WriteFile("./testdata/dest-file-url-drift", []byte("tampered"), 0664); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("synclocal_url.copy", "content_sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceURLRead_localDrift(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      srv.URL,
+		"filename": dest,
+		"headers":  map[string]interface{}{"Authorization": "Bearer secret"},
+	})
+	if diags := resourceURLCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if diags := resourceURLRead(context.Background(), data, nil); diags.HasError() || data.Id() == "" {
+		t.Fatalf("expected unmodified file to be kept: %v", diags)
+	}
+	if err := ioutil.WriteFile(dest, []byte("tampered"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceURLRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if data.Id() != "" {
+		t.Fatalf("expected modified file to clear the resource id")
+	}
+}
+
+func TestResourceURLDelete_keepOnDestroy(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		dest := filepath.Join(t.TempDir(), "dest-file")
+		if err := ioutil.WriteFile(dest, []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+			"url":             "http://localhost",
+			"filename":        dest,
+			"keep_on_destroy": keep,
+		})
+		id, _ := fileToID(dest)
+		data.SetId(id)
+		data.Set("managed", true)
+		if diags := resourceURLDelete(context.Background(), data, nil); diags.HasError() {
+			t.Fatalf("keep_on_destroy=%v: unexpected error: %v", keep, diags)
+		}
+		if _, err := os.Stat(dest); os.IsNotExist(err) == keep {
+			t.Fatalf("keep_on_destroy=%v: unexpected existence of %q after delete: %v", keep, dest, err)
+		}
+	}
+}
+
+func TestEnsureDownloadFile_maxRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/file", testURLHandler(t, "./testdata/source-file01"))
+	mux.Handle("/r1", http.RedirectHandler("/file", http.StatusFound))
+	mux.Handle("/r2", http.RedirectHandler("/r1", http.StatusFound))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	tests := []struct {
+		name         string
+		path         string
+		maxRedirects int
+		wantErr      *regexp.Regexp
+	}{
+		{name: "follow", path: "/r2", maxRedirects: 10},
+		{name: "disallow", path: "/r1", maxRedirects: 0, wantErr: regexp.MustCompile(`redirected to "/file"`)},
+		{name: "exceed", path: "/r2", maxRedirects: 1, wantErr: regexp.MustCompile(`stopped after 1 redirects`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":           srv.URL + tt.path,
+				"filename":      filepath.Join(t.TempDir(), "dest-file"),
+				"headers":       map[string]interface{}{"Authorization": "Bearer secret"},
+				"max_redirects": tt.maxRedirects,
+			})
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			if tt.wantErr == nil {
+				if diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				if got := data.Get("content_sha256").(string); got != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+					t.Fatalf("unexpected content_sha256 %q", got)
+				}
+				return
+			}
+			if !diags.HasError() || !tt.wantErr.MatchString(diags[0].Summary) {
+				t.Fatalf("expected error matching %q, got %v", tt.wantErr, diags)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_contentEncoding(t *testing.T) {
+	content, _ := readTestFile(t, "./testdata/source-file01")
+	gzipped := func(b []byte) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(b)
+		zw.Close()
+		return buf.Bytes()
+	}
+	zlibbed := func(b []byte) []byte {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(b)
+		zw.Close()
+		return buf.Bytes()
+	}
+	deflated := func(b []byte) []byte {
+		var buf bytes.Buffer
+		zw, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+		zw.Write(b)
+		zw.Close()
+		return buf.Bytes()
+	}
+	tests := []struct {
+		name       string
+		encoding   string
+		body       []byte
+		decompress bool
+		want       []byte
+		wantErr    *regexp.Regexp
+	}{
+		{name: "gzip", encoding: "gzip", body: gzipped(content), decompress: true, want: content},
+		{name: "gzip-in-gzip", encoding: "gzip, gzip", body: gzipped(gzipped(content)), decompress: true, want: content},
+		{name: "deflate", encoding: "deflate", body: zlibbed(content), decompress: true, want: content},
+		{name: "raw-deflate", encoding: "deflate", body: deflated(content), decompress: true, want: content},
+		{name: "deflate-in-gzip", encoding: "deflate, gzip", body: gzipped(zlibbed(content)), decompress: true, want: content},
+		{name: "identity", encoding: "identity, gzip", body: gzipped(content), decompress: true, want: content},
+		{name: "unsupported", encoding: "gzip, br", body: gzipped(content), decompress: true, wantErr: regexp.MustCompile(`unsupported Content-Encoding "gzip, br"`)},
+		{name: "disabled", encoding: "gzip, gzip", body: gzipped(gzipped(content)), decompress: false, want: gzipped(gzipped(content))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", tt.encoding)
+				w.WriteHeader(http.StatusOK)
+				w.Write(tt.body)
+			}))
+			defer srv.Close()
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":        srv.URL,
+				"filename":   dest,
+				"decompress": tt.decompress,
+			})
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			if tt.wantErr != nil {
+				if !diags.HasError() || !tt.wantErr.MatchString(diags[0].Detail) {
+					t.Fatalf("expected error matching %q, got %v", tt.wantErr, diags)
+				}
+				if _, err := os.Stat(dest); !os.IsNotExist(err) {
+					t.Fatalf("expected %q not to be written", dest)
+				}
+				return
+			}
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			got, err := ioutil.ReadFile(dest)
+			if err != nil {
+				t.Fatalf("could not read %q: %v", dest, err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("unexpected content %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_responseMetadata(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file02"))
+	defer srv.Close()
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      srv.URL,
+		"filename": filepath.Join(t.TempDir(), "dest-file"),
+		"headers":  map[string]interface{}{"Authorization": "Bearer secret"},
+	})
+	// the second request is answered with 304 Not Modified and must keep the values
+	duration := -1
+	for i := 0; i < 2; i++ {
+		if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if got := data.Get("bytes_written").(int); got != 7 {
+			t.Errorf("request %d: unexpected bytes_written %d", i, got)
+		}
+		if i == 0 {
+			duration = data.Get("download_duration_ms").(int)
+		} else if got := data.Get("download_duration_ms").(int); got != duration {
+			t.Errorf("request %d: download_duration_ms changed from %d to %d", i, duration, got)
+		}
+		if got := data.Get("content_type").(string); got != "text/plain; charset=utf-8" {
+			t.Errorf("request %d: unexpected content_type %q", i, got)
+		}
+		if got := data.Get("content_length").(int); got != 7 {
+			t.Errorf("request %d: unexpected content_length %d", i, got)
+		}
+		if got := data.Get("status_code").(int); got != http.StatusOK {
+			t.Errorf("request %d: unexpected status_code %d", i, got)
+		}
+		if got := data.Get("response_headers.Content-Type"); got != "text/plain; charset=utf-8" {
+			t.Errorf("request %d: unexpected response_headers %v", i, data.Get("response_headers"))
+		}
+	}
+}
+
+func TestEnsureDownloadFile_expectedContentType(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/archive.tar.gz":
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Write([]byte("archive"))
+		case "/api":
+			w.Header().Set("Content-Type", "application/vnd.api+json; charset=utf-8")
+			w.Write([]byte("{}"))
+		default:
+			// a login page served with 200 instead of the file
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html>please sign in</html>"))
+		}
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+		wantErr  bool
+	}{
+		{"match", "/archive.tar.gz", "application/gzip", false},
+		{"parameters and case", "/archive.tar.gz", "Application/GZIP; q=1", false},
+		{"structured suffix", "/api", "application/json", false},
+		{"html instead of archive", "/login", "application/gzip", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":                   srv.URL + tt.path,
+				"filename":              dest,
+				"expected_content_type": tt.expected,
+			})
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			if !tt.wantErr {
+				if diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				return
+			}
+			if !diags.HasError() {
+				t.Fatal("expected an error")
+			}
+			d := diags[len(diags)-1]
+			if !strings.Contains(d.Summary, `content type "text/html; charset=utf-8"`) || !strings.Contains(d.Detail, "please sign in") {
+				t.Fatalf("unexpected diagnostic %q: %q", d.Summary, d.Detail)
+			}
+			if !d.AttributePath.Equals(cty.GetAttrPath("expected_content_type")) {
+				t.Fatalf("unexpected attribute path %v", d.AttributePath)
+			}
+			if _, err := os.Stat(dest); !os.IsNotExist(err) {
+				t.Fatalf("the response was written to the destination")
+			}
+		})
+	}
+}
+
+func TestValidateMediaType(t *testing.T) {
+	for _, v := range []string{"application/gzip", "text/plain; charset=utf-8", "application/vnd.api+json"} {
+		if _, errs := validateMediaType(v, "expected_content_type"); len(errs) != 0 {
+			t.Errorf("%q: unexpected errors %v", v, errs)
+		}
+	}
+	for _, v := range []string{"", "gzip", "application/", "application/json+"} {
+		if _, errs := validateMediaType(v, "expected_content_type"); len(errs) == 0 {
+			t.Errorf("%q: expected an error", v)
+		}
+	}
+}
+
+func TestFlattenHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Type", "text/plain")
+	h.Add("Vary", "Accept")
+	h.Add("Vary", "Accept-Encoding")
+	got := flattenHeader(h)
+	want := map[string]interface{}{"Content-Type": "text/plain", "Vary": "Accept, Accept-Encoding"}
+	if len(got) != len(want) || got["Content-Type"] != want["Content-Type"] || got["Vary"] != want["Vary"] {
+		t.Fatalf("flattenHeader() = %v, want %v", got, want)
+	}
+}
+
+func TestEnsureDownloadFile_noFollow(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("O_NOFOLLOW is not supported on windows")
+	}
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := ioutil.WriteFile(target, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":       srv.URL,
+		"filename":  link,
+		"headers":   map[string]interface{}{"Authorization": "Bearer secret"},
+		"no_follow": true,
+	})
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); !diags.HasError() {
+		t.Fatalf("expected download through symlink %q to fail", link)
+	}
+	if b, _ := ioutil.ReadFile(target); string(b) != "original" {
+		t.Fatalf("symlink target was modified: %q", b)
+	}
+}
+
+func TestEnsureDownloadFile_createParents(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "a", "b", "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      srv.URL,
+		"filename": dest,
+		"headers":  map[string]interface{}{"Authorization": "Bearer secret"},
+	})
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("unexpected content %q", b)
+	}
+}
+
+func TestEnsureDownloadFile_preserveTimestamps(t *testing.T) {
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", mtime.Format(http.TimeFormat))
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":                 srv.URL,
+		"filename":            dest,
+		"preserve_timestamps": true,
+	})
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	stat, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stat.ModTime().Equal(mtime) {
+		t.Fatalf("unexpected mtime %s, want %s", stat.ModTime(), mtime)
+	}
+}
+
+func TestEnsureDownloadFile_truncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1024")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      srv.URL,
+		"filename": dest,
+	})
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); !diags.HasError() {
+		t.Fatalf("expected truncated download to fail")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("expected partial file %q to be removed", dest)
+	}
+}
+
+func TestWriteResponseBody_sizeMismatch(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	flag := os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	if err := writeResponseBody(strings.NewReader("hello"), dest, 0, flag, 1024, nil); err == nil {
+		t.Fatalf("expected size mismatch error")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("expected partial file %q to be removed", dest)
+	}
+	if err := writeResponseBody(strings.NewReader("hello"), dest, 0, flag, 5, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeResponseBody(strings.NewReader("hello"), dest, 0, flag, -1, nil); err != nil {
+		t.Fatalf("unexpected error with unknown size: %v", err)
+	}
+}
+
+func TestWriteResponseBody_interrupted(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dest-file")
+	if err := ioutil.WriteFile(dest, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	body := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errors.New("connection reset")))
+	flag := os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	if err := writeResponseBody(body, dest, 0, flag, -1, nil); err == nil {
+		t.Fatalf("expected the interrupted copy to fail")
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "original" {
+		t.Fatalf("destination was modified by an interrupted copy: %q", b)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("partial file was left behind: %v", entries)
+	}
+}
+
+func TestEnsureDownloadFile_requestBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("unexpected method %q", r.Method)
+		}
+		if r.ContentLength != 5 {
+			t.Errorf("unexpected Content-Length %d", r.ContentLength)
+		}
+		if v := r.Header.Get("If-None-Match"); v != "" {
+			t.Errorf("unexpected If-None-Match %q on %s", v, r.Method)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("ETag", `"abc"`)
+		w.Write(b)
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":          srv.URL,
+		"filename":     dest,
+		"method":       "POST",
+		"request_body": "hello",
+	})
+	// the second request must not be conditional even though an etag is stored
+	for i := 0; i < 2; i++ {
+		if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("unexpected content %q", b)
+	}
+}
+
+func TestResourceURLRead_nonConditionalMethod(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":          srv.URL,
+		"filename":     dest,
+		"method":       "POST",
+		"request_body": "hello",
+	})
+	if diags := resourceURLCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	for i := 0; i < 2; i++ {
+		if diags := resourceURLRead(context.Background(), data, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected refresh not to send the POST again, got %d requests", requests)
+	}
+	if data.Id() == "" {
+		t.Fatal("the file was removed from state")
+	}
+	// a changed local file is still found
+	if err := ioutil.WriteFile(dest, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceURLRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if data.Id() != "" {
+		t.Fatal("expected the changed file to be recreated")
+	}
+	if requests != 1 {
+		t.Fatalf("expected no more requests, got %d", requests)
+	}
+}
+
+func TestEnsureDownloadFile_head(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("unexpected method %q", r.Method)
+		}
+		w.Header().Set("Content-Length", "5")
+		w.Header().Set("ETag", `"abc"`)
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      srv.URL,
+		"filename": dest,
+		"method":   "HEAD",
+	})
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, err := ioutil.ReadFile(dest); err != nil || len(b) != 0 {
+		t.Fatalf("expected an empty file, got %q: %v", b, err)
+	}
+	if got := data.Get("content_length").(int); got != 5 {
+		t.Fatalf("unexpected content_length %d", got)
+	}
+	if got := data.Get("etag").(string); got != `"abc"` {
+		t.Fatalf("unexpected etag %q", got)
+	}
+}
+
+func TestEnsureDownloadFile_conditionalHeaders(t *testing.T) {
+	const etag = `"v1"`
+	const modified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	tests := []struct {
+		name         string
+		etag         string
+		lastModified string
+	}{
+		{"etag only", etag, ""},
+		{"last-modified only", "", modified},
+		{"both", etag, modified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests > 1 {
+					if got := r.Header.Get("If-None-Match"); got != tt.etag {
+						t.Errorf("unexpected If-None-Match %q, want %q", got, tt.etag)
+					}
+					if got := r.Header.Get("If-Modified-Since"); got != tt.lastModified {
+						t.Errorf("unexpected If-Modified-Since %q, want %q", got, tt.lastModified)
+					}
+				}
+				// the third response omits the validators
+				if requests < 3 {
+					if tt.etag != "" {
+						w.Header().Set("ETag", tt.etag)
+					}
+					if tt.lastModified != "" {
+						w.Header().Set("Last-Modified", tt.lastModified)
+					}
+				}
+				w.Write([]byte("hello"))
+			}))
+			defer srv.Close()
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":      srv.URL,
+				"filename": filepath.Join(t.TempDir(), "dest-file"),
+			})
+			for i := 0; i < 3; i++ {
+				if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				if got := data.Get("etag").(string); got != tt.etag {
+					t.Errorf("request %d: unexpected etag %q", i, got)
+				}
+				if got := data.Get("last_modified").(string); got != tt.lastModified {
+					t.Errorf("request %d: unexpected last_modified %q", i, got)
+				}
+			}
+		})
+	}
+}
+
+func TestETagMatch(t *testing.T) {
+	tests := []struct {
+		a, b         string
+		strong, weak bool
+	}{
+		{`"abc"`, `"abc"`, true, true},
+		{`W/"abc"`, `"abc"`, false, true},
+		{`W/"abc"`, `W/"abc"`, false, true},
+		{`"abc"`, `"xyz"`, false, false},
+		{`W/"abc"`, `W/"xyz"`, false, false},
+		{``, ``, false, false},
+	}
+	for _, tt := range tests {
+		if got := etagStrongMatch(tt.a, tt.b); got != tt.strong {
+			t.Errorf("etagStrongMatch(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.strong)
+		}
+		if got := etagWeakMatch(tt.a, tt.b); got != tt.weak {
+			t.Errorf("etagWeakMatch(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.weak)
+		}
+	}
+}
+
+func TestEnsureDownloadFile_weakETag(t *testing.T) {
+	const etag = `W/"abc"`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if inm := r.Header.Get("If-None-Match"); inm != "" {
+			if inm != etag {
+				t.Errorf("stored etag was not sent verbatim: %q", inm)
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      srv.URL,
+		"filename": filepath.Join(t.TempDir(), "dest-file"),
+	})
+	for i := 0; i < 2; i++ {
+		if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if got := data.Get("etag").(string); got != etag {
+			t.Fatalf("request %d: etag was not stored unmodified: %q", i, got)
+		}
+	}
+}
+
+func TestRemoteChanged_weakETag(t *testing.T) {
+	// the server ignores If-None-Match and always answers 200
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `W/"abc"`)
+	}))
+	defer srv.Close()
+	for _, tt := range []struct {
+		stored string
+		want   bool
+	}{
+		{`"abc"`, false},
+		{`W/"abc"`, false},
+		{`"xyz"`, true},
+	} {
+		data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+			"url":      srv.URL,
+			"filename": "dest-file",
+		})
+		data.Set("etag", tt.stored)
+		changed, err := remoteChanged(data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != tt.want {
+			t.Errorf("stored etag %s: remoteChanged() = %v, want %v", tt.stored, changed, tt.want)
+		}
+	}
+}
+
+func TestEnsureDownloadFile_userAgent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name      string
+		provider  map[string]interface{}
+		userAgent string
+		want      string
+	}{
+		{name: "default", want: "terraform-provider-synclocal/1.2.3"},
+		{name: "provider", provider: map[string]interface{}{"user_agent": "provider-agent"}, want: "provider-agent"},
+		{name: "resource", provider: map[string]interface{}{"user_agent": "provider-agent"}, userAgent: "resource-agent", want: "resource-agent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, diags := providerConfigure("1.2.3")(context.Background(), schema.TestResourceDataRaw(t, Provider().Schema, tt.provider))
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":        srv.URL,
+				"filename":   filepath.Join(t.TempDir(), "dest-file"),
+				"user_agent": tt.userAgent,
+			})
+			if diags := ensureDownloadFile(context.Background(), data, 0, configFromMeta(meta)); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if got != tt.want {
+				t.Fatalf("unexpected User-Agent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeRequest_defaultHeaders(t *testing.T) {
+	cfg := &providerConfig{defaultHeaders: map[string]string{
+		"Authorization": "Bearer default",
+		"User-Agent":    "synclocal-test",
+	}}
+	tests := []struct {
+		name    string
+		headers map[string]interface{}
+		cfg     *providerConfig
+		want    map[string]string
+	}{
+		{
+			name: "defaults only",
+			cfg:  cfg,
+			want: map[string]string{"Authorization": "Bearer default", "User-Agent": "synclocal-test"},
+		},
+		{
+			name:    "resource wins",
+			headers: map[string]interface{}{"authorization": "Bearer resource", "X-Extra": "1"},
+			cfg:     cfg,
+			want:    map[string]string{"Authorization": "Bearer resource", "User-Agent": "synclocal-test", "X-Extra": "1"},
+		},
+		{
+			name:    "no provider config",
+			headers: map[string]interface{}{"X-Extra": "1"},
+			want:    map[string]string{"Authorization": "", "X-Extra": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := map[string]interface{}{
+				"url":      "http://localhost",
+				"filename": "dest-file",
+			}
+			if tt.headers != nil {
+				raw["headers"] = tt.headers
+			}
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), raw)
+			req, err := makeRequest(http.MethodGet, data, tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, want := range tt.want {
+				if got := req.Header.Get(k); got != want {
+					t.Errorf("header %s = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceURLCreate_workingDirectory(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	dir := t.TempDir()
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      srv.URL,
+		"filename": "dest-file",
+		"headers":  map[string]interface{}{"Authorization": "Bearer secret"},
+	})
+	if diags := resourceURLCreate(context.Background(), data, &providerConfig{workingDir: dir}); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	dest := filepath.Join(dir, "dest-file")
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("unexpected content %q", b)
+	}
+	if want, _ := fileToID(dest); data.Id() != want {
+		t.Fatalf("unexpected id %q, want %q", data.Id(), want)
+	}
+}
+
+func TestEnsureDownloadFile_cookies(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Cookie")
+		session, err := r.Cookie("session")
+		if err != nil || session.Value != "abc123" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      srv.URL,
+		"filename": filepath.Join(t.TempDir(), "dest-file"),
+		"headers":  map[string]interface{}{"Cookie": "theme=dark"},
+		"cookies":  map[string]interface{}{"session": "abc123", "lang": "en"},
+	})
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	// cookies are added to the header in name order
+	if want := "theme=dark; lang=en; session=abc123"; got != want {
+		t.Fatalf("unexpected Cookie header %q, want %q", got, want)
+	}
+}
+
+func TestEnsureDownloadFile_unixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "http.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %s", err)
+	}
+	var host string
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+		if r.URL.Path != "/v1/file" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("hello"))
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":         "http://unix/v1/file",
+		"filename":    dest,
+		"unix_socket": socket,
+	})
+	// the proxy must not be used for the socket
+	cfg := &providerConfig{proxy: func(*url.URL) (*url.URL, error) {
+		return url.Parse("http://127.0.0.1:1")
+	}}
+	if diags := ensureDownloadFile(context.Background(), data, 0, cfg); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("unexpected content %q", b)
+	}
+	if host != "unix" {
+		t.Fatalf("unexpected Host %q", host)
+	}
+}
+
+func TestValidateRequestBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     map[string]interface{}
+		wantErr bool
+	}{
+		{"get without body", map[string]interface{}{}, false},
+		{"get with body", map[string]interface{}{"request_body": "x"}, true},
+		{"head with body", map[string]interface{}{"method": "HEAD", "request_body": "x"}, true},
+		{"get with allowed body", map[string]interface{}{"request_body": "x", "allow_get_body": true}, false},
+		{"post with body", map[string]interface{}{"method": "POST", "request_body": "x"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.raw["url"] = "http://localhost"
+			tt.raw["filename"] = "dest-file"
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), tt.raw)
+			if err := validateRequestBody(data); (err != nil) != tt.wantErr {
+				t.Fatalf("validateRequestBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func testAccDestroyURL(s *terraform.State) error {
 	for _, rs := range s.RootModule().Resources {
 		if rs.Type != "file" {
@@ -188,3 +1390,907 @@ func readTestFile(t *testing.T, filename string) (content []byte, hash string) {
 	hstr := hex.EncodeToString(h.Sum(nil))
 	return data, strconv.Quote(hstr)
 }
+
+func TestAccResourceURL_import(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	dest, err := filepath.Abs("./testdata/dest-file-url-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dest)
+	config := fmt.Sprintf(`
+provider "synclocal" {
+}
+
+resource "synclocal_url" "copy" {
+	url      = "%s"
+	filename = "./testdata/dest-file-url-import"
+}
+`, srv.URL)
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					if err := ioutil.WriteFile(dest, []byte("hello"), 0664); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config:        config,
+				ResourceName:  "synclocal_url.copy",
+				ImportState:   true,
+				ImportStateId: dest,
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if len(states) != 1 {
+						return fmt.Errorf("expected 1 state, got %d", len(states))
+					}
+					if got := states[0].Attributes["content_sha256"]; got != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+						return fmt.Errorf("unexpected content_sha256 %q", got)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestResourceURLImport(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	if err := ioutil.WriteFile(dest, []byte("hello"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{})
+	data.SetId(dest)
+	if _, err := resourceURLImport(context.Background(), data, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := data.Get("content_sha256").(string); got != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Fatalf("unexpected content_sha256 %q", got)
+	}
+	if got := data.Get("method").(string); got != http.MethodGet {
+		t.Fatalf("expected default method, got %q", got)
+	}
+	// refresh must not try to download from the unknown url
+	if diags := resourceURLRead(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected read error: %v", diags)
+	}
+	if data.Id() == "" {
+		t.Fatalf("expected imported file to remain in state")
+	}
+}
+
+func TestAccResourceURL_extract(t *testing.T) {
+	archive := testTarGz(t, []testArchiveEntry{
+		{name: "a.txt", body: "alpha\n"},
+		{name: "sub/b.txt", body: "bravo\n"},
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+	defer srv.Close()
+	want, err := hashTree("./testdata/source-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "synclocal" {
+}
+
+resource "synclocal_url" "archive" {
+	url             = "%s/release.tar.gz"
+	extract         = true
+	extract_to      = "./testdata/dest-url-extract"
+	expected_sha256 = "%s"
+}
+`, srv.URL, hashBytes(archive)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("synclocal_url.archive", "extracted_sha256", want),
+				),
+			},
+		},
+	})
+}
+
+func TestEnsureDownloadFile_extract(t *testing.T) {
+	tarGz := testTarGz(t, []testArchiveEntry{
+		{name: "a.txt", body: "alpha\n"},
+		{name: "sub/b.txt", body: "bravo\n"},
+	})
+	zipped := testZip(t, []testArchiveEntry{
+		{name: "a.txt", body: "alpha\n"},
+		{name: "sub/b.txt", body: "bravo\n"},
+	})
+	want, err := hashTree("./testdata/source-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		{"tar.gz", tarGz},
+		{"zip", zipped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(tt.archive)
+			}))
+			defer srv.Close()
+			dest := filepath.Join(t.TempDir(), "out")
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(dest, "stale.txt"), []byte("stale"), 0664); err != nil {
+				t.Fatal(err)
+			}
+			raw := map[string]interface{}{
+				"url":             srv.URL + "/download",
+				"extract":         true,
+				"extract_to":      dest,
+				"expected_sha256": strings.Repeat("0", 64),
+			}
+
+			// a checksum mismatch leaves the destination untouched
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), raw)
+			if diags := ensureDownloadFile(context.Background(), data, 0, nil); !diags.HasError() {
+				t.Fatalf("expected a checksum mismatch")
+			}
+			if _, err := os.Stat(filepath.Join(dest, "stale.txt")); err != nil {
+				t.Fatalf("expected destination to be untouched: %v", err)
+			}
+
+			raw["expected_sha256"] = hashBytes(tt.archive)
+			data = schema.TestResourceDataRaw(t, resourceURLSchema(), raw)
+			if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if got := data.Get("extracted_sha256").(string); got != want {
+				t.Fatalf("extracted tree does not match testdata/source-dir")
+			}
+			if got := data.Get("content_sha256").(string); got != hashBytes(tt.archive) {
+				t.Fatalf("unexpected content_sha256 %q", got)
+			}
+			entries, err := ioutil.ReadDir(filepath.Dir(dest))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected the temporary directory to be removed, found %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_extractPathTraversal(t *testing.T) {
+	archive := testTarGz(t, []testArchiveEntry{{name: "../evil.txt", body: "evil"}})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":        srv.URL + "/evil.tar.gz",
+		"extract":    true,
+		"extract_to": filepath.Join(dir, "out"),
+	})
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); !diags.HasError() {
+		t.Fatalf("expected an error")
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected nothing to be written, found %d entries", len(entries))
+	}
+}
+
+func TestValidateExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     map[string]interface{}
+		wantErr bool
+	}{
+		{"filename", map[string]interface{}{"filename": "out"}, false},
+		{"extract", map[string]interface{}{"extract": true, "extract_to": "out"}, false},
+		{"extract without extract_to", map[string]interface{}{"extract": true, "filename": "out"}, true},
+		{"extract_to without extract", map[string]interface{}{"extract_to": "out"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), tt.raw)
+			if err := validateExtract(data); (err != nil) != tt.wantErr {
+				t.Fatalf("validateExtract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	path := cty.GetAttrPath("url")
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://example.com/file.txt", false},
+		{"HTTP://example.com/file.txt", false},
+		{"files/file.txt", false},
+		{"example.com/file.txt", false},
+		{"", true},
+		{"file:///tmp/file.txt", false},
+		{"file://localhost/tmp/file.txt", false},
+		{"ftp://example.com/file.txt", true},
+		// a host names a UNC server on windows
+		{"file://server/share/file.txt", runtime.GOOS != "windows"},
+		{"http:///file.txt", true},
+		{"https://[::1/file.txt", true},
+	}
+	for _, tt := range tests {
+		diags := validateURL(tt.url, path)
+		if diags.HasError() != tt.wantErr {
+			t.Errorf("validateURL(%q) = %v, wantErr %v", tt.url, diags, tt.wantErr)
+			continue
+		}
+		if tt.wantErr && !diags[0].AttributePath.Equals(path) {
+			t.Errorf("validateURL(%q) attribute path = %#v", tt.url, diags[0].AttributePath)
+		}
+	}
+}
+
+func TestCheckRelativeURL(t *testing.T) {
+	base, err := url.Parse("https://registry.example.com/files/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		cfg     *providerConfig
+		url     string
+		wantErr bool
+	}{
+		{"absolute", nil, "https://example.com/file.txt", false},
+		{"schemeless", nil, "example.com/file.txt", true},
+		{"schemeless without base_url", &providerConfig{}, "example.com/file.txt", true},
+		{"relative with base_url", &providerConfig{baseURL: base}, "file.txt", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.checkRelativeURL(tt.url); (err != nil) != tt.wantErr {
+				t.Fatalf("checkRelativeURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_hashAlgorithm(t *testing.T) {
+	srv := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer srv.Close()
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{"md5", "5d41402abc4b2a76b9719d911017c592"},
+		{"sha1", "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{"sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{"sha512", "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":            srv.URL,
+				"headers":        map[string]interface{}{"Authorization": "Bearer secret"},
+				"filename":       filepath.Join(t.TempDir(), "dest-file"),
+				"hash_algorithm": tt.algorithm,
+			})
+			if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if got := data.Get("content_hash").(string); got != tt.want {
+				t.Fatalf("unexpected content_hash %q, want %q", got, tt.want)
+			}
+			if got := data.Get("content_sha256").(string); got != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+				t.Fatalf("unexpected content_sha256 %q", got)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_retry(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		wantErr    bool
+	}{
+		{"retried", 2, false},
+		{"exhausted", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if body, _ := ioutil.ReadAll(r.Body); string(body) != "payload" {
+					t.Errorf("attempt %d: unexpected body %q", attempts, body)
+				}
+				if attempts <= 2 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte("hello"))
+			}))
+			defer srv.Close()
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":          srv.URL,
+				"method":       http.MethodPost,
+				"request_body": "payload",
+				"filename":     filepath.Join(t.TempDir(), "dest-file"),
+				"max_retries":  tt.maxRetries,
+				"retry_wait":   "1ms",
+			})
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			if diags.HasError() != tt.wantErr {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if attempts != tt.maxRetries+1 {
+				t.Fatalf("expected %d attempts, got %d", tt.maxRetries+1, attempts)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_allowedStatusCodes(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch r.URL.Path {
+		case "/accepted":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("hello"))
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("maintenance"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name         string
+		path         string
+		allowed      []interface{}
+		want         string
+		wantErr      bool
+		wantAttempts int
+	}{
+		{"accepted", "/accepted", []interface{}{202}, "hello", false, 1},
+		{"not listed", "/accepted", []interface{}{204}, "", true, 1},
+		{"unavailable not retried", "/unavailable", []interface{}{503}, "maintenance", false, 1},
+		{"unavailable retried", "/unavailable", nil, "", true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts = 0
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":                  srv.URL + tt.path,
+				"filename":             dest,
+				"allowed_status_codes": tt.allowed,
+				"max_retries":          2,
+				"retry_wait":           "1ms",
+			})
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			if diags.HasError() != tt.wantErr {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if attempts != tt.wantAttempts {
+				t.Fatalf("expected %d attempts, got %d", tt.wantAttempts, attempts)
+			}
+			if tt.wantErr {
+				return
+			}
+			if b, _ := ioutil.ReadFile(dest); string(b) != tt.want {
+				t.Fatalf("unexpected content %q", b)
+			}
+			if got := data.Get("status_code").(int); got != tt.allowed[0].(int) {
+				t.Fatalf("unexpected status_code %d", got)
+			}
+		})
+	}
+}
+
+func TestResourceURLCreate_matchingDestination(t *testing.T) {
+	var gets int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		gets++
+		w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("/file.sha256", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s  file\n", hashHello)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	tests := []struct {
+		name     string
+		content  string
+		raw      map[string]interface{}
+		wantGets int
+	}{
+		{"expected_sha256", "hello", map[string]interface{}{"expected_sha256": hashHello}, 0},
+		{"checksum_url", "hello", map[string]interface{}{"checksum_url": srv.URL + "/file.sha256"}, 0},
+		{"different content", "goodbye", map[string]interface{}{"expected_sha256": hashHello}, 1},
+		{"no expected hash", "hello", map[string]interface{}{}, 1},
+		{"disabled", "hello", map[string]interface{}{"expected_sha256": hashHello, "skip_matching_destination": false}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gets = 0
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			if err := ioutil.WriteFile(dest, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tt.raw["url"] = srv.URL + "/file"
+			tt.raw["filename"] = dest
+			tt.raw["file_mode"] = "0600"
+			tt.raw["hash_algorithm"] = "md5"
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), tt.raw)
+			if diags := resourceURLCreate(context.Background(), data, nil); diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if gets != tt.wantGets {
+				t.Fatalf("expected %d downloads, got %d", tt.wantGets, gets)
+			}
+			if data.Id() == "" {
+				t.Fatal("expected the resource to be created")
+			}
+			if got := data.Get("content_sha256").(string); got != hashHello {
+				t.Fatalf("unexpected content_sha256 %q", got)
+			}
+			if got := data.Get("content_hash").(string); got != "5d41402abc4b2a76b9719d911017c592" {
+				t.Fatalf("unexpected content_hash %q", got)
+			}
+			if stat, err := os.Stat(dest); err != nil || stat.Mode().Perm() != 0600 {
+				t.Fatalf("unexpected destination mode: %v %v", stat, err)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      srv.URL,
+		"filename": filepath.Join(t.TempDir(), "dest-file"),
+		"timeout":  "20ms",
+	})
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); !diags.HasError() {
+		t.Fatalf("expected a timeout error")
+	}
+}
+
+func TestAccResourceURL_timeouts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer srv.Close()
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "synclocal" {
+}
+
+resource "synclocal_url" "slow" {
+	url      = %q
+	filename = %q
+	timeouts {
+		create = "1s"
+	}
+}
+`, srv.URL, filepath.Join(t.TempDir(), "dest-file")),
+				ExpectError: regexp.MustCompile(`create timed out after 1s`),
+			},
+		},
+	})
+}
+
+func TestResourceURLCreate_deadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// hold the response until the client gives up
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      srv.URL,
+		"filename": dest,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	diags := resourceURLCreate(ctx, data, nil)
+	if !diags.HasError() {
+		t.Fatalf("expected a deadline error")
+	}
+	if !strings.HasPrefix(diags[0].Summary, "create timed out after") {
+		t.Fatalf("unexpected summary %q", diags[0].Summary)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("expected no file to be written")
+	}
+}
+
+func testFileURL(t *testing.T, path string) string {
+	t.Helper()
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+func TestEnsureDownloadFile_fileURL(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      testFileURL(t, "./testdata/source-file01"),
+		"filename": dest,
+	})
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+		t.Fatalf("unexpected content %q", b)
+	}
+	if got, want := data.Get("content_sha256").(string), "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"; got != want {
+		t.Fatalf("content_sha256 = %q, want %q", got, want)
+	}
+	if got := data.Get("content_length").(int); got != 5 {
+		t.Fatalf("content_length = %d, want 5", got)
+	}
+	if etag := data.Get("etag").(string); etag != "" {
+		t.Fatalf("unexpected etag %q", etag)
+	}
+
+	// an unchanged source is not copied again
+	data.SetId("test")
+	if err := ioutil.WriteFile(dest, []byte("local"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if b, _ := ioutil.ReadFile(dest); string(b) != "local" {
+		t.Fatalf("unchanged source was copied again")
+	}
+	changed, err := remoteChanged(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Fatalf("remoteChanged() = true for an unchanged source")
+	}
+}
+
+func TestEnsureDownloadFile_fileURLMissing(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":      testFileURL(t, "./testdata/does-not-exist"),
+		"filename": dest,
+	})
+	diags := ensureDownloadFile(context.Background(), data, 0, nil)
+	if !diags.HasError() {
+		t.Fatalf("expected an error")
+	}
+	if !strings.Contains(diags[0].Summary, "does-not-exist") {
+		t.Fatalf("unexpected error: %v", diags[0].Summary)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatalf("destination should not exist")
+	}
+}
+
+func TestEnsureDownloadFile_mirrorURLs(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+	corrupt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("corrupt"))
+	}))
+	defer corrupt.Close()
+	good := httptest.NewServer(testURLHandler(t, "./testdata/source-file01"))
+	defer good.Close()
+	tests := []struct {
+		name    string
+		url     string
+		mirrors []interface{}
+		want    string
+		wantErr bool
+	}{
+		{"primary", good.URL, []interface{}{broken.URL}, good.URL, false},
+		{"second mirror", broken.URL, []interface{}{corrupt.URL, good.URL}, good.URL, false},
+		{"all fail", broken.URL, []interface{}{corrupt.URL}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":             tt.url,
+				"mirror_urls":     tt.mirrors,
+				"filename":        dest,
+				"expected_sha256": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+				"headers":         map[string]interface{}{"Authorization": "Bearer secret"},
+			})
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			if tt.wantErr {
+				if !diags.HasError() {
+					t.Fatalf("expected an error")
+				}
+				if len(diags) != 2 || !strings.HasPrefix(diags[0].Summary, broken.URL) || !strings.HasPrefix(diags[1].Summary, corrupt.URL) {
+					t.Fatalf("expected an error for each url, got %v", diags)
+				}
+				if _, err := os.Stat(dest); !os.IsNotExist(err) {
+					t.Fatalf("destination should not exist")
+				}
+				return
+			}
+			if diags.HasError() {
+				t.Fatalf("unexpected error: %v", diags)
+			}
+			if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+				t.Fatalf("unexpected content %q", b)
+			}
+			if got := data.Get("source_url").(string); got != tt.want {
+				t.Fatalf("source_url = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiagResponseError_largeBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		truncated bool
+	}{
+		{"small", "not found", false},
+		{"at limit", strings.Repeat("x", maxErrorBodySize), false},
+		{"large", strings.Repeat("x", 4*1024*1024), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Header:     http.Header{"Content-Type": []string{"text/plain"}},
+				Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+			}
+			diags := diagResponseError(resp, "failed")
+			if len(diags) != 1 {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			detail := diags[0].Detail
+			if !tt.truncated {
+				if detail != tt.body {
+					t.Fatalf("detail was modified")
+				}
+				return
+			}
+			if !strings.HasSuffix(detail, "(response body truncated)") {
+				t.Fatalf("missing truncation note")
+			}
+			if len(detail) > maxErrorBodySize+64 {
+				t.Fatalf("detail is %d bytes, expected it to be capped", len(detail))
+			}
+		})
+	}
+}
+
+func TestDiagResponseError_contentType(t *testing.T) {
+	binary := string([]byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00})
+	tests := []struct {
+		name          string
+		contentType   string
+		body          string
+		contentLength int64
+		want          string
+	}{
+		{
+			name:          "binary",
+			contentType:   "application/octet-stream",
+			body:          binary,
+			contentLength: 6,
+			want:          "The response body is not shown since it is not text (application/octet-stream, 6 bytes).",
+		},
+		{
+			name:          "large binary with length",
+			contentType:   "image/png",
+			body:          strings.Repeat("\x00", 4*1024*1024),
+			contentLength: 4 * 1024 * 1024,
+			want:          "The response body is not shown since it is not text (image/png, 4194304 bytes).",
+		},
+		{
+			name:          "large binary without length",
+			contentType:   "application/zip",
+			body:          strings.Repeat("\x00", 4*1024*1024),
+			contentLength: -1,
+			want:          fmt.Sprintf("The response body is not shown since it is not text (application/zip, more than %d bytes).", maxErrorBodySize),
+		},
+		{
+			name:          "sniffed binary",
+			body:          binary,
+			contentLength: -1,
+			want:          "The response body is not shown since it is not text (application/x-gzip, 6 bytes).",
+		},
+		{
+			name:          "sniffed text",
+			body:          "service unavailable",
+			contentLength: -1,
+			want:          "service unavailable",
+		},
+		{
+			name:          "empty",
+			contentType:   "application/octet-stream",
+			contentLength: 0,
+			want:          "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &countingReader{r: strings.NewReader(tt.body)}
+			resp := &http.Response{
+				StatusCode:    http.StatusInternalServerError,
+				Header:        http.Header{},
+				Body:          ioutil.NopCloser(body),
+				ContentLength: tt.contentLength,
+			}
+			if tt.contentType != "" {
+				resp.Header.Set("Content-Type", tt.contentType)
+			}
+			diags := diagResponseError(resp, "failed")
+			if len(diags) != 1 {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if diags[0].Detail != tt.want {
+				t.Fatalf("detail = %q, want %q", diags[0].Detail, tt.want)
+			}
+			if body.n > maxErrorBodySize+1 {
+				t.Fatalf("read %d bytes of the body, expected at most %d", body.n, maxErrorBodySize+1)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_maxBytes(t *testing.T) {
+	body := strings.Repeat("x", 2048)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// flushing before the body is written prevents a Content-Length
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name     string
+		path     string
+		maxBytes int
+		wantErr  bool
+	}{
+		{"content-length", "/", 1024, true},
+		{"chunked", "/chunked", 1024, true},
+		{"at limit", "/chunked", 2048, false},
+		{"unlimited", "/", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "dest-file")
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":       srv.URL + tt.path,
+				"filename":  dest,
+				"max_bytes": tt.maxBytes,
+			})
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			if diags.HasError() != tt.wantErr {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if !tt.wantErr {
+				return
+			}
+			if !strings.Contains(diags[0].Summary, "max_bytes") {
+				t.Fatalf("unexpected error: %s", diags[0].Summary)
+			}
+			files, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 0 {
+				t.Fatalf("expected the partial file to be removed, found %s", files[0].Name())
+			}
+		})
+	}
+}
+
+func TestNormalizeHTTPDate(t *testing.T) {
+	const want = "Sun, 06 Nov 1994 08:49:37 GMT"
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"RFC1123 GMT", "Sun, 06 Nov 1994 08:49:37 GMT", want},
+		{"RFC1123Z", "Sun, 06 Nov 1994 08:49:37 +0000", want},
+		{"RFC1123Z offset", "Sun, 06 Nov 1994 09:49:37 +0100", want},
+		{"RFC850", "Sunday, 06-Nov-94 08:49:37 GMT", want},
+		{"ANSI C", "Sun Nov  6 08:49:37 1994", want},
+		{"whitespace", " Sun, 06 Nov 1994 08:49:37 GMT ", want},
+		{"invalid", "yesterday", "yesterday"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeHTTPDate(tt.in); got != tt.want {
+				t.Fatalf("normalizeHTTPDate(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureDownloadFile_forceDownload(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+			t.Errorf("request %d: unexpected conditional headers %v", requests, r.Header)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	dest := filepath.Join(t.TempDir(), "dest-file")
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":            srv.URL,
+		"filename":       dest,
+		"force_download": true,
+	})
+	for i := 0; i < 2; i++ {
+		if err := ioutil.WriteFile(dest, []byte("stale"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if diags := ensureDownloadFile(context.Background(), data, 0, nil); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+		if b, _ := ioutil.ReadFile(dest); string(b) != "hello" {
+			t.Fatalf("request %d: unexpected content %q", i, b)
+		}
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests, got %d", requests)
+	}
+}
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// defaultRetryWait is the wait between retries when retry_wait is not
+// configured.
+const defaultRetryWait = time.Second
+
+func timeoutSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		ValidateFunc: validateDuration,
+		Description:  "Time limit for the request, including retries and reading the response, such as \"30s\". Defaults to the provider request_timeout.",
+	}
+}
+
+func maxRetriesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeInt,
+		Optional:     true,
+		Default:      -1,
+		ValidateFunc: validation.IntAtLeast(-1),
+		Description:  "Number of times to retry a request that fails with a connection error, a 429 or a 5xx status. -1 uses the provider max_retries.",
+	}
+}
+
+func retryWaitSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		ValidateFunc: validateDuration,
+		Description:  "Time to wait between retries, such as \"500ms\". Defaults to the provider retry_wait.",
+	}
+}
+
+func validateDuration(v interface{}, k string) (warnings []string, errors []error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+	if d, err := time.ParseDuration(s); err != nil {
+		errors = append(errors, fmt.Errorf("%s is not a valid duration: %w", k, err))
+	} else if d < 0 {
+		errors = append(errors, fmt.Errorf("%s must not be negative", k))
+	}
+	return
+}
+
+// httpSettings are the timeout and retry settings of a request, resolved
+// from the resource and the provider defaults.
+type httpSettings struct {
+	timeout    time.Duration
+	maxRetries int
+	retryWait  time.Duration
+}
+
+// httpSettings resolves the timeout, max_retries and retry_wait of data,
+// using the provider value for any that are not set.
+func (c *providerConfig) httpSettings(data resourceGetter) (httpSettings, error) {
+	s := httpSettings{retryWait: defaultRetryWait}
+	if c != nil {
+		s = httpSettings{timeout: c.requestTimeout, maxRetries: c.maxRetries, retryWait: c.retryWait}
+	}
+	var err error
+	if v, ok := data.GetOk("timeout"); ok {
+		if s.timeout, err = time.ParseDuration(v.(string)); err != nil {
+			return s, fmt.Errorf("timeout is not a valid duration: %w", err)
+		}
+	}
+	if v, ok := data.Get("max_retries").(int); ok && v >= 0 {
+		s.maxRetries = v
+	}
+	if v, ok := data.GetOk("retry_wait"); ok {
+		if s.retryWait, err = time.ParseDuration(v.(string)); err != nil {
+			return s, fmt.Errorf("retry_wait is not a valid duration: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// retryTransport retries requests that fail with a connection error, a 429
+// or a 5xx status, unless the status is in allowed. A request body is
+// rewound with GetBody before a retry.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	wait       time.Duration
+	allowed    map[int]bool
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.maxRetries || !retryable(resp, err) || (err == nil && t.allowed[resp.StatusCode]) {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			// the body cannot be sent again
+			return resp, err
+		}
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+		}
+		logEvent("DEBUG", "retrying request", "url", req.URL.Redacted(), "attempt", attempt+1, "max_retries", t.maxRetries, "reason", reason, "wait", t.wait)
+		if resp != nil {
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(t.wait):
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
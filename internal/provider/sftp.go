@@ -0,0 +1,369 @@
+package provider
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"os"
+	"time"
+)
+
+// sftpClient is a minimal client for version 3 of the SFTP protocol, which
+// is all synclocal_sftp needs to stat and download a single file. Requests
+// are sent one at a time.
+type sftpClient struct {
+	session *ssh.Session
+	w       io.WriteCloser
+	r       io.Reader
+	nextID  uint32
+}
+
+// SFTP packet types from draft-ietf-secsh-filexfer-02
+const (
+	sftpInit    = 1
+	sftpVersion = 2
+	sftpOpen    = 3
+	sftpClose   = 4
+	sftpRead    = 5
+	sftpStat    = 17
+	sftpStatus  = 101
+	sftpHandle  = 102
+	sftpData    = 103
+	sftpAttrs   = 105
+)
+
+// SFTP status codes
+const (
+	sftpOK               = 0
+	sftpEOF              = 1
+	sftpNoSuchFile       = 2
+	sftpPermissionDenied = 3
+)
+
+// SFTP attribute flags
+const (
+	sftpAttrSize        = 0x00000001
+	sftpAttrUIDGID      = 0x00000002
+	sftpAttrPermissions = 0x00000004
+	sftpAttrACModTime   = 0x00000008
+	sftpAttrExtended    = 0x80000000
+)
+
+const (
+	sftpOpenRead = 0x00000001
+	// sftpChunkSize is the size of each read request. 32KB is the largest
+	// size every server must accept.
+	sftpChunkSize = 32 * 1024
+	// sftpMaxPacket bounds the packets accepted from the server.
+	sftpMaxPacket = 256 * 1024
+)
+
+// sftpStatusError is a status response other than OK.
+type sftpStatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *sftpStatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("sftp: %s (status %d)", e.Message, e.Code)
+	}
+	return fmt.Sprintf("sftp: status %d", e.Code)
+}
+
+// Is lets errors.Is match the os errors of the status.
+func (e *sftpStatusError) Is(target error) bool {
+	switch e.Code {
+	case sftpNoSuchFile:
+		return target == os.ErrNotExist
+	case sftpPermissionDenied:
+		return target == os.ErrPermission
+	}
+	return false
+}
+
+// sftpFileInfo holds the attributes of a remote file that are used.
+type sftpFileInfo struct {
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+}
+
+// newSFTPClient starts the sftp subsystem on conn.
+func newSFTPClient(conn *ssh.Client) (*sftpClient, error) {
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	w, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("the server does not support the sftp subsystem: %w", err)
+	}
+	c := &sftpClient{session: session, w: w, r: r}
+	if err := c.init(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *sftpClient) init() error {
+	// INIT has no request id; the version takes its place
+	if err := writeSFTPPacket(c.w, sftpInit, appendUint32(nil, 3)); err != nil {
+		return err
+	}
+	typ, payload, err := readSFTPPacket(c.r)
+	if err != nil {
+		return err
+	}
+	if typ != sftpVersion || len(payload) < 4 {
+		return fmt.Errorf("sftp: unexpected packet type %d in reply to init", typ)
+	}
+	if v := binary.BigEndian.Uint32(payload); v < 3 {
+		return fmt.Errorf("sftp: server protocol version %d is not supported", v)
+	}
+	return nil
+}
+
+// Close ends the sftp session. The ssh connection is left open.
+func (c *sftpClient) Close() error {
+	c.w.Close()
+	return c.session.Close()
+}
+
+// request sends a packet with a new request id and returns the reply.
+func (c *sftpClient) request(typ byte, payload []byte) (byte, []byte, error) {
+	c.nextID++
+	id := c.nextID
+	if err := writeSFTPPacket(c.w, typ, append(appendUint32(nil, id), payload...)); err != nil {
+		return 0, nil, err
+	}
+	rtyp, reply, err := readSFTPPacket(c.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	rid, reply, ok := consumeUint32(reply)
+	if !ok || rid != id {
+		return 0, nil, fmt.Errorf("sftp: reply for request %d does not match request %d", rid, id)
+	}
+	return rtyp, reply, nil
+}
+
+// Stat returns the attributes of path, following symlinks.
+func (c *sftpClient) Stat(path string) (*sftpFileInfo, error) {
+	typ, reply, err := c.request(sftpStat, appendString(nil, path))
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case sftpAttrs:
+		fi, _, err := parseSFTPAttrs(reply)
+		return fi, err
+	case sftpStatus:
+		return nil, parseSFTPStatus(reply)
+	}
+	return nil, fmt.Errorf("sftp: unexpected packet type %d in reply to stat", typ)
+}
+
+// Open opens path for reading.
+func (c *sftpClient) Open(path string) (*sftpFile, error) {
+	payload := appendString(nil, path)
+	payload = appendUint32(payload, sftpOpenRead)
+	payload = appendUint32(payload, 0) // no attributes
+	typ, reply, err := c.request(sftpOpen, payload)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case sftpHandle:
+		handle, _, ok := consumeString(reply)
+		if !ok {
+			return nil, errors.New("sftp: malformed handle")
+		}
+		return &sftpFile{c: c, handle: handle}, nil
+	case sftpStatus:
+		return nil, parseSFTPStatus(reply)
+	}
+	return nil, fmt.Errorf("sftp: unexpected packet type %d in reply to open", typ)
+}
+
+// sftpFile is a remote file open for reading.
+type sftpFile struct {
+	c      *sftpClient
+	handle string
+	offset uint64
+}
+
+func (f *sftpFile) Read(p []byte) (int, error) {
+	if len(p) > sftpChunkSize {
+		p = p[:sftpChunkSize]
+	}
+	payload := appendString(nil, f.handle)
+	payload = appendUint64(payload, f.offset)
+	payload = appendUint32(payload, uint32(len(p)))
+	typ, reply, err := f.c.request(sftpRead, payload)
+	if err != nil {
+		return 0, err
+	}
+	switch typ {
+	case sftpData:
+		data, _, ok := consumeString(reply)
+		if !ok || len(data) > len(p) {
+			return 0, errors.New("sftp: malformed data")
+		}
+		n := copy(p, data)
+		f.offset += uint64(n)
+		return n, nil
+	case sftpStatus:
+		err := parseSFTPStatus(reply)
+		var serr *sftpStatusError
+		if errors.As(err, &serr) && serr.Code == sftpEOF {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	return 0, fmt.Errorf("sftp: unexpected packet type %d in reply to read", typ)
+}
+
+func (f *sftpFile) Close() error {
+	typ, reply, err := f.c.request(sftpClose, appendString(nil, f.handle))
+	if err != nil {
+		return err
+	}
+	if typ != sftpStatus {
+		return fmt.Errorf("sftp: unexpected packet type %d in reply to close", typ)
+	}
+	return parseSFTPStatus(reply)
+}
+
+// parseSFTPStatus returns nil for an OK status and a *sftpStatusError
+// otherwise.
+func parseSFTPStatus(b []byte) error {
+	code, b, ok := consumeUint32(b)
+	if !ok {
+		return errors.New("sftp: malformed status")
+	}
+	if code == sftpOK {
+		return nil
+	}
+	// the message was added in version 3, but some servers leave it out
+	msg, _, _ := consumeString(b)
+	return &sftpStatusError{Code: code, Message: msg}
+}
+
+// parseSFTPAttrs parses an ATTRS structure and returns the rest of b.
+func parseSFTPAttrs(b []byte) (*sftpFileInfo, []byte, error) {
+	var fi sftpFileInfo
+	flags, b, ok := consumeUint32(b)
+	if !ok {
+		return nil, nil, errors.New("sftp: malformed attributes")
+	}
+	if flags&sftpAttrSize != 0 {
+		var size uint64
+		if size, b, ok = consumeUint64(b); !ok {
+			return nil, nil, errors.New("sftp: malformed size attribute")
+		}
+		fi.Size = int64(size)
+	}
+	if flags&sftpAttrUIDGID != 0 {
+		if len(b) < 8 {
+			return nil, nil, errors.New("sftp: malformed uid attribute")
+		}
+		b = b[8:]
+	}
+	if flags&sftpAttrPermissions != 0 {
+		var perm uint32
+		if perm, b, ok = consumeUint32(b); !ok {
+			return nil, nil, errors.New("sftp: malformed permissions attribute")
+		}
+		fi.Mode = os.FileMode(perm) & os.ModePerm
+	}
+	if flags&sftpAttrACModTime != 0 {
+		if len(b) < 8 {
+			return nil, nil, errors.New("sftp: malformed time attribute")
+		}
+		fi.ModTime = time.Unix(int64(binary.BigEndian.Uint32(b[4:8])), 0).UTC()
+		b = b[8:]
+	}
+	if flags&sftpAttrExtended != 0 {
+		var count uint32
+		if count, b, ok = consumeUint32(b); !ok {
+			return nil, nil, errors.New("sftp: malformed extended attributes")
+		}
+		for i := uint32(0); i < 2*count; i++ {
+			if _, b, ok = consumeString(b); !ok {
+				return nil, nil, errors.New("sftp: malformed extended attributes")
+			}
+		}
+	}
+	return &fi, b, nil
+}
+
+func writeSFTPPacket(w io.Writer, typ byte, payload []byte) error {
+	b := appendUint32(make([]byte, 0, 5+len(payload)), uint32(1+len(payload)))
+	b = append(b, typ)
+	_, err := w.Write(append(b, payload...))
+	return err
+}
+
+func readSFTPPacket(r io.Reader) (byte, []byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n == 0 || n > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, nil, err
+	}
+	return b[0], b[1:], nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v>>32)), uint32(v))
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(appendUint32(b, uint32(len(s))), s...)
+}
+
+func consumeUint32(b []byte) (uint32, []byte, bool) {
+	if len(b) < 4 {
+		return 0, b, false
+	}
+	return binary.BigEndian.Uint32(b), b[4:], true
+}
+
+func consumeUint64(b []byte) (uint64, []byte, bool) {
+	if len(b) < 8 {
+		return 0, b, false
+	}
+	return binary.BigEndian.Uint64(b), b[8:], true
+}
+
+func consumeString(b []byte) (string, []byte, bool) {
+	n, rest, ok := consumeUint32(b)
+	if !ok || uint64(len(rest)) < uint64(n) {
+		return "", b, false
+	}
+	return string(rest[:n]), rest[n:], true
+}
@@ -0,0 +1,323 @@
+package provider
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+func awsSigV4Schema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"access_key": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			Description: "AWS access key ID. Defaults to AWS_ACCESS_KEY_ID, then the shared credentials file.",
+		},
+		"secret_key": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			Description: "AWS secret access key. Required with access_key.",
+		},
+		"session_token": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			Description: "AWS session token for temporary credentials",
+		},
+		"region": {
+			Type:        schema.TypeString,
+			Required:    true,
+			Description: "AWS region of the endpoint, such as \"us-east-1\"",
+		},
+		"service": {
+			Type:        schema.TypeString,
+			Optional:    true,
+			Default:     "s3",
+			Description: "AWS service name used in the signature",
+		},
+	}
+}
+
+type awsCredentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// signRequest signs req with the aws_sigv4 block of data, if it is set.
+func signRequest(req *http.Request, data resourceGetter) error {
+	v, ok := data.GetOk("aws_sigv4")
+	if !ok {
+		return nil
+	}
+	blocks := v.([]interface{})
+	if len(blocks) == 0 || blocks[0] == nil {
+		return nil
+	}
+	block := blocks[0].(map[string]interface{})
+	creds := awsCredentials{
+		accessKey:    block["access_key"].(string),
+		secretKey:    block["secret_key"].(string),
+		sessionToken: block["session_token"].(string),
+	}
+	if creds.accessKey != "" && creds.secretKey == "" {
+		return fmt.Errorf("aws_sigv4 secret_key is required with access_key")
+	}
+	if creds.accessKey == "" {
+		var err error
+		if creds, err = defaultAWSCredentials(); err != nil {
+			return err
+		}
+	}
+	return signV4(req, creds, block["region"].(string), block["service"].(string), time.Now())
+}
+
+// defaultAWSCredentials reads credentials from the AWS environment variables
+// or the shared credentials file.
+func defaultAWSCredentials() (awsCredentials, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return awsCredentials{
+			accessKey:    id,
+			secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("no AWS credentials configured: %w", err)
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return awsCredentials{}, fmt.Errorf("no AWS credentials configured: set access_key and secret_key, AWS_ACCESS_KEY_ID, or the shared credentials file")
+	}
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("could not read AWS credentials: %w", err)
+	}
+	defer f.Close()
+	creds, err := parseAWSCredentials(f, profile)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("could not read AWS credentials from %q: %w", path, err)
+	}
+	return creds, nil
+}
+
+// parseAWSCredentials reads profile from a shared credentials file.
+func parseAWSCredentials(r io.Reader, profile string) (awsCredentials, error) {
+	var creds awsCredentials
+	var section string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			continue
+		}
+		value := strings.TrimSpace(line[eq+1:])
+		switch strings.TrimSpace(line[:eq]) {
+		case "aws_access_key_id":
+			creds.accessKey = value
+		case "aws_secret_access_key":
+			creds.secretKey = value
+		case "aws_session_token":
+			creds.sessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return creds, err
+	}
+	if creds.accessKey == "" || creds.secretKey == "" {
+		return creds, fmt.Errorf("profile %q has no aws_access_key_id and aws_secret_access_key", profile)
+	}
+	return creds, nil
+}
+
+// unsignedHeaders are left out of the signature since they may be changed
+// or added after signing.
+var unsignedHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"accept-encoding": true,
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req.
+func signV4(req *http.Request, creds awsCredentials, region, service string, now time.Time) error {
+	payloadHash, err := requestPayloadHash(req)
+	if err != nil {
+		return err
+	}
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	if req.Host != "" {
+		headers["host"] = req.Host
+	}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if unsignedHeaders[k] {
+			continue
+		}
+		values := make([]string, len(v))
+		for i := range v {
+			values[i] = strings.Join(strings.Fields(v[i]), " ")
+		}
+		headers[k] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURIPath(req.URL.EscapedPath(), service != "s3"),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKey, scope, signedHeaders, signature))
+	return nil
+}
+
+// requestPayloadHash hashes the request body without consuming it.
+func requestPayloadHash(req *http.Request) (string, error) {
+	if req.Body == nil || req.GetBody == nil {
+		return sha256Hex(nil), nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	return sha256Hex(b), nil
+}
+
+// canonicalURIPath encodes each segment of the escaped path as SigV4
+// requires. Services other than S3 encode the path a second time.
+func canonicalURIPath(escaped string, double bool) string {
+	if escaped == "" {
+		return "/"
+	}
+	segments := strings.Split(escaped, "/")
+	for i, s := range segments {
+		if unescaped, err := url.PathUnescape(s); err == nil {
+			s = unescaped
+		}
+		s = awsURIEscape(s)
+		if double {
+			s = awsURIEscape(s)
+		}
+		segments[i] = s
+	}
+	return strings.Join(segments, "/")
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return awsURIEscape(keys[i]) < awsURIEscape(keys[j]) })
+	var pairs []string
+	for _, k := range keys {
+		vs := make([]string, len(values[k]))
+		for i, v := range values[k] {
+			vs[i] = awsURIEscape(v)
+		}
+		sort.Strings(vs)
+		for _, v := range vs {
+			pairs = append(pairs, awsURIEscape(k)+"="+v)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEscape percent-encodes everything except the unreserved characters
+// of RFC 3986.
+func awsURIEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io"
+	"time"
+)
+
+// defaultOperationTimeout bounds resources that copy or download files when
+// no timeouts block is set. It is longer than the SDK default of 20 minutes,
+// since copies did not have a deadline before the timeouts block existed.
+const defaultOperationTimeout = 60 * time.Minute
+
+// copyTimeouts are the timeouts of resources that copy or download files.
+// The SDK puts the deadline on the context passed to each operation.
+func copyTimeouts() *schema.ResourceTimeout {
+	return &schema.ResourceTimeout{
+		Create: schema.DefaultTimeout(defaultOperationTimeout),
+		Read:   schema.DefaultTimeout(defaultOperationTimeout),
+		Update: schema.DefaultTimeout(defaultOperationTimeout),
+		Delete: schema.DefaultTimeout(defaultOperationTimeout),
+	}
+}
+
+// timeoutDiagnostics adds a diagnostic naming the timeout to diags if they
+// failed because the deadline of ctx passed.
+func timeoutDiagnostics(ctx context.Context, diags diag.Diagnostics, data *schema.ResourceData, key string) diag.Diagnostics {
+	if !diags.HasError() || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return diags
+	}
+	return append(diag.Diagnostics{{
+		Severity: diag.Error,
+		Summary:  fmt.Sprintf("%s timed out after %s", key, data.Timeout(key)),
+		Detail:   fmt.Sprintf("The operation did not finish in time. The limit can be raised with the %s argument of the timeouts block.", key),
+	}}, diags...)
+}
+
+// contextReader fails reads once ctx is done, so a long copy stops at its
+// deadline.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// newlineOnlyChange reports whether ignore_trailing_newline is set and the
+// content read from src only differs from the file dest by a single trailing
+// newline. Both have to be text, unless text is set.
+func newlineOnlyChange(data resourceGetter, src io.Reader, dest string) (bool, error) {
+	if v, _ := data.Get("ignore_trailing_newline").(bool); !v {
+		return false, nil
+	}
+	text, _ := data.Get("text").(bool)
+	srcHash, ok, err := hashTrimmedText(src, text)
+	if err != nil || !ok {
+		return false, err
+	}
+	f, err := os.Open(dest)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	destHash, ok, err := hashTrimmedText(f, text)
+	if err != nil {
+		return false, fmt.Errorf("could not read %q: %w", dest, err)
+	}
+	return ok && destHash == srcHash, nil
+}
+
+// newlineOnlyChangeFile is newlineOnlyChange for the source file, read the
+// way it is copied.
+func newlineOnlyChangeFile(data resourceGetter, source, dest string) (bool, error) {
+	if v, _ := data.Get("ignore_trailing_newline").(bool); !v {
+		return false, nil
+	}
+	f, err := os.Open(source)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	r, err := decompressionFrom(data).wrap(f)
+	if err != nil {
+		return false, fmt.Errorf("could not read %q: %w", source, err)
+	}
+	if r, err = lineEndingsFrom(data).wrap(r); err != nil {
+		return false, fmt.Errorf("could not read %q: %w", source, err)
+	}
+	return newlineOnlyChange(data, r, dest)
+}
+
+// hashTrimmedText hashes the content of r without a single trailing LF or
+// CRLF. ok is false if the first bytes of r do not look like text and text
+// is not set.
+func hashTrimmedText(r io.Reader, text bool) (hash string, ok bool, err error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return "", false, err
+	}
+	if !text && !isTextual(http.DetectContentType(head)) {
+		return "", false, nil
+	}
+	h := sha256.New()
+	w := &trimNewlineWriter{w: h}
+	if _, err := io.Copy(w, br); err != nil {
+		return "", false, err
+	}
+	if err := w.flush(); err != nil {
+		return "", false, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), true, nil
+}
+
+// trimNewlineWriter writes everything to w but the last two bytes, which
+// are held back until more is written. flush writes them unless they are a
+// trailing newline.
+type trimNewlineWriter struct {
+	w    io.Writer
+	tail []byte
+}
+
+func (t *trimNewlineWriter) Write(p []byte) (int, error) {
+	if len(p) < 2 {
+		t.tail = append(t.tail, p...)
+		if n := len(t.tail) - 2; n > 0 {
+			if _, err := t.w.Write(t.tail[:n]); err != nil {
+				return 0, err
+			}
+			t.tail = append(t.tail[:0], t.tail[n:]...)
+		}
+		return len(p), nil
+	}
+	if _, err := t.w.Write(t.tail); err != nil {
+		return 0, err
+	}
+	if _, err := t.w.Write(p[:len(p)-2]); err != nil {
+		return 0, err
+	}
+	t.tail = append(t.tail[:0], p[len(p)-2:]...)
+	return len(p), nil
+}
+
+func (t *trimNewlineWriter) flush() error {
+	tail := t.tail
+	if bytes.HasSuffix(tail, []byte("\r\n")) {
+		tail = tail[:len(tail)-2]
+	} else if bytes.HasSuffix(tail, []byte("\n")) {
+		tail = tail[:len(tail)-1]
+	}
+	_, err := t.w.Write(tail)
+	return err
+}
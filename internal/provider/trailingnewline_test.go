@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+)
+
+func TestHashTrimmedText(t *testing.T) {
+	want, _, _ := hashTrimmedText(bytes.NewReader([]byte("hello\nworld")), false)
+	tests := []struct {
+		name    string
+		content string
+		text    bool
+		same    bool
+		ok      bool
+	}{
+		{"no newline", "hello\nworld", false, true, true},
+		{"lf", "hello\nworld\n", false, true, true},
+		{"crlf", "hello\nworld\r\n", false, true, true},
+		{"two newlines", "hello\nworld\n\n", false, false, true},
+		{"cr", "hello\nworld\r", false, false, true},
+		{"binary", "\x00\x01hello\nworld\n", false, false, false},
+		{"binary as text", "\x00\x01hello\nworld\n", true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// one byte reads split a CRLF across writes
+			got, ok, err := hashTrimmedText(iotest.OneByteReader(bytes.NewReader([]byte(tt.content))), tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.ok {
+				t.Fatalf("unexpected ok %v", ok)
+			}
+			if same := got == want; ok && same != tt.same {
+				t.Fatalf("expected same hash to be %v", tt.same)
+			}
+			whole, _, _ := hashTrimmedText(bytes.NewReader([]byte(tt.content)), tt.text)
+			if whole != got {
+				t.Fatalf("hash depends on the read size: %q != %q", whole, got)
+			}
+		})
+	}
+}
+
+func TestResourceFile_ignoreTrailingNewline(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		dest   string
+		ignore bool
+		want   string
+	}{
+		{"added", "hello\n", "hello", true, "hello"},
+		{"removed", "hello", "hello\r\n", true, "hello\r\n"},
+		{"not ignored", "hello\n", "hello", false, "hello\n"},
+		{"content changed", "goodbye\n", "hello", true, "goodbye\n"},
+		{"binary", "\x00hello\n", "\x00hello", true, "\x00hello\n"},
+	}
+	for _, tt := range tests {
+		for _, inline := range []bool{false, true} {
+			name := tt.name
+			if inline {
+				name += " inline"
+			}
+			t.Run(name, func(t *testing.T) {
+				dir := t.TempDir()
+				dest := filepath.Join(dir, "dest")
+				// inline content gets the default mode, which would be a change
+				// of its own
+				if err := ioutil.WriteFile(dest, []byte(tt.dest), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if inline {
+					if err := os.Chmod(dest, defaultContentFileMode); err != nil {
+						t.Fatal(err)
+					}
+				}
+				raw := map[string]interface{}{
+					"destination":             dest,
+					"ignore_trailing_newline": tt.ignore,
+				}
+				if inline {
+					raw["content"] = tt.source
+				} else {
+					source := filepath.Join(dir, "source")
+					if err := ioutil.WriteFile(source, []byte(tt.source), 0644); err != nil {
+						t.Fatal(err)
+					}
+					raw["source"] = source
+				}
+				data := schema.TestResourceDataRaw(t, resourceFileSchema(), raw)
+				id, err := fileToID(dest)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data.SetId(id)
+				data.Set("managed", true)
+				if diags := resourceFileRead(context.Background(), data, nil); diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				diff, err := resourceFile().Diff(context.Background(), data.State(), terraform.NewResourceConfigRaw(raw), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				changed := diff != nil && diff.Attributes["content_sha256"] != nil
+				if wantChange := tt.want != tt.dest; changed != wantChange {
+					t.Fatalf("expected content_sha256 change to be %v, got %#v", wantChange, diff)
+				}
+				if diags := resourceFileUpdate(context.Background(), data, nil); diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				if b, _ := ioutil.ReadFile(dest); string(b) != tt.want {
+					t.Fatalf("unexpected content %q, want %q", b, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// defaultMaxIdleConns is the number of idle connections kept for reuse when
+// max_idle_conns is not configured.
+const defaultMaxIdleConns = 100
+
+// newTransport builds the transport of url requests from the provider
+// settings. max_idle_conns applies per host too, since many resources
+// usually download from the same few hosts.
+func (c *providerConfig) newTransport(forceHTTP2 bool, maxIdleConns int) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = c.proxyFunc()
+	t.TLSClientConfig = &tls.Config{MinVersion: c.tlsMinVersion()}
+	t.ForceAttemptHTTP2 = forceHTTP2
+	t.MaxIdleConns = maxIdleConns
+	t.MaxIdleConnsPerHost = maxIdleConns
+	return t
+}
+
+// httpTransport returns the transport shared by the url requests of every
+// resource and data source, so their connections are reused. It must not be
+// modified; clone it instead.
+func (c *providerConfig) httpTransport() *http.Transport {
+	if c == nil || c.transport == nil {
+		return c.newTransport(true, defaultMaxIdleConns)
+	}
+	return c.transport
+}
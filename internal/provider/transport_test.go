@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestProviderTransport_reusesConnections(t *testing.T) {
+	var mu sync.Mutex
+	conns := 0
+	srv := httptest.NewUnstartedServer(testURLHandler(t, "./testdata/source-file01"))
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+	meta, diags := providerConfigure("test")(context.Background(), schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{}))
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	dir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+			"url":      srv.URL,
+			"filename": filepath.Join(dir, fmt.Sprintf("dest-%d", i)),
+			"headers":  map[string]interface{}{"Authorization": "Bearer secret"},
+		})
+		if diags := ensureDownloadFile(context.Background(), data, 0, configFromMeta(meta)); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if conns != 1 {
+		t.Fatalf("expected the downloads to share one connection, got %d", conns)
+	}
+}
+
+func TestProviderConfigure_transport(t *testing.T) {
+	meta, diags := providerConfigure("test")(context.Background(), schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"force_http2":    false,
+		"max_idle_conns": 4,
+	}))
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	cfg := configFromMeta(meta)
+	transport := cfg.httpTransport()
+	if transport.ForceAttemptHTTP2 || transport.MaxIdleConns != 4 || transport.MaxIdleConnsPerHost != 4 {
+		t.Fatalf("unexpected transport settings: force_http2 %v, max_idle_conns %d/%d", transport.ForceAttemptHTTP2, transport.MaxIdleConns, transport.MaxIdleConnsPerHost)
+	}
+	// a resource that verifies differently gets a transport of its own
+	data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+		"url":                  "https://example.com/file",
+		"filename":             "dest",
+		"insecure_skip_verify": true,
+	})
+	c, diags := newHTTPClient(data, cfg)
+	if diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if c.Transport.(*retryTransport).base == transport {
+		t.Fatal("insecure_skip_verify modified the shared transport")
+	}
+	if transport.TLSClientConfig.InsecureSkipVerify {
+		t.Fatal("insecure_skip_verify was set on the shared transport")
+	}
+}
@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	formatNone = "none"
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatAuto = "auto"
+)
+
+func validateSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:          schema.TypeString,
+		Optional:      true,
+		Default:       formatNone,
+		ValidateFunc:  validation.StringInSlice([]string{formatNone, formatJSON, formatYAML, formatAuto}, false),
+		ConflictsWith: []string{"extract"},
+		Description:   "Check that the downloaded file is well-formed: none, json, yaml, or auto to pick json or yaml from the Content-Type of the response and skip other types. A file that does not parse is removed and the apply fails.",
+	}
+}
+
+// documentFormat returns the format to check a download with the content
+// type contentType against, or "" to not check it.
+func documentFormat(data resourceGetter, contentType string) string {
+	v, _ := data.Get("validate").(string)
+	switch v {
+	case formatJSON, formatYAML:
+		return v
+	case formatAuto:
+		switch getNormalizedMediaType(contentType) {
+		case "application/json", "text/json":
+			return formatJSON
+		case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+			return formatYAML
+		}
+	}
+	return ""
+}
+
+// checkDownloadFormat parses dest in the format of validate, and removes it
+// if it is not well-formed.
+func checkDownloadFormat(data resourceGetter, dest, contentType string) diag.Diagnostics {
+	format := documentFormat(data, contentType)
+	if format == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(dest)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not read %q: %w", dest, err))
+	}
+	if err = parseDocument(format, b); err == nil {
+		return nil
+	}
+	logEvent("DEBUG", "removing malformed download", "destination", dest, "format", format)
+	detail := fmt.Sprintf("%s. %q was removed.", err, dest)
+	if rmErr := os.Remove(dest); rmErr != nil && !os.IsNotExist(rmErr) {
+		detail = fmt.Sprintf("%s. %q could not be removed: %s", err, dest, rmErr)
+	}
+	return diag.Diagnostics{{
+		Severity:      diag.Error,
+		Summary:       fmt.Sprintf("the downloaded file is not valid %s", strings.ToUpper(format)),
+		Detail:        detail,
+		AttributePath: cty.GetAttrPath("validate"),
+	}}
+}
+
+// parseDocument returns an error with the position of the first syntax
+// error in b, if it is not a well-formed document of format.
+func parseDocument(format string, b []byte) error {
+	switch format {
+	case formatJSON:
+		var v interface{}
+		err := json.Unmarshal(b, &v)
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, column := lineColumn(b, syntaxErr.Offset)
+			return fmt.Errorf("line %d, column %d (offset %d): %s", line, column, syntaxErr.Offset, err)
+		}
+		return err
+	case formatYAML:
+		// a stream may hold several documents; the errors of yaml.v2 already
+		// name the line
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		for {
+			var v interface{}
+			if err := dec.Decode(&v); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lineColumn returns the 1-based line and column of the byte before offset,
+// which is where encoding/json reports a syntax error.
+func lineColumn(b []byte, offset int64) (line, column int) {
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	head := b[:offset]
+	line = bytes.Count(head, []byte("\n")) + 1
+	column = len(head) - bytes.LastIndexByte(head, '\n') - 1
+	return
+}
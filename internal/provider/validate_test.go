@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDocument(t *testing.T) {
+	tests := []struct {
+		format  string
+		content string
+		wantErr string
+	}{
+		{formatJSON, `{"a": [1, 2], "b": null}`, ""},
+		{formatJSON, "{\n  \"a\": 1,\n  \"b\" 2\n}", "line 3, column 7"},
+		{formatJSON, `{"a": 1`, "unexpected end of JSON input"},
+		{formatJSON, `{"a": 1} {"b": 2}`, "line 1, column 10"},
+		{formatYAML, "a: 1\nb:\n  - x\n  - y\n", ""},
+		{formatYAML, "a: 1\n---\nb: 2\n", ""},
+		{formatYAML, "a: 1\nb: [x, y\n", "line 2"},
+		{formatYAML, "a: 1\n  b: 2\n", "line 2"},
+	}
+	for _, tt := range tests {
+		err := parseDocument(tt.format, []byte(tt.content))
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s %q: unexpected error: %v", tt.format, tt.content, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s %q: expected an error containing %q, got %v", tt.format, tt.content, tt.wantErr, err)
+		}
+	}
+}
+
+func TestDocumentFormat(t *testing.T) {
+	tests := []struct {
+		validate    string
+		contentType string
+		want        string
+	}{
+		{formatNone, "application/json", ""},
+		{formatJSON, "text/plain", formatJSON},
+		{formatYAML, "", formatYAML},
+		{formatAuto, "application/json; charset=utf-8", formatJSON},
+		{formatAuto, "application/vnd.api+json", formatJSON},
+		{formatAuto, "application/x-yaml", formatYAML},
+		{formatAuto, "text/yaml", formatYAML},
+		{formatAuto, "text/plain", ""},
+		{formatAuto, "", ""},
+	}
+	for _, tt := range tests {
+		data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+			"url":      "https://example.com",
+			"filename": "dest-file",
+			"validate": tt.validate,
+		})
+		if got := documentFormat(data, tt.contentType); got != tt.want {
+			t.Errorf("validate %q content type %q: got %q, want %q", tt.validate, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestEnsureDownloadFile_validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		validate    string
+		contentType string
+		content     string
+		wantErr     bool
+	}{
+		{"valid json", formatJSON, "application/json", `{"a": 1}`, false},
+		{"malformed json", formatJSON, "application/json", `{"a": 1,}`, true},
+		{"valid yaml", formatYAML, "text/plain", "a: 1\n", false},
+		{"malformed yaml", formatYAML, "text/plain", "a: [1\n", true},
+		{"auto json", formatAuto, "application/json", `{"a"}`, true},
+		{"auto yaml", formatAuto, "application/yaml", "a: 1\n  b: 2\n", true},
+		{"auto other", formatAuto, "text/plain", `{"a"}`, false},
+		{"none", formatNone, "application/json", `{"a"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.content))
+			}))
+			defer srv.Close()
+			dest := filepath.Join(t.TempDir(), "dest-file")
+			data := schema.TestResourceDataRaw(t, resourceURLSchema(), map[string]interface{}{
+				"url":      srv.URL,
+				"filename": dest,
+				"validate": tt.validate,
+			})
+			diags := ensureDownloadFile(context.Background(), data, 0, nil)
+			_, statErr := os.Stat(dest)
+			if !tt.wantErr {
+				if diags.HasError() {
+					t.Fatalf("unexpected error: %v", diags)
+				}
+				if statErr != nil {
+					t.Fatalf("the download was not kept: %v", statErr)
+				}
+				return
+			}
+			if !diags.HasError() {
+				t.Fatal("expected the malformed download to fail")
+			}
+			if d := diags[len(diags)-1]; !strings.HasPrefix(d.Summary, "the downloaded file is not valid") {
+				t.Fatalf("unexpected diagnostic %q: %s", d.Summary, d.Detail)
+			}
+			if !os.IsNotExist(statErr) {
+				t.Fatalf("the malformed download was not removed: %v", statErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"io/fs"
+	"sort"
+)
+
+// xattrsChanged reports whether an extended attribute of the source is
+// missing from the destination or has a different value. Attributes that
+// only the destination has are not compared, since the system may add its
+// own, such as an SELinux label.
+func xattrsChanged(data resourceGetter, cfg *providerConfig) (bool, error) {
+	if !xattrSupported || !data.Get("preserve_xattrs").(bool) {
+		return false, nil
+	}
+	want, err := listXattrs(cfg.resolvePath(data.Get("source").(string)))
+	if err != nil {
+		// the source may be created during apply
+		return false, nil
+	}
+	have, err := listXattrs(cfg.resolvePath(data.Get("destination").(string)))
+	if err != nil {
+		return false, nil
+	}
+	for name, value := range want {
+		if v, ok := have[name]; !ok || !bytes.Equal(v, value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ensureXattrs copies the extended attributes of the source to the
+// destination if preserve_xattrs is set.
+func ensureXattrs(data *schema.ResourceData, cfg *providerConfig) diag.Diagnostics {
+	if !data.Get("preserve_xattrs").(bool) {
+		return nil
+	}
+	source := cfg.resolvePath(data.Get("source").(string))
+	dest := cfg.resolvePath(data.Get("destination").(string))
+	if !xattrSupported {
+		return diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  "preserve_xattrs is not supported on this platform",
+			Detail:   fmt.Sprintf("the extended attributes of %q were not copied to %q", source, dest),
+		}}
+	}
+	attrs, err := listXattrs(source)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("could not read extended attributes of %q: %w", source, err))
+	}
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err := setXattr(dest, name, attrs[name])
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, fs.ErrPermission):
+			return diag.Diagnostics{{
+				Severity: diag.Error,
+				Summary:  "insufficient privileges to set extended attributes",
+				Detail:   fmt.Sprintf("could not set %q on %q: %s. Attributes outside of the user namespace, such as SELinux labels, usually require root.", name, dest, err),
+			}}
+		case errors.Is(err, errXattrNotSupported):
+			return diag.Diagnostics{{
+				Severity: diag.Error,
+				Summary:  "the destination does not support extended attributes",
+				Detail:   fmt.Sprintf("could not set %q on %q: %s", name, dest, err),
+			}}
+		default:
+			return diag.FromErr(fmt.Errorf("could not set extended attribute %q on %q: %w", name, dest, err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+
+package provider
+
+import "errors"
+
+// xattrSupported is false where extended attributes are not implemented.
+const xattrSupported = false
+
+var errXattrNotSupported = errors.New("extended attributes are not supported on this platform")
+
+func listXattrs(path string) (map[string][]byte, error) {
+	return nil, errXattrNotSupported
+}
+
+func setXattr(path, name string, value []byte) error {
+	return errXattrNotSupported
+}
@@ -0,0 +1,54 @@
+//go:build linux || darwin
+
+package provider
+
+import (
+	"bytes"
+	"golang.org/x/sys/unix"
+)
+
+const xattrSupported = true
+
+// errXattrNotSupported is returned when the file system has no extended
+// attributes.
+const errXattrNotSupported = unix.ENOTSUP
+
+// listXattrs returns the extended attributes of path by name.
+func listXattrs(path string) (map[string][]byte, error) {
+	size, err := unix.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if size, err = unix.Listxattr(path, buf); err != nil {
+		return nil, err
+	}
+	attrs := make(map[string][]byte)
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		value, err := getXattr(path, string(name))
+		if err != nil {
+			return nil, err
+		}
+		attrs[string(name)] = value
+	}
+	return attrs, nil
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	size, err := unix.Getxattr(path, name, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if size, err = unix.Getxattr(path, name, buf); err != nil {
+		return nil, err
+	}
+	return buf[:size], nil
+}
+
+func setXattr(path, name string, value []byte) error {
+	return unix.Setxattr(path, name, value, 0)
+}
@@ -0,0 +1,81 @@
+//go:build linux || darwin
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestResourceFile_preserveXattrs(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	dest := filepath.Join(dir, "dest")
+	if err := ioutil.WriteFile(source, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	const name = "user.synclocal.test"
+	if err := setXattr(source, name, []byte("value")); errors.Is(err, errXattrNotSupported) {
+		t.Skipf("the file system does not support extended attributes: %s", err)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	raw := map[string]interface{}{
+		"source":          source,
+		"destination":     dest,
+		"preserve_xattrs": true,
+	}
+	data := schema.TestResourceDataRaw(t, resourceFileSchema(), raw)
+	if diags := resourceFileCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	checkXattr := func(want string) {
+		t.Helper()
+		attrs, err := listXattrs(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(attrs[name]); got != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+	checkXattr("value")
+
+	// a changed attribute is planned as an update and copied again
+	if err := setXattr(dest, name, []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+	diff, err := resourceFile().Diff(context.Background(), data.State(), terraform.NewResourceConfigRaw(raw), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == nil || !diff.Attributes["content_sha256"].NewComputed {
+		t.Fatalf("expected xattr drift to be planned, got %v", diff)
+	}
+	if diags := resourceFileUpdate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	checkXattr("value")
+
+	// attributes are not copied unless preserve_xattrs is set
+	plain := filepath.Join(dir, "plain")
+	data = schema.TestResourceDataRaw(t, resourceFileSchema(), map[string]interface{}{
+		"source":      source,
+		"destination": plain,
+	})
+	if diags := resourceFileCreate(context.Background(), data, nil); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	attrs, err := listXattrs(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := attrs[name]; ok {
+		t.Fatalf("%s was copied without preserve_xattrs", name)
+	}
+}
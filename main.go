@@ -1,7 +1,6 @@
 package main
 
 import (
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
 
 	"github.com/justenwalker/terraform-provider-synclocal/internal/provider"
@@ -18,8 +17,6 @@ var (
 
 func main() {
 	plugin.Serve(&plugin.ServeOpts{
-		ProviderFunc: func() *schema.Provider {
-			return provider.Provider()
-		},
+		ProviderFunc: provider.New(version),
 	})
 }